@@ -9,11 +9,14 @@ import (
 )
 
 type Config struct {
-	Port     string
-	GinMode  string
-	Database DatabaseConfig
-	Redis    RedisConfig
-	JWT      JWTConfig
+	Port      string
+	GinMode   string
+	Database  DatabaseConfig
+	Redis     RedisConfig
+	JWT       JWTConfig
+	Scheduler SchedulerConfig
+	SMTP      SMTPConfig
+	Storage   StorageConfig
 }
 
 type DatabaseConfig struct {
@@ -37,6 +40,37 @@ type JWTConfig struct {
 	ExpireHours int
 }
 
+type SchedulerConfig struct {
+	Enabled                     bool
+	IntervalSeconds             int
+	ServiceTaskIntervalSeconds  int
+	WebhookIntervalSeconds      int
+	NotificationIntervalSeconds int
+	RealtimeIntervalMillis      int
+	FileCleanupIntervalSeconds  int
+}
+
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+type StorageConfig struct {
+	Driver         string // local 或 s3
+	LocalDir       string
+	S3Endpoint     string
+	S3Region       string
+	S3Bucket       string
+	S3AccessKey    string
+	S3SecretKey    string
+	S3UseSSL       bool
+	MaxUploadMB    int
+	OrphanTTLHours int
+}
+
 func LoadConfig() *Config {
 	// 尝试加载环境变量文件
 	if err := godotenv.Load(".env"); err != nil {
@@ -47,6 +81,16 @@ func LoadConfig() *Config {
 
 	redisDB, _ := strconv.Atoi(getEnv("REDIS_DB", "0"))
 	jwtExpire, _ := strconv.Atoi(getEnv("JWT_EXPIRE_HOURS", "24"))
+	schedulerEnabled, _ := strconv.ParseBool(getEnv("SCHEDULER_ENABLED", "true"))
+	schedulerInterval, _ := strconv.Atoi(getEnv("SCHEDULER_INTERVAL_SECONDS", "60"))
+	serviceTaskInterval, _ := strconv.Atoi(getEnv("SERVICE_TASK_INTERVAL_SECONDS", "5"))
+	webhookInterval, _ := strconv.Atoi(getEnv("WEBHOOK_INTERVAL_SECONDS", "5"))
+	notificationInterval, _ := strconv.Atoi(getEnv("NOTIFICATION_INTERVAL_SECONDS", "5"))
+	realtimeInterval, _ := strconv.Atoi(getEnv("REALTIME_INTERVAL_MILLIS", "1000"))
+	fileCleanupInterval, _ := strconv.Atoi(getEnv("FILE_CLEANUP_INTERVAL_SECONDS", "3600"))
+	s3UseSSL, _ := strconv.ParseBool(getEnv("S3_USE_SSL", "false"))
+	maxUpload, _ := strconv.Atoi(getEnv("UPLOAD_MAX_SIZE_MB", "50"))
+	orphanTTL, _ := strconv.Atoi(getEnv("UPLOAD_ORPHAN_TTL_HOURS", "24"))
 
 	return &Config{
 		Port:    getEnv("PORT", "8080"),
@@ -69,6 +113,34 @@ func LoadConfig() *Config {
 			Secret:      getEnv("JWT_SECRET", "your-secret-key"),
 			ExpireHours: jwtExpire,
 		},
+		Scheduler: SchedulerConfig{
+			Enabled:                     schedulerEnabled,
+			IntervalSeconds:             schedulerInterval,
+			ServiceTaskIntervalSeconds:  serviceTaskInterval,
+			WebhookIntervalSeconds:      webhookInterval,
+			NotificationIntervalSeconds: notificationInterval,
+			RealtimeIntervalMillis:      realtimeInterval,
+			FileCleanupIntervalSeconds:  fileCleanupInterval,
+		},
+		SMTP: SMTPConfig{
+			Host:     getEnv("SMTP_HOST", ""),
+			Port:     getEnv("SMTP_PORT", "25"),
+			Username: getEnv("SMTP_USERNAME", ""),
+			Password: getEnv("SMTP_PASSWORD", ""),
+			From:     getEnv("SMTP_FROM", "workflow@localhost"),
+		},
+		Storage: StorageConfig{
+			Driver:         getEnv("STORAGE_DRIVER", "local"),
+			LocalDir:       getEnv("STORAGE_LOCAL_DIR", "./uploads"),
+			S3Endpoint:     getEnv("S3_ENDPOINT", "localhost:9000"),
+			S3Region:       getEnv("S3_REGION", "us-east-1"),
+			S3Bucket:       getEnv("S3_BUCKET", "workflow-files"),
+			S3AccessKey:    getEnv("S3_ACCESS_KEY", ""),
+			S3SecretKey:    getEnv("S3_SECRET_KEY", ""),
+			S3UseSSL:       s3UseSSL,
+			MaxUploadMB:    maxUpload,
+			OrphanTTLHours: orphanTTL,
+		},
 	}
 }
 
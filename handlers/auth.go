@@ -147,4 +147,17 @@ func (h *AuthHandler) GetProfile(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"user": user.ToResponse(),
 	})
-} 
\ No newline at end of file
+} 
+// StreamToken 获取实时事件流令牌，浏览器的 EventSource 通过 token 查询参数使用，有效期1分钟
+func (h *AuthHandler) StreamToken(c *gin.Context) {
+	token, expiresAt, err := middleware.GenerateStreamToken(c.GetUint("user_id"), c.GetString("username"), h.config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "令牌生成失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"token":      token,
+		"expires_at": expiresAt,
+	})
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"gin-web-api/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type FileHandler struct {
+	fileService *services.FileService
+}
+
+func NewFileHandler() *FileHandler {
+	return &FileHandler{
+		fileService: services.NewFileService(),
+	}
+}
+
+// respondFileError 返回文件操作错误
+func respondFileError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrFileForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrFileNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
+
+// UploadFile 上传文件（multipart/form-data）
+func (h *FileHandler) UploadFile(c *gin.Context) {
+	var req services.UploadFileRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "缺少上传的文件"})
+		return
+	}
+	content, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "读取上传的文件失败"})
+		return
+	}
+	defer content.Close()
+
+	userID := c.GetUint("user_id")
+	file, err := h.fileService.UploadFile(&req, header.Filename, header.Size, content, userID)
+	if err != nil {
+		respondFileError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"data": file})
+}
+
+// CreateUpload 创建分片上传
+func (h *FileHandler) CreateUpload(c *gin.Context) {
+	var req services.CreateUploadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	upload, err := h.fileService.CreateUpload(&req, userID)
+	if err != nil {
+		respondFileError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"data": upload})
+}
+
+// GetUpload 获取分片上传进度
+func (h *FileHandler) GetUpload(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	upload, err := h.fileService.GetUpload(c.Param("key"), userID)
+	if err != nil {
+		respondFileError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": upload})
+}
+
+// UploadChunk 上传分片，请求体为分片内容，Upload-Offset 头为分片在文件中的位置
+func (h *FileHandler) UploadChunk(c *gin.Context) {
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的 Upload-Offset"})
+		return
+	}
+	chunk, err := io.ReadAll(io.LimitReader(c.Request.Body, services.FileChunkMaxSize+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "读取分片失败"})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	upload, err := h.fileService.UploadChunk(c.Param("key"), offset, chunk, userID)
+	if err != nil {
+		if errors.Is(err, services.ErrUploadOffsetMismatch) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "data": gin.H{"offset": upload.Offset}})
+			return
+		}
+		respondFileError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": upload})
+}
+
+// DownloadFile 下载文件
+func (h *FileHandler) DownloadFile(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	file, content, err := h.fileService.OpenFile(c.Param("key"), userID)
+	if err != nil {
+		respondFileError(c, err)
+		return
+	}
+	defer content.Close()
+
+	c.DataFromReader(http.StatusOK, file.Size, file.ContentType, content, map[string]string{
+		"Content-Disposition":    mime.FormatMediaType("attachment", map[string]string{"filename": file.FileName}),
+		"X-Content-Type-Options": "nosniff",
+	})
+}
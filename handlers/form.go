@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -20,6 +21,25 @@ func NewFormHandler() *FormHandler {
 	}
 }
 
+// respondFormError 返回错误，表单验证未通过时返回400并附带逐字段的错误列表，缺少表单数据版本时返回400，
+// 表单数据已被他人修改时返回409
+func respondFormError(c *gin.Context, status int, err error) {
+	var validationErr *services.FormValidationError
+	if errors.As(err, &validationErr) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "errors": validationErr.Errors})
+		return
+	}
+	if errors.Is(err, services.ErrFormVersionRequired) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, services.ErrFormDataConflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(status, gin.H{"error": err.Error()})
+}
+
 // CreateFormDefinition 创建表单定义
 func (h *FormHandler) CreateFormDefinition(c *gin.Context) {
 	var req services.CreateFormRequest
@@ -119,7 +139,7 @@ func (h *FormHandler) CreateFormData(c *gin.Context) {
 	userID := c.GetUint("user_id")
 	formData, err := h.formService.CreateFormData(&req, userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondFormError(c, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -144,7 +164,7 @@ func (h *FormHandler) UpdateFormData(c *gin.Context) {
 	userID := c.GetUint("user_id")
 	formData, err := h.formService.UpdateFormData(uint(id), &req, userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondFormError(c, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -190,6 +210,39 @@ func (h *FormHandler) RenderForm(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"data": renderData})
 }
 
+// GetFieldOptions 查询表单字段的选项，支持 keyword 搜索、parent 逐级加载和分页
+func (h *FormHandler) GetFieldOptions(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的表单ID"})
+		return
+	}
+	page, pageSize := parsePagination(c)
+
+	options, total, err := h.formService.SearchFieldOptions(uint(id), c.Param("key"), services.OptionQuery{
+		Keyword:  c.Query("keyword"),
+		Parent:   c.Query("parent"),
+		Page:     page,
+		PageSize: pageSize,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"options": options,
+			"pagination": gin.H{
+				"page":       page,
+				"page_size":  pageSize,
+				"total":      total,
+				"total_page": (total + int64(pageSize) - 1) / int64(pageSize),
+			},
+		},
+	})
+}
+
 // CreateFormFromJSON 从JSON创建表单（用于支持node.txt格式）
 func (h *FormHandler) CreateFormFromJSON(c *gin.Context) {
 	var req services.CreateFormRequest
@@ -249,24 +302,20 @@ func (h *FormHandler) ValidateFormData(c *gin.Context) {
 		return
 	}
 
-	// 通过创建临时表单数据来验证
-	userID := c.GetUint("user_id")
-	tempReq := &services.CreateFormDataRequest{
-		FormID:      req.FormID,
-		BusinessKey: "temp-validation",
-		FormValues:  req.FormValues,
-	}
-
-	_, err := h.formService.CreateFormData(tempReq, userID)
+	fieldErrors, err := h.formService.ValidateFormValues(req.FormID, req.FormValues)
 	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(fieldErrors) > 0 {
 		c.JSON(http.StatusBadRequest, gin.H{
-			"valid": false,
-			"error": err.Error(),
+			"valid":  false,
+			"error":  (&services.FormValidationError{Errors: fieldErrors}).Error(),
+			"errors": fieldErrors,
 		})
 		return
 	}
 
-	// 删除临时数据（实际实现中可能需要更好的验证方式）
 	c.JSON(http.StatusOK, gin.H{
 		"valid":   true,
 		"message": "表单数据验证通过",
@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"gin-web-api/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type NotificationHandler struct {
+	notificationService *services.NotificationService
+}
+
+func NewNotificationHandler() *NotificationHandler {
+	return &NotificationHandler{
+		notificationService: services.NewNotificationService(),
+	}
+}
+
+// parsePagination 获取分页参数
+func parsePagination(c *gin.Context) (int, int) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	return page, pageSize
+}
+
+// GetInbox 获取我的站内信
+func (h *NotificationHandler) GetInbox(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	page, pageSize := parsePagination(c)
+	unreadOnly := c.Query("unread") == "true"
+
+	notifications, total, err := h.notificationService.ListInbox(userID, unreadOnly, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取站内信失败"})
+		return
+	}
+	unread, err := h.notificationService.CountUnread(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取站内信失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"notifications": notifications,
+			"unread":        unread,
+			"pagination": gin.H{
+				"page":       page,
+				"page_size":  pageSize,
+				"total":      total,
+				"total_page": (total + int64(pageSize) - 1) / int64(pageSize),
+			},
+		},
+	})
+}
+
+// GetUnreadCount 获取我的未读站内信数量
+func (h *NotificationHandler) GetUnreadCount(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	count, err := h.notificationService.CountUnread(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取未读数量失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{"unread": count}})
+}
+
+// MarkRead 将站内信标记为已读
+func (h *NotificationHandler) MarkRead(c *gin.Context) {
+	h.markRead(c, true)
+}
+
+// MarkUnread 将站内信标记为未读
+func (h *NotificationHandler) MarkUnread(c *gin.Context) {
+	h.markRead(c, false)
+}
+
+func (h *NotificationHandler) markRead(c *gin.Context, read bool) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的站内信ID"})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	if err := h.notificationService.MarkRead(uint(id), userID, read); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "操作成功"})
+}
+
+// MarkAllRead 将我的全部站内信标记为已读
+func (h *NotificationHandler) MarkAllRead(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	count, err := h.notificationService.MarkAllRead(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "已全部标记为已读", "data": gin.H{"count": count}})
+}
+
+// GetPreferences 获取我的通知偏好
+func (h *NotificationHandler) GetPreferences(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	preferences, err := h.notificationService.GetPreferences(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取通知偏好失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": preferences})
+}
+
+// UpdatePreferences 修改我的通知偏好
+func (h *NotificationHandler) UpdatePreferences(c *gin.Context) {
+	var req struct {
+		Preferences []services.NotificationPreferenceItem `json:"preferences" binding:"required,dive"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	preferences, err := h.notificationService.UpdatePreferences(userID, req.Preferences)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": preferences})
+}
+
+// GetTemplates 获取通知模板
+func (h *NotificationHandler) GetTemplates(c *gin.Context) {
+	workflowID, _ := strconv.ParseUint(c.Query("workflow_id"), 10, 32)
+	templates, err := h.notificationService.ListTemplates(uint(workflowID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取通知模板失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": templates})
+}
+
+// CreateTemplate 创建通知模板
+func (h *NotificationHandler) CreateTemplate(c *gin.Context) {
+	var req services.NotificationTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	template, err := h.notificationService.CreateTemplate(&req, userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"data": template})
+}
+
+// UpdateTemplate 更新通知模板
+func (h *NotificationHandler) UpdateTemplate(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的模板ID"})
+		return
+	}
+
+	var req services.NotificationTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	template, err := h.notificationService.UpdateTemplate(uint(id), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": template})
+}
+
+// DeleteTemplate 删除通知模板
+func (h *NotificationHandler) DeleteTemplate(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的模板ID"})
+		return
+	}
+
+	if err := h.notificationService.DeleteTemplate(uint(id)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "通知模板已删除"})
+}
+
+// GetNotificationLog 获取通知发送记录
+func (h *NotificationHandler) GetNotificationLog(c *gin.Context) {
+	userID, _ := strconv.ParseUint(c.Query("user_id"), 10, 32)
+	page, pageSize := parsePagination(c)
+
+	notifications, total, err := h.notificationService.ListNotifications(uint(userID), c.Query("channel"), c.Query("status"), page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取通知记录失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"notifications": notifications,
+			"pagination": gin.H{
+				"page":       page,
+				"page_size":  pageSize,
+				"total":      total,
+				"total_page": (total + int64(pageSize) - 1) / int64(pageSize),
+			},
+		},
+	})
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"gin-web-api/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// realtimeHeartbeat 心跳间隔，避免代理因连接空闲而断开
+const realtimeHeartbeat = 30 * time.Second
+
+type RealtimeHandler struct {
+	hub *services.RealtimeHub
+}
+
+func NewRealtimeHandler() *RealtimeHandler {
+	return &RealtimeHandler{
+		hub: services.GetRealtimeHub(),
+	}
+}
+
+// Stream 实时事件流（SSE）：推送当前用户相关的任务和实例变化
+func (h *RealtimeHandler) Stream(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	subscriber := h.hub.Subscribe(userID)
+	defer h.hub.Unsubscribe(subscriber)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.SSEvent("connected", gin.H{"user_id": userID})
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(realtimeHeartbeat)
+	defer heartbeat.Stop()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case event := <-subscriber.Events:
+			c.SSEvent(event.Type, event)
+			return true
+		case now := <-heartbeat.C:
+			c.SSEvent("ping", gin.H{"time": now.Unix()})
+			return true
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"gin-web-api/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WebhookHandler struct {
+	webhookService *services.WebhookService
+}
+
+func NewWebhookHandler() *WebhookHandler {
+	return &WebhookHandler{
+		webhookService: services.NewWebhookService(),
+	}
+}
+
+// GetWebhooks 获取事件订阅列表
+func (h *WebhookHandler) GetWebhooks(c *gin.Context) {
+	webhooks, err := h.webhookService.ListWebhooks()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取事件订阅失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": webhooks})
+}
+
+// GetWebhook 获取事件订阅详情
+func (h *WebhookHandler) GetWebhook(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的订阅ID"})
+		return
+	}
+
+	webhook, err := h.webhookService.GetWebhook(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "事件订阅不存在"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": webhook})
+}
+
+// CreateWebhook 创建事件订阅
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	var req services.CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	webhook, err := h.webhookService.CreateWebhook(&req, userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"data": webhook})
+}
+
+// UpdateWebhook 更新事件订阅
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的订阅ID"})
+		return
+	}
+
+	var req services.UpdateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	webhook, err := h.webhookService.UpdateWebhook(uint(id), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": webhook})
+}
+
+// RotateWebhookSecret 重置事件订阅的签名密钥
+func (h *WebhookHandler) RotateWebhookSecret(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的订阅ID"})
+		return
+	}
+
+	webhook, err := h.webhookService.RotateWebhookSecret(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "事件订阅不存在"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": webhook})
+}
+
+// DeleteWebhook 删除事件订阅
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的订阅ID"})
+		return
+	}
+
+	if err := h.webhookService.DeleteWebhook(uint(id)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "事件订阅已删除"})
+}
+
+// GetDeliveries 获取事件订阅的推送记录
+func (h *WebhookHandler) GetDeliveries(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的订阅ID"})
+		return
+	}
+
+	page, pageSize := parsePagination(c)
+	deliveries, total, err := h.webhookService.ListDeliveries(uint(id), c.Query("status"), page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取推送记录失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"deliveries": deliveries,
+			"pagination": gin.H{
+				"page":       page,
+				"page_size":  pageSize,
+				"total":      total,
+				"total_page": (total + int64(pageSize) - 1) / int64(pageSize),
+			},
+		},
+	})
+}
+
+// RedeliverFailed 重新推送事件订阅下全部失败的推送
+func (h *WebhookHandler) RedeliverFailed(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的订阅ID"})
+		return
+	}
+
+	count, err := h.webhookService.RedeliverFailed(uint(id))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "已重新推送", "data": gin.H{"count": count}})
+}
+
+// Redeliver 重新推送单条推送记录
+func (h *WebhookHandler) Redeliver(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的推送记录ID"})
+		return
+	}
+
+	delivery, err := h.webhookService.Redeliver(uint(id))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "已重新推送", "data": delivery})
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -175,6 +176,62 @@ func (h *WorkflowHandler) GetWorkflowNodeTree(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"data": nodeTree})
 }
 
+// ExportWorkflow 导出工作流定义（含节点配置和关联表单），导出格式与导入接口一致
+func (h *WorkflowHandler) ExportWorkflow(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的工作流ID"})
+		return
+	}
+
+	exportData, err := h.workflowService.ExportWorkflow(uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "工作流导出成功",
+		"data":    exportData,
+	})
+}
+
+// ValidateWorkflow 校验工作流定义，返回带节点路径的问题列表
+func (h *WorkflowHandler) ValidateWorkflow(c *gin.Context) {
+	var req services.ValidateWorkflowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.workflowService.ValidateWorkflow(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": result})
+}
+
+// SimulateWorkflow 模拟流程运行，返回执行路径、审批人和分支决策，不保留任何数据
+func (h *WorkflowHandler) SimulateWorkflow(c *gin.Context) {
+	var req services.SimulateWorkflowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	result, err := h.workflowService.SimulateWorkflow(&req, userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": result})
+}
+
 // UpdateWorkflowStatus 更新工作流状态
 func (h *WorkflowHandler) UpdateWorkflowStatus(c *gin.Context) {
 	idStr := c.Param("id")
@@ -192,22 +249,175 @@ func (h *WorkflowHandler) UpdateWorkflowStatus(c *gin.Context) {
 		return
 	}
 
-	var workflow models.WorkflowDefinition
-	db := h.workflowService.GetDB()
-	if err := db.First(&workflow, uint(id)).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "工作流不存在"})
+	userID := c.GetUint("user_id")
+	workflow, err := h.workflowService.UpdateWorkflowStatus(uint(id), req.Status, userID)
+	if err != nil {
+		respondWorkflowError(c, err)
 		return
 	}
 
-	workflow.Status = req.Status
-	if err := db.Save(&workflow).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新工作流状态失败"})
+	c.JSON(http.StatusOK, gin.H{"data": workflow})
+}
+
+// UpdateWorkflow 修改草稿版本的工作流
+func (h *WorkflowHandler) UpdateWorkflow(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的工作流ID"})
+		return
+	}
+
+	var req services.UpdateWorkflowDraftRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	workflow, err := h.workflowService.UpdateWorkflowDraft(uint(id), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"data": workflow})
 }
 
+// CreateWorkflowVersion 基于指定版本创建新的草稿版本
+func (h *WorkflowHandler) CreateWorkflowVersion(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的工作流ID"})
+		return
+	}
+
+	var req services.CreateWorkflowVersionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	workflow, err := h.workflowService.CreateWorkflowVersion(uint(id), &req, userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "新版本创建成功",
+		"data":    workflow,
+	})
+}
+
+// GetWorkflowVersions 获取工作流的版本列表
+func (h *WorkflowHandler) GetWorkflowVersions(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的工作流ID"})
+		return
+	}
+
+	versions, err := h.workflowService.ListWorkflowVersions(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": versions})
+}
+
+// DiffWorkflowVersions 比较两个版本的差异
+func (h *WorkflowHandler) DiffWorkflowVersions(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的工作流ID"})
+		return
+	}
+	toID, err := strconv.ParseUint(c.Query("to"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的对比版本ID"})
+		return
+	}
+
+	diff, err := h.workflowService.DiffWorkflowVersions(uint(id), uint(toID))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": diff})
+}
+
+// PublishWorkflow 发布草稿版本
+func (h *WorkflowHandler) PublishWorkflow(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的工作流ID"})
+		return
+	}
+
+	var req struct {
+		SetDefault bool `json:"set_default"`
+	}
+	c.ShouldBindJSON(&req)
+
+	userID := c.GetUint("user_id")
+	workflow, err := h.workflowService.PublishWorkflow(uint(id), userID, req.SetDefault)
+	if err != nil {
+		respondWorkflowError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "版本发布成功",
+		"data":    workflow,
+	})
+}
+
+// PromoteWorkflowVersion 将指定版本设为默认版本
+func (h *WorkflowHandler) PromoteWorkflowVersion(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的工作流ID"})
+		return
+	}
+
+	workflow, err := h.workflowService.PromoteWorkflowVersion(uint(id))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "已设为默认版本",
+		"data":    workflow,
+	})
+}
+
+// MigrateInstances 将运行中的实例迁移到同一工作流的其它版本
+func (h *WorkflowHandler) MigrateInstances(c *gin.Context) {
+	var req services.MigrateInstancesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	report, err := h.workflowService.MigrateInstances(&req, userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": report})
+}
+
 // StartWorkflow 启动工作流实例
 func (h *WorkflowHandler) StartWorkflow(c *gin.Context) {
 	var req services.StartWorkflowRequest
@@ -237,7 +447,7 @@ func (h *WorkflowHandler) StartWorkflowWithForm(c *gin.Context) {
 	userID := c.GetUint("user_id")
 	instance, err := h.workflowService.StartWorkflowWithForm(&req, userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondFormError(c, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -249,35 +459,14 @@ func (h *WorkflowHandler) StartWorkflowWithForm(c *gin.Context) {
 
 // GetInstances 获取工作流实例列表
 func (h *WorkflowHandler) GetInstances(c *gin.Context) {
-	var instances []models.WorkflowInstance
-	
 	userID := c.GetUint("user_id")
-	db := h.workflowService.GetDB()
-	
-	// 根据权限过滤实例
+
+	// 有实例查看权限的管理员可以看到所有实例，普通用户只能看到自己发起的实例和需要自己审批的实例
 	hasReadPermission, _ := h.permissionService.CheckPermission(userID, models.PermissionInstanceRead)
-	if hasReadPermission {
-		// 管理员可以看到所有实例
-		err := db.Preload("Workflow").
-			Preload("Initiator").
-			Preload("FormData.Form").
-			Find(&instances).Error
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取实例列表失败"})
-			return
-		}
-	} else {
-		// 普通用户只能看到自己发起的实例和需要自己审批的实例
-		err := db.Preload("Workflow").
-			Preload("Initiator").
-			Preload("FormData.Form").
-			Where("initiator_id = ? OR id IN (SELECT DISTINCT instance_id FROM workflow_tasks WHERE assignee_id = ?)", 
-				userID, userID).
-			Find(&instances).Error
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取实例列表失败"})
-			return
-		}
+	instances, err := h.workflowService.ListInstances(userID, hasReadPermission)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取实例列表失败"})
+		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"data": instances})
@@ -304,6 +493,13 @@ func (h *WorkflowHandler) GetInstance(c *gin.Context) {
 		return
 	}
 
+	// 去掉审批人不可见的表单字段
+	userID := c.GetUint("user_id")
+	if err := h.workflowService.FilterInstanceForViewer(&instance, userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{"data": instance})
 }
 
@@ -316,28 +512,34 @@ func (h *WorkflowHandler) GetInstanceFormData(c *gin.Context) {
 		return
 	}
 
-	var instance models.WorkflowInstance
-	db := h.workflowService.GetDB()
-	if err := db.Preload("FormData.Form.Cards.Attributes.Attribute").
-		Preload("FormData.Form.Buttons").
-		First(&instance, uint(id)).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "实例不存在"})
+	// 按查看人的字段权限渲染表单数据
+	userID := c.GetUint("user_id")
+	renderData, err := h.workflowService.RenderInstanceForm(uint(id), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
 	}
 
-	if instance.FormData == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "该实例没有关联的表单数据"})
+	c.JSON(http.StatusOK, gin.H{"data": renderData})
+}
+
+// GetInstanceFormChanges 获取实例表单数据各字段的变更历史
+func (h *WorkflowHandler) GetInstanceFormChanges(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的实例ID"})
 		return
 	}
 
-	// 渲染表单数据
-	renderData, err := h.formService.RenderFormWithData(instance.FormData.FormID, instance.FormData.FormValues)
+	userID := c.GetUint("user_id")
+	histories, err := h.workflowService.GetFormDataChanges(uint(id), userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "渲染表单数据失败"})
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"data": renderData})
+	c.JSON(http.StatusOK, gin.H{"data": histories})
 }
 
 // CancelInstance 取消工作流实例
@@ -349,38 +551,127 @@ func (h *WorkflowHandler) CancelInstance(c *gin.Context) {
 		return
 	}
 
-	var instance models.WorkflowInstance
-	db := h.workflowService.GetDB()
-	if err := db.First(&instance, uint(id)).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "实例不存在"})
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	c.ShouldBindJSON(&req)
+
+	userID := c.GetUint("user_id")
+	if err := h.workflowService.CancelInstance(uint(id), userID, req.Reason); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	if instance.Status != models.InstanceStatusRunning {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "只能取消运行中的实例"})
+	c.JSON(http.StatusOK, gin.H{"message": "实例已取消"})
+}
+
+// RecallInstance 发起人撤回实例
+func (h *WorkflowHandler) RecallInstance(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的实例ID"})
 		return
 	}
 
-	instance.Status = models.InstanceStatusCancelled
-	if err := db.Save(&instance).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "取消实例失败"})
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	c.ShouldBindJSON(&req)
+
+	userID := c.GetUint("user_id")
+	if err := h.workflowService.RecallInstance(uint(id), userID, req.Reason); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "实例已取消"})
+	c.JSON(http.StatusOK, gin.H{"message": "实例已撤回"})
+}
+
+// ResubmitInstance 发起人重新提交已撤回的实例
+func (h *WorkflowHandler) ResubmitInstance(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的实例ID"})
+		return
+	}
+
+	var req struct {
+		FormValues  string `json:"form_values"`
+		FormVersion int    `json:"form_version"` // 读取实例表单时的表单数据版本，提交 form_values 时必填，用于检测并发修改
+		Comment     string `json:"comment"`
+	}
+	c.ShouldBindJSON(&req)
+
+	userID := c.GetUint("user_id")
+	if err := h.workflowService.ResubmitInstance(uint(id), userID, req.FormValues, req.FormVersion, req.Comment); err != nil {
+		respondFormError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "实例已重新提交"})
+}
+
+// SuspendInstance 挂起实例
+func (h *WorkflowHandler) SuspendInstance(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的实例ID"})
+		return
+	}
+
+	var req struct {
+		Reason string `json:"reason" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	if err := h.workflowService.SuspendInstance(uint(id), userID, req.Reason); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "实例已挂起"})
+}
+
+// ResumeInstance 恢复已挂起的实例
+func (h *WorkflowHandler) ResumeInstance(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的实例ID"})
+		return
+	}
+
+	var req struct {
+		Reason string `json:"reason" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	if err := h.workflowService.ResumeInstance(uint(id), userID, req.Reason); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "实例已恢复"})
 }
 
-// GetMyTasks 获取我的待办任务
+// GetMyTasks 获取我的待办任务（包括已认领和可认领的任务），
+// 默认不返回已挂起实例的任务，include_suspended=true 时一并返回
 func (h *WorkflowHandler) GetMyTasks(c *gin.Context) {
 	userID := c.GetUint("user_id")
-	
-	var tasks []models.WorkflowTask
-	db := h.workflowService.GetDB()
-	if err := db.Preload("Instance.Workflow").
-		Preload("Instance.Initiator").
-		Preload("Instance.FormData.Form").
-		Where("assignee_id = ? AND status = ?", userID, models.TaskStatusPending).
-		Find(&tasks).Error; err != nil {
+
+	tasks, err := h.workflowService.ListMyTasks(userID, c.Query("include_suspended") == "true")
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取待办任务失败"})
 		return
 	}
@@ -398,8 +689,9 @@ func (h *WorkflowHandler) ApproveTask(c *gin.Context) {
 	}
 
 	var req struct {
-		Comment    string `json:"comment"`
-		FormValues string `json:"form_values"`
+		Comment     string `json:"comment"`
+		FormValues  string `json:"form_values"`
+		FormVersion int    `json:"form_version"` // 获取任务表单时的表单数据版本，提交 form_values 时必填，用于检测并发修改
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -407,14 +699,33 @@ func (h *WorkflowHandler) ApproveTask(c *gin.Context) {
 	}
 
 	userID := c.GetUint("user_id")
-	if err := h.workflowService.ApproveTaskWithForm(uint(id), userID, req.Comment, req.FormValues); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.workflowService.ApproveTaskWithFormVersion(uint(id), userID, req.Comment, req.FormValues, req.FormVersion); err != nil {
+		respondFormError(c, http.StatusInternalServerError, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "任务已审批通过"})
 }
 
+// GetTaskForm 获取任务的表单：按任务所在节点的字段权限返回可见字段和可编辑字段
+func (h *WorkflowHandler) GetTaskForm(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的任务ID"})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	renderData, err := h.workflowService.RenderTaskForm(uint(id), userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": renderData})
+}
+
 // RejectTask 拒绝任务
 func (h *WorkflowHandler) RejectTask(c *gin.Context) {
 	idStr := c.Param("id")
@@ -425,7 +736,8 @@ func (h *WorkflowHandler) RejectTask(c *gin.Context) {
 	}
 
 	var req struct {
-		Comment string `json:"comment" binding:"required"`
+		Comment       string `json:"comment" binding:"required"`
+		TargetNodeKey string `json:"target_node_key"` // 退回指定节点时选择的目标节点
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -433,7 +745,7 @@ func (h *WorkflowHandler) RejectTask(c *gin.Context) {
 	}
 
 	userID := c.GetUint("user_id")
-	if err := h.workflowService.RejectTask(uint(id), userID, req.Comment); err != nil {
+	if err := h.workflowService.RejectTaskWithTarget(uint(id), userID, req.Comment, req.TargetNodeKey); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -441,6 +753,147 @@ func (h *WorkflowHandler) RejectTask(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "任务已拒绝"})
 }
 
+// GetRejectTargets 获取任务可退回的节点
+func (h *WorkflowHandler) GetRejectTargets(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的任务ID"})
+		return
+	}
+
+	targets, err := h.workflowService.GetRejectTargets(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": targets})
+}
+
+// ClaimTask 认领任务
+func (h *WorkflowHandler) ClaimTask(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的任务ID"})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	if err := h.workflowService.ClaimTask(uint(id), userID); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "任务已认领"})
+}
+
+// ReleaseTask 释放已认领的任务
+func (h *WorkflowHandler) ReleaseTask(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的任务ID"})
+		return
+	}
+
+	var req struct {
+		Comment string `json:"comment"`
+	}
+	c.ShouldBindJSON(&req)
+
+	userID := c.GetUint("user_id")
+	if err := h.workflowService.ReleaseTask(uint(id), userID, req.Comment); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "任务已释放"})
+}
+
+// TransferTask 转办任务
+func (h *WorkflowHandler) TransferTask(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的任务ID"})
+		return
+	}
+
+	var req struct {
+		TargetUserID uint   `json:"target_user_id" binding:"required"`
+		Comment      string `json:"comment"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	if err := h.workflowService.TransferTask(uint(id), userID, req.TargetUserID, req.Comment); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "任务已转办"})
+}
+
+// DelegateTask 委托任务
+func (h *WorkflowHandler) DelegateTask(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的任务ID"})
+		return
+	}
+
+	var req struct {
+		TargetUserID uint   `json:"target_user_id" binding:"required"`
+		Comment      string `json:"comment"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	if err := h.workflowService.DelegateTask(uint(id), userID, req.TargetUserID, req.Comment); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "任务已委托"})
+}
+
+// AddSigner 加签
+func (h *WorkflowHandler) AddSigner(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的任务ID"})
+		return
+	}
+
+	var req struct {
+		UserIDs  []uint `json:"user_ids" binding:"required"`
+		Position string `json:"position" binding:"required"` // before: 前加签, after: 后加签
+		Comment  string `json:"comment"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	if err := h.workflowService.AddSigner(uint(id), userID, req.UserIDs, req.Position, req.Comment); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "加签成功"})
+}
+
 // GetInstanceHistory 获取实例历史记录
 func (h *WorkflowHandler) GetInstanceHistory(c *gin.Context) {
 	idStr := c.Param("id")
@@ -458,9 +911,55 @@ func (h *WorkflowHandler) GetInstanceHistory(c *gin.Context) {
 		return
 	}
 
+	userID := c.GetUint("user_id")
+	if err := h.workflowService.FilterHistoryForViewer(uint(id), userID, history); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{"data": history})
 }
 
+// GetInstanceServiceJobs 获取实例的服务调用记录
+func (h *WorkflowHandler) GetInstanceServiceJobs(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的实例ID"})
+		return
+	}
+
+	jobs, err := h.workflowService.GetInstanceServiceJobs(uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": jobs})
+}
+
+// RetryServiceJob 人工重试失败的服务调用
+func (h *WorkflowHandler) RetryServiceJob(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的调用任务ID"})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	job, err := h.workflowService.RetryServiceJob(uint(id), userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "已重新安排服务调用",
+		"data":    job,
+	})
+}
+
 // GetWorkflowStatistics 获取工作流统计信息
 func (h *WorkflowHandler) GetWorkflowStatistics(c *gin.Context) {
 	db := h.workflowService.GetDB()
@@ -480,8 +979,8 @@ func (h *WorkflowHandler) GetWorkflowStatistics(c *gin.Context) {
 	// 统计待办任务数量
 	userID := c.GetUint("user_id")
 	var pendingTaskCount int64
-	db.Model(&models.WorkflowTask{}).Where("assignee_id = ? AND status = ?", 
-		userID, models.TaskStatusPending).Count(&pendingTaskCount)
+	db.Model(&models.WorkflowTask{}).Where("assignee_id = ? AND status IN ?", 
+		userID, models.ActionableTaskStatuses).Count(&pendingTaskCount)
 	
 	// 统计各状态的实例数量
 	var statusStats []struct {
@@ -502,4 +1001,17 @@ func (h *WorkflowHandler) GetWorkflowStatistics(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{"data": stats})
-} 
\ No newline at end of file
+} 
+
+// respondWorkflowError 返回工作流操作错误，校验未通过时附带问题列表
+func respondWorkflowError(c *gin.Context, err error) {
+	var validationErr *services.WorkflowValidationError
+	if errors.As(err, &validationErr) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":  err.Error(),
+			"issues": validationErr.Issues,
+		})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
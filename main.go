@@ -2,6 +2,7 @@ package main
 
 import (
 	"log"
+	"time"
 
 	"gin-web-api/config"
 	"gin-web-api/database"
@@ -41,6 +42,7 @@ func main() {
 		&models.FieldAttribute{},
 		&models.FormButton{},
 		&models.FormData{},
+		&models.FormDataChange{},
 		
 		// 工作流相关模型
 		&models.WorkflowDefinition{},
@@ -48,11 +50,43 @@ func main() {
 		&models.WorkflowBranch{},
 		&models.WorkflowInstance{},
 		&models.WorkflowTask{},
+		&models.WorkflowTaskCandidate{},
 		&models.WorkflowHistory{},
+		&models.WorkflowServiceJob{},
+		&models.WorkflowEvent{},
+
+		// 事件订阅相关模型
+		&models.WebhookSubscription{},
+		&models.WebhookDelivery{},
+
+		// 通知相关模型
+		&models.Notification{},
+		&models.NotificationTemplate{},
+		&models.NotificationPreference{},
+
+		// 文件相关模型
+		&models.FileObject{},
+		&models.FileUpload{},
 	); err != nil {
 		log.Fatal("数据库迁移失败:", err)
 	}
 
+	// 待认领任务没有处理人、系统自动操作没有操作人，移除旧版本创建的外键约束
+	legacyConstraints := []struct {
+		model interface{}
+		name  string
+	}{
+		{&models.WorkflowTask{}, "fk_workflow_tasks_assignee"},
+		{&models.WorkflowHistory{}, "fk_workflow_histories_operator"},
+	}
+	for _, constraint := range legacyConstraints {
+		if database.DB.Migrator().HasConstraint(constraint.model, constraint.name) {
+			if err := database.DB.Migrator().DropConstraint(constraint.model, constraint.name); err != nil {
+				log.Printf("移除外键约束 %s 失败: %v", constraint.name, err)
+			}
+		}
+	}
+
 	// 初始化默认权限数据
 	permissionService := services.NewPermissionService()
 	if err := permissionService.InitializeDefaultData(); err != nil {
@@ -61,6 +95,58 @@ func main() {
 		log.Println("权限数据初始化完成")
 	}
 
+	// 注册通知渠道：站内信始终可用，配置了SMTP服务器时启用邮件通知
+	services.RegisterNotificationChannel(models.NotificationChannelWebhook, services.NewWebhookChannel())
+	if cfg.SMTP.Host != "" {
+		services.RegisterNotificationChannel(models.NotificationChannelEmail, services.NewSMTPChannel(cfg.SMTP))
+	}
+
+	// 文件存储：本地目录或S3兼容的对象存储
+	fileStorage, err := services.NewFileStorage(cfg.Storage)
+	if err != nil {
+		log.Fatal("文件存储初始化失败:", err)
+	}
+	services.SetFileStorage(fileStorage, int64(cfg.Storage.MaxUploadMB)<<20)
+
+	// 启动工作流定时任务（超时提醒、升级和自动处理）
+	if cfg.Scheduler.Enabled {
+		scheduler := services.NewWorkflowScheduler(time.Duration(cfg.Scheduler.IntervalSeconds) * time.Second)
+		scheduler.Start()
+		defer scheduler.Stop()
+
+		// 服务节点的HTTP调用
+		serviceTaskWorker := services.NewServiceTaskWorker(time.Duration(cfg.Scheduler.ServiceTaskIntervalSeconds) * time.Second)
+		serviceTaskWorker.Start()
+		defer serviceTaskWorker.Stop()
+
+		// 工作流事件推送给订阅方
+		webhookDispatcher := services.NewWebhookDispatcher(time.Duration(cfg.Scheduler.WebhookIntervalSeconds) * time.Second)
+		webhookDispatcher.Start()
+		defer webhookDispatcher.Stop()
+
+		// 邮件、推送等渠道的通知发送
+		notificationWorker := services.NewNotificationWorker(time.Duration(cfg.Scheduler.NotificationIntervalSeconds) * time.Second)
+		notificationWorker.Start()
+		defer notificationWorker.Stop()
+
+		// 工作流事件发布到Redis，供各副本推送实时事件
+		realtimeRelay := services.NewRealtimeRelay(time.Duration(cfg.Scheduler.RealtimeIntervalMillis) * time.Millisecond)
+		realtimeRelay.Start()
+		defer realtimeRelay.Stop()
+
+		// 清理未关联到表单数据的文件和过期的分片上传
+		fileCleanupWorker := services.NewFileCleanupWorker(
+			time.Duration(cfg.Scheduler.FileCleanupIntervalSeconds)*time.Second,
+			time.Duration(cfg.Storage.OrphanTTLHours)*time.Hour)
+		fileCleanupWorker.Start()
+		defer fileCleanupWorker.Stop()
+	}
+
+	// 订阅实时事件，推送给本副本上连接的用户
+	realtimeHub := services.GetRealtimeHub()
+	realtimeHub.Start()
+	defer realtimeHub.Stop()
+
 	// 设置路由
 	r := routes.SetupRoutes(cfg)
 
@@ -75,6 +161,12 @@ func main() {
 	log.Println("- 条件分支和复杂流转逻辑")
 	log.Println("- 基于角色的细粒度权限控制")
 	log.Println("- 完整的审批历史记录")
+	log.Println("- 任务处理时限、超时升级和自动处理")
+	log.Println("- 服务节点自动调用HTTP接口")
+	log.Println("- 工作流事件订阅和签名推送（Webhook）")
+	log.Println("- 站内信、邮件和推送通知")
+	log.Println("- 任务和实例变化实时推送（SSE）")
+	log.Println("- 表单文件上传（支持断点续传）和授权下载")
 	log.Println("- 支持node.txt格式的导入导出")
 	
 	if err := r.Run(":" + cfg.Port); err != nil {
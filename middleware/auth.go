@@ -12,9 +12,16 @@ import (
 	"github.com/golang-jwt/jwt/v4"
 )
 
+// 令牌用途：登录令牌不设置，实时事件流令牌只能用于建立事件流连接
+const TokenScopeStream = "stream"
+
+// StreamTokenLifetime 实时事件流令牌的有效期，只需覆盖从获取令牌到建立连接的时间
+const StreamTokenLifetime = time.Minute
+
 type Claims struct {
 	UserID   uint   `json:"user_id"`
 	Username string `json:"username"`
+	Scope    string `json:"scope,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -35,23 +42,54 @@ func JWTMiddleware(cfg *config.Config) gin.HandlerFunc {
 			return
 		}
 
-		// 解析令牌
-		claims := &Claims{}
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return []byte(cfg.JWT.Secret), nil
-		})
+		authenticate(c, cfg, tokenString, "")
+	}
+}
 
-		if err != nil || !token.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的令牌"})
+// JWTStreamMiddleware 实时事件流的认证：浏览器的 EventSource 不能设置请求头，
+// 未提供 Authorization 时可通过 token 查询参数传递令牌。查询参数会出现在访问日志中，
+// 因此只接受短期有效的事件流令牌（见 GenerateStreamToken），不接受登录令牌
+func JWTStreamMiddleware(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if authHeader := c.GetHeader("Authorization"); authHeader != "" {
+			tokenString := strings.TrimPrefix(authHeader, "Bearer ")
+			if tokenString == authHeader {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的授权格式"})
+				c.Abort()
+				return
+			}
+			authenticate(c, cfg, tokenString, "")
+			return
+		}
+
+		tokenString := c.Query("token")
+		if tokenString == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "未提供授权令牌"})
 			c.Abort()
 			return
 		}
+		authenticate(c, cfg, tokenString, TokenScopeStream)
+	}
+}
 
-		// 将用户信息存储到上下文中
-		c.Set("user_id", claims.UserID)
-		c.Set("username", claims.Username)
-		c.Next()
+// authenticate 解析令牌并检查令牌用途，将用户信息存储到上下文中
+func authenticate(c *gin.Context, cfg *config.Config, tokenString, scope string) {
+	// 解析令牌
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(cfg.JWT.Secret), nil
+	})
+
+	if err != nil || !token.Valid || claims.Scope != scope {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的令牌"})
+		c.Abort()
+		return
 	}
+
+	// 将用户信息存储到上下文中
+	c.Set("user_id", claims.UserID)
+	c.Set("username", claims.Username)
+	c.Next()
 }
 
 func GenerateToken(user *models.User, cfg *config.Config) (string, error) {
@@ -68,4 +106,22 @@ func GenerateToken(user *models.User, cfg *config.Config) (string, error) {
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString([]byte(cfg.JWT.Secret))
-} 
\ No newline at end of file
+}
+
+// GenerateStreamToken 生成实时事件流令牌，有效期为 StreamTokenLifetime，只能用于建立事件流连接
+func GenerateStreamToken(userID uint, username string, cfg *config.Config) (string, time.Time, error) {
+	now := time.Now()
+	expirationTime := now.Add(StreamTokenLifetime)
+	claims := &Claims{
+		UserID:   userID,
+		Username: username,
+		Scope:    TokenScopeStream,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expirationTime),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(cfg.JWT.Secret))
+	return token, expirationTime, err
+}
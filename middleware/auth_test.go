@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gin-web-api/config"
+	"gin-web-api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestStreamTokenAuthentication(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{JWT: config.JWTConfig{Secret: "test-secret", ExpireHours: 24}}
+
+	loginToken, err := GenerateToken(&models.User{ID: 7, Username: "user7"}, cfg)
+	if err != nil {
+		t.Fatalf("GenerateToken() error = %v", err)
+	}
+	streamToken, _, err := GenerateStreamToken(7, "user7", cfg)
+	if err != nil {
+		t.Fatalf("GenerateStreamToken() error = %v", err)
+	}
+
+	router := gin.New()
+	handler := func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"user_id": c.GetUint("user_id")}) }
+	router.GET("/stream", JWTStreamMiddleware(cfg), handler)
+	router.GET("/api", JWTMiddleware(cfg), handler)
+
+	tests := []struct {
+		name          string
+		path          string
+		authorization string
+		wantStatus    int
+	}{
+		{"事件流使用查询参数中的事件流令牌", "/stream?token=" + streamToken, "", http.StatusOK},
+		{"事件流使用请求头中的登录令牌", "/stream", "Bearer " + loginToken, http.StatusOK},
+		{"事件流不接受查询参数中的登录令牌", "/stream?token=" + loginToken, "", http.StatusUnauthorized},
+		{"事件流缺少令牌", "/stream", "", http.StatusUnauthorized},
+		{"事件流令牌不能访问接口", "/api", "Bearer " + streamToken, http.StatusUnauthorized},
+		{"登录令牌访问接口", "/api", "Bearer " + loginToken, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+			if recorder.Code != tt.wantStatus {
+				t.Errorf("状态码 = %d, want %d: %s", recorder.Code, tt.wantStatus, recorder.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+)
+
+// 分片上传状态
+const (
+	FileUploadStatusUploading = "uploading" // 上传中
+	FileUploadStatusMerging   = "merging"   // 已上传全部分片，正在合并保存
+	FileUploadStatusCompleted = "completed" // 已完成，文件已保存
+	FileUploadStatusFailed    = "failed"    // 文件类型检查未通过，分片已删除
+)
+
+// FileObject 上传到文件字段的文件，提交表单数据时关联到表单数据，表单值中以 file_key 引用
+type FileObject struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	FileKey     string    `json:"file_key" gorm:"uniqueIndex;not null"`      // 文件标识（随机生成）
+	StorageKey  string    `json:"-" gorm:"not null"`                         // 存储中的对象键
+	FileName    string    `json:"file_name" gorm:"not null"`                 // 原始文件名
+	ContentType string    `json:"content_type"`                              // 按文件内容识别的MIME类型
+	Size        int64     `json:"size"`                                      // 文件大小（字节）
+	Checksum    string    `json:"checksum"`                                  // SHA-256
+	FormID      uint      `json:"form_id" gorm:"index"`                      // 上传到的表单定义ID
+	FieldKey    string    `json:"field_key"`                                 // 上传到的字段标识
+	FormDataID  *uint     `json:"form_data_id" gorm:"index"`                 // 关联的表单数据，提交前为空
+	UploadedBy  uint      `json:"uploaded_by"`                               // 上传人ID
+	Uploader    User      `json:"uploader" gorm:"foreignKey:UploadedBy"`     // 上传人信息
+	CreatedAt   time.Time `json:"created_at" gorm:"index"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// FileUpload 分片上传（断点续传）：按顺序上传分片，全部上传后合并保存为文件
+type FileUpload struct {
+	ID          uint        `json:"id" gorm:"primaryKey"`
+	UploadKey   string      `json:"upload_key" gorm:"uniqueIndex;not null"`  // 上传标识（随机生成）
+	FormID      uint        `json:"form_id"`                                 // 上传到的表单定义ID
+	FieldKey    string      `json:"field_key"`                               // 上传到的字段标识
+	FileName    string      `json:"file_name" gorm:"not null"`               // 原始文件名
+	Size        int64       `json:"size"`                                    // 文件总大小（字节）
+	Offset      int64       `json:"offset"`                                  // 已上传的字节数
+	Parts       int         `json:"parts"`                                   // 已上传的分片数
+	Status      string      `json:"status" gorm:"default:uploading;index"`   // 状态
+	FileID      *uint       `json:"file_id"`                                 // 完成后保存的文件
+	File        *FileObject `json:"file,omitempty" gorm:"foreignKey:FileID"` // 完成后保存的文件
+	UploadedBy  uint        `json:"uploaded_by"`                             // 上传人ID
+	ExpiresAt   time.Time   `json:"expires_at"`                              // 过期时间，过期未完成的上传会被清理
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+}
@@ -54,6 +54,9 @@ type FormAttribute struct {
 	DefaultValue  string         `json:"default_value"`                             // 默认值
 	Options       string         `json:"options"`                                   // 选项配置(JSON)
 	Validation    string         `json:"validation"`                                // 验证规则(JSON)
+	Rules         string         `json:"rules"`                                     // 条件规则(JSON)
+	DataSource    string         `json:"data_source"`                               // 选项数据源(JSON)
+	Columns       string         `json:"columns"`                                   // 明细表列定义(JSON)
 	CreatedAt     time.Time      `json:"created_at"`
 	UpdatedAt     time.Time      `json:"updated_at"`
 	DeletedAt     gorm.DeletedAt `json:"-" gorm:"index"`
@@ -99,6 +102,7 @@ type FormData struct {
 	Instance     WorkflowInstance `json:"instance" gorm:"foreignKey:InstanceID"` // 工作流实例
 	BusinessKey  string         `json:"business_key"`                           // 业务标识
 	FormValues   string         `json:"form_values"`                            // 表单值(JSON)
+	Version      int            `json:"version" gorm:"default:1"`               // 版本号，每次修改表单值加1（乐观锁）
 	Status       string         `json:"status" gorm:"default:draft"`            // 状态
 	SubmittedBy  uint           `json:"submitted_by"`                           // 提交人ID
 	Submitter    User           `json:"submitter" gorm:"foreignKey:SubmittedBy"` // 提交人信息
@@ -108,6 +112,22 @@ type FormData struct {
 	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
+// FormDataChange 表单数据的字段变更记录：发起、审批人修改和重新提交时每个变化的字段一条
+type FormDataChange struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	FormDataID uint      `json:"form_data_id" gorm:"index"`               // 表单数据ID
+	InstanceID uint      `json:"instance_id" gorm:"index"`                // 工作流实例ID
+	TaskID     *uint     `json:"task_id"`                                 // 修改时处理的任务ID
+	NodeKey    string    `json:"node_key"`                                // 修改时所在节点，发起和重新提交时为空
+	FieldKey   string    `json:"field_key" gorm:"not null"`               // 字段标识
+	OldValue   string    `json:"old_value"`                               // 原值(JSON)，新增字段时为空
+	NewValue   string    `json:"new_value"`                               // 新值(JSON)，删除字段时为空
+	Version    int       `json:"version"`                                 // 修改后的表单数据版本
+	ChangedBy  uint      `json:"changed_by"`                              // 修改人ID
+	Changer    User      `json:"changer" gorm:"foreignKey:ChangedBy"`     // 修改人信息
+	CreatedAt  time.Time `json:"created_at"`
+}
+
 // 表单元素类型常量
 const (
 	ElementTypeInput      = "input"       // 文本输入
@@ -121,6 +141,7 @@ const (
 	ElementTypeFile       = "file"        // 文件上传
 	ElementTypeCascader   = "cascader"    // 级联选择
 	ElementTypeTreeSelect = "treeSelect"  // 树形选择
+	ElementTypeTable      = "table"       // 明细表
 )
 
 // 数据类型常量
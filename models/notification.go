@@ -0,0 +1,85 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 通知事件：任务创建和实例结束沿用工作流事件类型，另有超时提醒和超时升级
+const (
+	NotificationTaskReminder  = "task.reminder"  // 任务超时提醒
+	NotificationTaskEscalated = "task.escalated" // 任务超时升级或转交给新的处理人
+)
+
+// NotificationEvents 全部通知事件
+var NotificationEvents = []string{
+	EventTaskCreated, NotificationTaskReminder, NotificationTaskEscalated,
+	EventInstanceApproved, EventInstanceRejected, EventInstanceCancelled,
+}
+
+// 通知渠道
+const (
+	NotificationChannelInbox   = "inbox"   // 站内信
+	NotificationChannelEmail   = "email"   // 邮件
+	NotificationChannelWebhook = "webhook" // 推送到用户配置的地址
+)
+
+// NotificationStatus 通知发送状态
+type NotificationStatus string
+
+const (
+	NotificationStatusPending NotificationStatus = "pending" // 等待发送（含等待重试）
+	NotificationStatusRunning NotificationStatus = "running" // 发送中
+	NotificationStatusSent    NotificationStatus = "sent"    // 已发送
+	NotificationStatusFailed  NotificationStatus = "failed"  // 重试用尽仍失败
+)
+
+// Notification 通知记录：每次发送一条，站内信渠道的记录即收件箱内容
+type Notification struct {
+	ID          uint               `json:"id" gorm:"primaryKey"`
+	UserID      uint               `json:"user_id" gorm:"index"`                // 接收人ID
+	Channel     string             `json:"channel" gorm:"index"`                // 通知渠道
+	Event       string             `json:"event"`                               // 通知事件
+	InstanceID  uint               `json:"instance_id" gorm:"index"`            // 实例ID
+	TaskID      *uint              `json:"task_id"`                             // 任务ID
+	Target      string             `json:"target"`                              // 接收地址（邮箱、推送地址）
+	Subject     string             `json:"subject"`                             // 标题
+	Body        string             `json:"body"`                                // 内容
+	Status      NotificationStatus `json:"status" gorm:"default:pending;index"` // 发送状态
+	Attempts    int                `json:"attempts"`                            // 已尝试次数
+	NextRunAt   time.Time          `json:"next_run_at" gorm:"index"`            // 下次发送时间
+	LockedUntil *time.Time         `json:"locked_until"`                        // 发送中的锁定期限
+	LastError   string             `json:"last_error"`                          // 最近一次错误
+	SentAt      *time.Time         `json:"sent_at"`                             // 发送成功时间
+	ReadAt      *time.Time         `json:"read_at"`                             // 已读时间（站内信）
+	CreatedAt   time.Time          `json:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+}
+
+// NotificationTemplate 通知模板：可按工作流、节点和渠道配置，越具体的模板优先
+type NotificationTemplate struct {
+	ID         uint           `json:"id" gorm:"primaryKey"`
+	Event      string         `json:"event" gorm:"not null;index"` // 通知事件
+	WorkflowID *uint          `json:"workflow_id" gorm:"index"`    // 工作流（版本系列）ID，为空适用于全部工作流
+	NodeKey    string         `json:"node_key"`                    // 节点标识，为空适用于全部节点
+	Channel    string         `json:"channel"`                     // 通知渠道，为空适用于全部渠道
+	Subject    string         `json:"subject"`                     // 标题模板，支持 ${instance.title} 等占位符
+	Body       string         `json:"body"`                        // 内容模板
+	CreatedBy  uint           `json:"created_by"`                  // 创建人ID
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// NotificationPreference 用户通知偏好：按渠道和事件开关，事件为空表示该渠道的全部事件
+type NotificationPreference struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"uniqueIndex:idx_notification_preference"` // 用户ID
+	Channel   string    `json:"channel" gorm:"uniqueIndex:idx_notification_preference"` // 通知渠道
+	Event     string    `json:"event" gorm:"uniqueIndex:idx_notification_preference"`   // 通知事件
+	Enabled   bool      `json:"enabled"`                                                // 是否接收
+	Target    string    `json:"target"`                                                 // 接收地址，为空时使用渠道默认地址（如用户邮箱）
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
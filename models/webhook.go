@@ -0,0 +1,84 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// WebhookSubscription 事件订阅：将工作流事件推送到外部系统
+type WebhookSubscription struct {
+	ID          uint           `json:"id" gorm:"primaryKey"`
+	Name        string         `json:"name" gorm:"not null"`           // 订阅名称
+	URL         string         `json:"url" gorm:"not null"`            // 推送地址
+	Secret      string         `json:"-"`                              // 签名密钥，仅在创建和重置时返回
+	Events      string         `json:"events"`                         // 订阅的事件(JSON数组)，为空订阅全部事件
+	WorkflowID  *uint          `json:"workflow_id"`                    // 只推送该工作流（版本系列）的事件，为空推送全部工作流
+	MaxAttempts int            `json:"max_attempts" gorm:"default:6"`  // 每次推送最多尝试次数
+	IsActive    bool           `json:"is_active" gorm:"default:true"`  // 是否启用
+	CreatedBy   uint           `json:"created_by"`                     // 创建人ID
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// GetEvents 获取订阅的事件列表
+func (w *WebhookSubscription) GetEvents() []string {
+	var events []string
+	if w.Events != "" {
+		json.Unmarshal([]byte(w.Events), &events)
+	}
+	return events
+}
+
+// Matches 判断订阅是否接收指定工作流版本系列的事件
+func (w *WebhookSubscription) Matches(eventType string, lineageID uint) bool {
+	if !w.IsActive {
+		return false
+	}
+	if w.WorkflowID != nil && *w.WorkflowID != lineageID {
+		return false
+	}
+	events := w.GetEvents()
+	if len(events) == 0 {
+		return true
+	}
+	for _, event := range events {
+		if event == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDeliveryStatus 推送状态
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "pending"   // 等待推送（含等待重试）
+	WebhookDeliveryRunning   WebhookDeliveryStatus = "running"   // 推送中
+	WebhookDeliverySucceeded WebhookDeliveryStatus = "succeeded" // 推送成功
+	WebhookDeliveryFailed    WebhookDeliveryStatus = "failed"    // 重试用尽仍失败
+)
+
+// WebhookDelivery 推送记录
+type WebhookDelivery struct {
+	ID             uint                  `json:"id" gorm:"primaryKey"`
+	SubscriptionID uint                  `json:"subscription_id" gorm:"index"`            // 订阅ID
+	EventID        uint                  `json:"event_id" gorm:"index"`                   // 事件ID
+	EventType      string                `json:"event_type"`                              // 事件类型
+	Payload        string                `json:"payload"`                                 // 推送内容(JSON)
+	Status         WebhookDeliveryStatus `json:"status" gorm:"default:pending;index"`     // 推送状态
+	Attempts       int                   `json:"attempts"`                                // 已尝试次数
+	MaxAttempts    int                   `json:"max_attempts"`                            // 最多尝试次数
+	NextRunAt      time.Time             `json:"next_run_at" gorm:"index"`                // 下次推送时间
+	LockedUntil    *time.Time            `json:"locked_until"`                            // 推送中的锁定期限
+	ResponseStatus int                   `json:"response_status"`                         // 最近一次响应状态码
+	ResponseBody   string                `json:"response_body"`                           // 最近一次响应内容
+	LastError      string                `json:"last_error"`                              // 最近一次错误
+	RedeliveryOf   *uint                 `json:"redelivery_of"`                           // 人工重新推送的原推送记录ID
+	DeliveredAt    *time.Time            `json:"delivered_at"`                            // 推送成功时间
+	CreatedAt      time.Time             `json:"created_at"`
+	UpdatedAt      time.Time             `json:"updated_at"`
+}
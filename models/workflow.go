@@ -23,6 +23,9 @@ type WorkflowDefinition struct {
 	Description string         `json:"description"`                             // 描述
 	Category    string         `json:"category"`                                // 分类
 	Version     int            `json:"version" gorm:"default:1"`                // 版本号
+	LineageID   uint           `json:"lineage_id" gorm:"index"`                 // 版本系列ID（首个版本的ID）
+	PublishedAt *time.Time     `json:"published_at"`                            // 发布时间，发布后版本不可修改
+	PublishedBy *uint          `json:"published_by"`                            // 发布人ID
 	Status      WorkflowStatus `json:"status" gorm:"default:draft"`             // 状态
 	IsDefault   bool           `json:"is_default" gorm:"default:false"`         // 是否默认版本
 	FormID      *uint          `json:"form_id"`                                 // 关联表单ID
@@ -49,6 +52,7 @@ const (
 	NodeTypeCondition NodeType = "condition" // 条件节点
 	NodeTypeParallel  NodeType = "parallel"  // 并行节点
 	NodeTypeMerge     NodeType = "merge"     // 合并节点
+	NodeTypeService   NodeType = "service"   // 服务节点（自动调用HTTP接口）
 )
 
 // ApprovalMode 审批模式
@@ -80,12 +84,101 @@ type WorkflowNode struct {
 	Settings         string            `json:"settings"`                                         // 其他设置(JSON)
 	NextNodes        string            `json:"next_nodes"`                                       // 下一个节点(JSON数组)
 	FormConditions   string            `json:"form_conditions"`                                  // 表单条件(JSON)
+	FieldPermissions string            `json:"field_permissions"`                                // 表单字段权限(JSON, 字段标识 -> 权限)
 	SortOrder        int               `json:"sort_order" gorm:"default:0"`                      // 排序
 	CreatedAt        time.Time         `json:"created_at"`
 	UpdatedAt        time.Time         `json:"updated_at"`
 	DeletedAt        gorm.DeletedAt    `json:"-" gorm:"index"`
 }
 
+// 表单字段权限（WorkflowNode.FieldPermissions）
+const (
+	FieldPermissionEditable = "editable" // 可编辑
+	FieldPermissionReadonly = "readonly" // 只读
+	FieldPermissionHidden   = "hidden"   // 隐藏
+)
+
+// NodeSettings 节点其他设置（WorkflowNode.Settings）
+type NodeSettings struct {
+	CompleteCount int    `json:"complete_count,omitempty"` // 合并节点：需完成的分支数，0表示全部分支
+	RejectAction  string `json:"reject_action,omitempty"`  // 审批节点：拒绝处理方式
+	RejectTarget  string `json:"reject_target,omitempty"`  // 审批节点：退回的目标节点（reject_action=node）
+	SLA           *SLASettings `json:"sla,omitempty"`      // 审批节点：处理时限
+	RecallRule    string `json:"recall_rule,omitempty"`    // 审批节点：发起人撤回规则
+	Service       *ServiceSettings `json:"service,omitempty"` // 服务节点：HTTP调用设置
+}
+
+// 发起人撤回规则
+const (
+	RecallRuleNotActed = "not_acted" // 当前节点无人处理时可撤回（默认）
+	RecallRuleAlways   = "always"    // 始终可撤回
+	RecallRuleNever    = "never"     // 不可撤回
+)
+
+// SLASettings 审批节点处理时限设置
+type SLASettings struct {
+	Duration       string            `json:"duration"`                  // 处理时限，如 "4h"、"30m"、"2d"
+	Calendar       *BusinessCalendar `json:"calendar,omitempty"`        // 工作时间日历，为空时按自然时间计算
+	Action         string            `json:"action"`                    // 超时处理方式
+	RemindInterval string            `json:"remind_interval,omitempty"` // 超时提醒的重复间隔，为空只提醒一次
+	ReassignTo     uint              `json:"reassign_to,omitempty"`     // 超时转交的用户ID（action=reassign）
+	Priority       int               `json:"priority,omitempty"`        // 任务优先级
+}
+
+// ServiceSettings 服务节点HTTP调用设置，url、headers、body 中的 ${form.xxx}、${variables.xxx}、
+// ${initiator.xxx}、${instance.xxx} 会替换为对应的值
+type ServiceSettings struct {
+	URL             string            `json:"url"`                        // 请求地址
+	Method          string            `json:"method,omitempty"`           // 请求方法，默认 POST
+	Headers         map[string]string `json:"headers,omitempty"`          // 请求头
+	Body            json.RawMessage   `json:"body,omitempty"`             // 请求体模板(JSON)
+	Timeout         string            `json:"timeout,omitempty"`          // 超时时间，默认 "30s"
+	MaxRetries      int               `json:"max_retries,omitempty"`      // 失败后的重试次数，默认 3
+	RetryBackoff    string            `json:"retry_backoff,omitempty"`    // 首次重试间隔，之后每次翻倍，默认 "30s"
+	ResponseMapping map[string]string `json:"response_mapping,omitempty"` // 响应字段映射：流程变量名 -> 响应JSON中的字段路径
+	ErrorBranch     string            `json:"error_branch,omitempty"`     // 调用失败时执行的分支，节点只有一个分支时可不填
+}
+
+// 超时处理方式
+const (
+	SLAActionRemind      = "remind"       // 提醒处理人
+	SLAActionEscalate    = "escalate"     // 升级给处理人的部门负责人
+	SLAActionAutoApprove = "auto_approve" // 自动通过
+	SLAActionAutoReject  = "auto_reject"  // 自动拒绝
+	SLAActionReassign    = "reassign"     // 转交给指定用户
+)
+
+// BusinessCalendar 工作时间日历
+type BusinessCalendar struct {
+	Timezone      string   `json:"timezone,omitempty"`       // 时区，如 "Asia/Shanghai"，默认服务器时区
+	WorkDays      []int    `json:"work_days,omitempty"`      // 工作日(0为周日)，默认周一至周五
+	StartTime     string   `json:"start_time,omitempty"`     // 上班时间，默认 "09:00"
+	EndTime       string   `json:"end_time,omitempty"`       // 下班时间，默认 "18:00"
+	Holidays      []string `json:"holidays,omitempty"`       // 节假日，如 "2024-10-01"
+	ExtraWorkdays []string `json:"extra_workdays,omitempty"` // 调休上班日，如 "2024-10-12"
+}
+
+// SystemOperatorID 系统自动操作（如超时自动处理）在历史记录中的操作人ID
+const SystemOperatorID uint = 0
+
+// 拒绝处理方式
+const (
+	RejectActionTerminate = "terminate" // 终止流程
+	RejectActionPrevious  = "previous"  // 退回上一审批节点
+	RejectActionNode      = "node"      // 退回执行路径上的指定节点
+	RejectActionInitiator = "initiator" // 退回发起人重新提交
+)
+
+// ParseSettings 解析节点设置
+func (n *WorkflowNode) ParseSettings() (*NodeSettings, error) {
+	var settings NodeSettings
+	if n.Settings == "" {
+		return &settings, nil
+	}
+	err := json.Unmarshal([]byte(n.Settings), &settings)
+	return &settings, err
+}
+
 // WorkflowBranch 工作流分支节点
 type WorkflowBranch struct {
 	ID           uint           `json:"id" gorm:"primaryKey"`
@@ -129,8 +222,11 @@ type WorkflowInstance struct {
 	CurrentNodes       string           `json:"current_nodes"`                                  // 当前节点(JSON数组)
 	ExecutionPath      string           `json:"execution_path"`                                 // 执行路径(JSON)
 	Variables          string           `json:"variables"`                                      // 流程变量(JSON)
+	ApprovalChains     string           `json:"approval_chains"`                                // 依次审批人顺序(JSON, 节点标识 -> 审批人ID列表)
 	StartTime          time.Time        `json:"start_time"`                                     // 开始时间
 	EndTime            *time.Time       `json:"end_time"`                                       // 结束时间
+	SuspendedAt        *time.Time       `json:"suspended_at"`                                   // 挂起时间
+	WorkflowVersion    int              `json:"workflow_version"`                               // 启动时的工作流版本号
 	InitiatorID        uint             `json:"initiator_id"`                                   // 发起人ID
 	Initiator          User             `json:"initiator" gorm:"foreignKey:InitiatorID"`       // 发起人信息
 	Tasks              []WorkflowTask   `json:"tasks" gorm:"foreignKey:InstanceID"`             // 任务列表
@@ -139,6 +235,110 @@ type WorkflowInstance struct {
 	DeletedAt          gorm.DeletedAt   `json:"-" gorm:"index"`
 }
 
+// 执行路径动作
+const (
+	ExecutionActionEnter  = "enter"  // 进入节点
+	ExecutionActionFork   = "fork"   // 并行分叉
+	ExecutionActionArrive = "arrive" // 分支到达汇聚点
+	ExecutionActionJoin   = "join"   // 分支汇聚
+	ExecutionActionJump   = "jump"   // 退回跳转
+)
+
+// ExecutionStep 执行路径步骤
+type ExecutionStep struct {
+	NodeKey   string    `json:"node_key"`
+	NodeName  string    `json:"node_name"`
+	NodeType  NodeType  `json:"node_type"`
+	Action    string    `json:"action"`
+	BranchKey string    `json:"branch_key,omitempty"`
+	Time      time.Time `json:"time"`
+}
+
+// GetCurrentNodes 获取当前活动节点
+func (i *WorkflowInstance) GetCurrentNodes() []string {
+	var nodes []string
+	if i.CurrentNodes != "" {
+		json.Unmarshal([]byte(i.CurrentNodes), &nodes)
+	}
+	return nodes
+}
+
+// SetCurrentNodes 设置当前活动节点
+func (i *WorkflowInstance) SetCurrentNodes(nodes []string) {
+	if nodes == nil {
+		nodes = []string{}
+	}
+	data, _ := json.Marshal(nodes)
+	i.CurrentNodes = string(data)
+}
+
+// AddCurrentNode 添加活动节点（并行分支时存在多个活动节点）
+func (i *WorkflowInstance) AddCurrentNode(nodeKey string) {
+	nodes := i.GetCurrentNodes()
+	for _, key := range nodes {
+		if key == nodeKey {
+			return
+		}
+	}
+	i.SetCurrentNodes(append(nodes, nodeKey))
+}
+
+// RemoveCurrentNodes 移除活动节点
+func (i *WorkflowInstance) RemoveCurrentNodes(nodeKeys ...string) {
+	removed := make(map[string]bool, len(nodeKeys))
+	for _, key := range nodeKeys {
+		removed[key] = true
+	}
+
+	nodes := make([]string, 0)
+	for _, key := range i.GetCurrentNodes() {
+		if !removed[key] {
+			nodes = append(nodes, key)
+		}
+	}
+	i.SetCurrentNodes(nodes)
+}
+
+// GetApprovalChains 获取各节点的依次审批人顺序
+func (i *WorkflowInstance) GetApprovalChains() map[string][]uint {
+	chains := make(map[string][]uint)
+	if i.ApprovalChains != "" {
+		json.Unmarshal([]byte(i.ApprovalChains), &chains)
+	}
+	return chains
+}
+
+// GetApprovalChain 获取节点的依次审批人顺序
+func (i *WorkflowInstance) GetApprovalChain(nodeKey string) []uint {
+	return i.GetApprovalChains()[nodeKey]
+}
+
+// SetApprovalChain 设置节点的依次审批人顺序
+func (i *WorkflowInstance) SetApprovalChain(nodeKey string, assignees []uint) {
+	chains := i.GetApprovalChains()
+	chains[nodeKey] = assignees
+	data, _ := json.Marshal(chains)
+	i.ApprovalChains = string(data)
+}
+
+// GetExecutionPath 获取执行路径
+func (i *WorkflowInstance) GetExecutionPath() []ExecutionStep {
+	var steps []ExecutionStep
+	if i.ExecutionPath != "" {
+		json.Unmarshal([]byte(i.ExecutionPath), &steps)
+	}
+	return steps
+}
+
+// AppendExecutionStep 追加执行路径步骤
+func (i *WorkflowInstance) AppendExecutionStep(step ExecutionStep) {
+	if step.Time.IsZero() {
+		step.Time = time.Now()
+	}
+	data, _ := json.Marshal(append(i.GetExecutionPath(), step))
+	i.ExecutionPath = string(data)
+}
+
 // TaskStatus 任务状态
 type TaskStatus string
 
@@ -149,8 +349,30 @@ const (
 	TaskStatusSkipped   TaskStatus = "skipped"   // 已跳过
 	TaskStatusCancelled TaskStatus = "cancelled" // 已取消
 	TaskStatusClaimed   TaskStatus = "claimed"   // 已认领
+	TaskStatusWaiting   TaskStatus = "waiting"   // 等待中（等待加签人处理）
 )
 
+// OpenTaskStatuses 尚未处理完成的任务状态
+var OpenTaskStatuses = []TaskStatus{TaskStatusPending, TaskStatusClaimed, TaskStatusWaiting}
+
+// ActionableTaskStatuses 处理人可以审批的任务状态
+var ActionableTaskStatuses = []TaskStatus{TaskStatusPending, TaskStatusClaimed}
+
+// IsOpen 任务是否尚未处理完成
+func (s TaskStatus) IsOpen() bool {
+	for _, status := range OpenTaskStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// IsActionable 任务是否可由处理人审批
+func (s TaskStatus) IsActionable() bool {
+	return s == TaskStatusPending || s == TaskStatusClaimed
+}
+
 // WorkflowTask 工作流任务
 type WorkflowTask struct {
 	ID           uint           `json:"id" gorm:"primaryKey"`
@@ -159,18 +381,65 @@ type WorkflowTask struct {
 	NodeKey      string         `json:"node_key" gorm:"not null"`                     // 节点标识
 	NodeName     string         `json:"node_name" gorm:"not null"`                    // 节点名称
 	AssigneeID   uint           `json:"assignee_id"`                                  // 处理人ID
-	Assignee     User           `json:"assignee" gorm:"foreignKey:AssigneeID"`        // 处理人信息
+	Assignee     User           `json:"assignee" gorm:"foreignKey:AssigneeID;constraint:-"` // 处理人信息（待认领任务没有处理人）
+	DelegatorID  *uint          `json:"delegator_id"`                                 // 委托人ID（被委托人处理后任务交还委托人）
+	ParentTaskID *uint          `json:"parent_task_id"`                               // 加签来源任务ID
+	SignType     string         `json:"sign_type"`                                    // 加签类型(before/after)
 	Status       TaskStatus     `json:"status" gorm:"default:pending"`                // 任务状态
 	Comment      string         `json:"comment"`                                      // 处理意见
 	FormValues   string         `json:"form_values"`                                  // 表单提交值(JSON)
 	ProcessTime  *time.Time     `json:"process_time"`                                 // 处理时间
 	DueTime      *time.Time     `json:"due_time"`                                     // 截止时间
+	SLACheckAt   *time.Time     `json:"sla_check_at" gorm:"index"`                    // 下次超时检查时间
 	Priority     int            `json:"priority" gorm:"default:0"`                    // 优先级
 	CreatedAt    time.Time      `json:"created_at"`
 	UpdatedAt    time.Time      `json:"updated_at"`
 	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
+// WorkflowTaskCandidate 待认领任务的候选人
+type WorkflowTaskCandidate struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	TaskID    uint      `json:"task_id" gorm:"index;not null"` // 任务ID
+	UserID    uint      `json:"user_id" gorm:"index;not null"` // 候选人ID
+	User      User      `json:"user" gorm:"foreignKey:UserID"` // 候选人信息
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ServiceJobStatus 服务节点调用任务状态
+type ServiceJobStatus string
+
+const (
+	ServiceJobStatusPending   ServiceJobStatus = "pending"   // 等待执行（含等待重试）
+	ServiceJobStatusRunning   ServiceJobStatus = "running"   // 执行中
+	ServiceJobStatusSucceeded ServiceJobStatus = "succeeded" // 调用成功
+	ServiceJobStatusFailed    ServiceJobStatus = "failed"    // 重试用尽仍失败
+	ServiceJobStatusCancelled ServiceJobStatus = "cancelled" // 实例已结束或已离开节点，不再执行
+)
+
+// WorkflowServiceJob 服务节点调用任务，由后台任务在事务外执行HTTP调用
+type WorkflowServiceJob struct {
+	ID             uint             `json:"id" gorm:"primaryKey"`
+	InstanceID     uint             `json:"instance_id" gorm:"index"`                 // 实例ID
+	NodeKey        string           `json:"node_key" gorm:"not null"`                 // 节点标识
+	NodeName       string           `json:"node_name"`                                // 节点名称
+	Status         ServiceJobStatus `json:"status" gorm:"default:pending;index"`      // 任务状态
+	Method         string           `json:"method"`                                   // 请求方法
+	URL            string           `json:"url"`                                      // 请求地址
+	Headers        string           `json:"headers"`                                  // 请求头(JSON)
+	RequestBody    string           `json:"request_body"`                             // 请求体
+	Attempts       int              `json:"attempts"`                                 // 已执行次数
+	MaxAttempts    int              `json:"max_attempts"`                             // 最多执行次数
+	NextRunAt      time.Time        `json:"next_run_at" gorm:"index"`                 // 下次执行时间
+	LockedUntil    *time.Time       `json:"locked_until"`                             // 执行中的锁定期限，超过后视为执行中断，可重新执行
+	ResponseStatus int              `json:"response_status"`                          // 最近一次响应状态码
+	ResponseBody   string           `json:"response_body"`                            // 最近一次响应内容
+	LastError      string           `json:"last_error"`                               // 最近一次错误
+	CompletedAt    *time.Time       `json:"completed_at"`                             // 完成时间
+	CreatedAt      time.Time        `json:"created_at"`
+	UpdatedAt      time.Time        `json:"updated_at"`
+}
+
 // WorkflowHistory 工作流历史记录
 type WorkflowHistory struct {
 	ID          uint           `json:"id" gorm:"primaryKey"`
@@ -180,7 +449,7 @@ type WorkflowHistory struct {
 	NodeName    string         `json:"node_name"`                                // 节点名称
 	Action      string         `json:"action"`                                   // 操作类型
 	OperatorID  uint           `json:"operator_id"`                              // 操作人ID
-	Operator    User           `json:"operator" gorm:"foreignKey:OperatorID"`    // 操作人信息
+	Operator    User           `json:"operator" gorm:"foreignKey:OperatorID;constraint:-"` // 操作人信息（系统自动操作时为空）
 	Comment     string         `json:"comment"`                                  // 操作备注
 	FormValues  string         `json:"form_values"`                              // 表单值(JSON)
 	Variables   string         `json:"variables"`                                // 变量信息(JSON)
@@ -188,15 +457,123 @@ type WorkflowHistory struct {
 	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
+// 工作流事件类型
+const (
+	EventInstanceStarted   = "instance.started"   // 实例启动（含重新提交）
+	EventInstanceApproved  = "instance.approved"  // 实例审批通过
+	EventInstanceRejected  = "instance.rejected"  // 实例被拒绝
+	EventInstanceCancelled = "instance.cancelled" // 实例取消
+	EventInstanceSuspended = "instance.suspended" // 实例挂起
+	EventInstanceResumed   = "instance.resumed"   // 实例恢复
+	EventInstanceRecalled  = "instance.recalled"  // 实例被发起人撤回
+	EventTaskCreated       = "task.created"       // 任务创建
+	EventTaskAssigned      = "task.assigned"      // 任务处理人变化（转办、委托、认领、释放、超时升级）
+	EventTaskApproved      = "task.approved"      // 任务通过
+	EventTaskRejected      = "task.rejected"      // 任务拒绝
+)
+
+// WorkflowEvents 全部工作流事件类型
+var WorkflowEvents = []string{
+	EventInstanceStarted, EventInstanceApproved, EventInstanceRejected, EventInstanceCancelled,
+	EventInstanceSuspended, EventInstanceResumed, EventInstanceRecalled,
+	EventTaskCreated, EventTaskAssigned, EventTaskApproved, EventTaskRejected,
+}
+
+// WorkflowEvent 工作流事件（发件箱），与历史记录在同一事务中写入，由后台任务分发
+type WorkflowEvent struct {
+	ID           uint       `json:"id" gorm:"primaryKey"`
+	EventType    string     `json:"event_type" gorm:"index"`   // 事件类型
+	InstanceID   uint       `json:"instance_id" gorm:"index"`  // 实例ID
+	TaskID       *uint      `json:"task_id"`                   // 任务ID
+	LineageID    uint       `json:"lineage_id"`                // 工作流版本系列ID
+	Payload      string     `json:"payload"`                   // 事件内容(JSON)
+	DispatchedAt *time.Time `json:"dispatched_at" gorm:"index"` // 分发时间，为空表示尚未分发
+	StreamedAt   *time.Time `json:"streamed_at" gorm:"index"`   // 实时推送时间，为空表示尚未推送
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
 // NodeTreeData 节点树结构
 type NodeTreeData struct {
 	Key      string          `json:"key"`
 	Name     string          `json:"name"`
 	Type     NodeType        `json:"type"`
+	Props    *NodeProps      `json:"props,omitempty"` // 节点配置，仅用于导入导出，不保存在节点树中
 	Child    *NodeTreeData   `json:"child,omitempty"`
 	Branches []NodeTreeData  `json:"branches,omitempty"`
 }
 
+// NodeProps 节点配置，对应 WorkflowNode 的配置字段；分支只使用 conditions（WorkflowBranch.Conditions）
+type NodeProps struct {
+	ApprovalMode     ApprovalMode    `json:"approval_mode,omitempty"`     // 审批模式
+	Assignees        json.RawMessage `json:"assignees,omitempty"`         // 审批人配置
+	Conditions       json.RawMessage `json:"conditions,omitempty"`        // 节点条件或分支条件
+	Settings         json.RawMessage `json:"settings,omitempty"`          // 其他设置（拒绝处理、处理时限、撤回规则等）
+	FieldPermissions json.RawMessage `json:"field_permissions,omitempty"` // 表单字段权限
+}
+
+// WithoutProps 复制节点树结构，去掉节点配置
+func (n *NodeTreeData) WithoutProps() *NodeTreeData {
+	if n == nil {
+		return nil
+	}
+	copied := *n
+	copied.Props = nil
+	copied.Child = n.Child.WithoutProps()
+	if n.Branches != nil {
+		copied.Branches = make([]NodeTreeData, len(n.Branches))
+		for i := range n.Branches {
+			copied.Branches[i] = *n.Branches[i].WithoutProps()
+		}
+	}
+	return &copied
+}
+
+// IsEmpty 判断是否为空节点（如导入数据中的 "child": {}）
+func (n *NodeTreeData) IsEmpty() bool {
+	return n == nil || n.Key == ""
+}
+
+// FindPath 查找从当前节点到目标节点的路径（包含两端），未找到返回nil
+func (n *NodeTreeData) FindPath(key string) []*NodeTreeData {
+	if n.IsEmpty() {
+		return nil
+	}
+	if n.Key == key {
+		return []*NodeTreeData{n}
+	}
+	if path := n.Child.FindPath(key); path != nil {
+		return append([]*NodeTreeData{n}, path...)
+	}
+	for i := range n.Branches {
+		if path := n.Branches[i].FindPath(key); path != nil {
+			return append([]*NodeTreeData{n}, path...)
+		}
+	}
+	return nil
+}
+
+// Keys 获取子树中所有节点标识（包含自身）
+func (n *NodeTreeData) Keys() []string {
+	if n.IsEmpty() {
+		return nil
+	}
+	keys := []string{n.Key}
+	keys = append(keys, n.Child.Keys()...)
+	for i := range n.Branches {
+		keys = append(keys, n.Branches[i].Keys()...)
+	}
+	return keys
+}
+
+// FindNode 在节点树中查找节点
+func (n *NodeTreeData) FindNode(key string) *NodeTreeData {
+	path := n.FindPath(key)
+	if path == nil {
+		return nil
+	}
+	return path[len(path)-1]
+}
+
 // ParseNodeTree 解析节点树结构
 func (w *WorkflowDefinition) ParseNodeTree() (*NodeTreeData, error) {
 	if w.NodeData == "" {
@@ -208,9 +585,22 @@ func (w *WorkflowDefinition) ParseNodeTree() (*NodeTreeData, error) {
 	return &nodeTree, err
 }
 
-// SetNodeTree 设置节点树结构
+// GetLineageID 获取版本系列ID，旧数据没有记录时以自身ID为系列ID
+func (w *WorkflowDefinition) GetLineageID() uint {
+	if w.LineageID == 0 {
+		return w.ID
+	}
+	return w.LineageID
+}
+
+// IsPublished 是否已发布（已发布的版本不可修改）
+func (w *WorkflowDefinition) IsPublished() bool {
+	return w.PublishedAt != nil
+}
+
+// SetNodeTree 设置节点树结构，节点配置保存在节点记录中，不写入节点树
 func (w *WorkflowDefinition) SetNodeTree(nodeTree *NodeTreeData) error {
-	data, err := json.Marshal(nodeTree)
+	data, err := json.Marshal(nodeTree.WithoutProps())
 	if err != nil {
 		return err
 	}
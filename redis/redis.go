@@ -47,6 +47,34 @@ func Del(key string) error {
 	return Client.Del(ctx, key).Err()
 }
 
+// SetNX 仅在键不存在时设置，成功返回 true，可用作分布式锁
+func SetNX(key string, value interface{}, expiration time.Duration) (bool, error) {
+	return Client.SetNX(ctx, key, value, expiration).Result()
+}
+
+// releaseLockScript 仅当锁仍由自己持有（值相同）时删除
+var releaseLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// ReleaseLock 释放 SetNX 获取的锁，避免误删其他实例重新获取的锁
+func ReleaseLock(key, value string) error {
+	return releaseLockScript.Run(ctx, Client, []string{key}, value).Err()
+}
+
+// Publish 向频道发布消息
+func Publish(channel string, message interface{}) error {
+	return Client.Publish(ctx, channel, message).Err()
+}
+
+// Subscribe 订阅频道，使用完毕后需调用 Close
+func Subscribe(channels ...string) *redis.PubSub {
+	return Client.Subscribe(ctx, channels...)
+}
+
 func Exists(key string) (bool, error) {
 	count, err := Client.Exists(ctx, key).Result()
 	return count > 0, err
@@ -41,6 +41,7 @@ func SetupRoutes(cfg *config.Config) *gin.Engine {
 			auth.POST("/login", authHandler.Login)
 			auth.POST("/logout", middleware.JWTMiddleware(cfg), authHandler.Logout)
 			auth.GET("/profile", middleware.JWTMiddleware(cfg), authHandler.GetProfile)
+			auth.POST("/stream-token", middleware.JWTMiddleware(cfg), authHandler.StreamToken)
 		}
 
 		// 文章相关路由
@@ -13,6 +13,13 @@ func SetupWorkflowRoutes(r *gin.Engine, cfg *config.Config) {
 	workflowHandler := handlers.NewWorkflowHandler()
 	permissionHandler := handlers.NewPermissionHandler()
 	formHandler := handlers.NewFormHandler()
+	webhookHandler := handlers.NewWebhookHandler()
+	notificationHandler := handlers.NewNotificationHandler()
+	realtimeHandler := handlers.NewRealtimeHandler()
+	fileHandler := handlers.NewFileHandler()
+
+	// 实时事件流（SSE），支持通过 token 查询参数传递事件流令牌（/auth/stream-token）认证
+	r.GET("/api/v1/events/stream", middleware.JWTStreamMiddleware(cfg), realtimeHandler.Stream)
 
 	// API v1 路由组
 	api := r.Group("/api/v1")
@@ -86,6 +93,11 @@ func SetupWorkflowRoutes(r *gin.Engine, cfg *config.Config) {
 			middleware.RequirePermission(models.PermissionWorkflowRead), 
 			formHandler.RenderForm)
 		
+		// 查询字段选项
+		formGroup.GET("/:id/fields/:key/options", 
+			middleware.RequirePermission(models.PermissionWorkflowRead), 
+			formHandler.GetFieldOptions)
+		
 		// 验证表单数据
 		formGroup.POST("/validate", 
 			middleware.RequirePermission(models.PermissionInstanceCreate), 
@@ -134,6 +146,16 @@ func SetupWorkflowRoutes(r *gin.Engine, cfg *config.Config) {
 			middleware.RequirePermission(models.PermissionWorkflowCreate), 
 			workflowHandler.ImportWorkflowFromJSON)
 		
+		// 校验工作流定义（已保存的工作流或未保存的节点树）- 需要更新权限
+		workflowGroup.POST("/validate", 
+			middleware.RequirePermission(models.PermissionWorkflowUpdate), 
+			workflowHandler.ValidateWorkflow)
+		
+		// 模拟流程运行（不保留任何数据）- 需要更新权限
+		workflowGroup.POST("/simulate", 
+			middleware.RequirePermission(models.PermissionWorkflowUpdate), 
+			workflowHandler.SimulateWorkflow)
+		
 		// 获取工作流详情 - 需要读取权限
 		workflowGroup.GET("/:id", 
 			middleware.RequirePermission(models.PermissionWorkflowRead), 
@@ -144,10 +166,45 @@ func SetupWorkflowRoutes(r *gin.Engine, cfg *config.Config) {
 			middleware.RequirePermission(models.PermissionWorkflowRead), 
 			workflowHandler.GetWorkflowNodeTree)
 		
+		// 导出工作流定义
+		workflowGroup.GET("/:id/export", 
+			middleware.RequirePermission(models.PermissionWorkflowRead), 
+			workflowHandler.ExportWorkflow)
+		
 		// 更新工作流状态 - 需要部署权限
 		workflowGroup.PUT("/:id/status", 
 			middleware.RequirePermission(models.PermissionWorkflowDeploy), 
 			workflowHandler.UpdateWorkflowStatus)
+		
+		// 修改草稿版本 - 需要更新权限
+		workflowGroup.PUT("/:id", 
+			middleware.RequirePermission(models.PermissionWorkflowUpdate), 
+			workflowHandler.UpdateWorkflow)
+		
+		// 获取版本列表
+		workflowGroup.GET("/:id/versions", 
+			middleware.RequirePermission(models.PermissionWorkflowRead), 
+			workflowHandler.GetWorkflowVersions)
+		
+		// 基于当前版本创建新版本
+		workflowGroup.POST("/:id/versions", 
+			middleware.RequirePermission(models.PermissionWorkflowUpdate), 
+			workflowHandler.CreateWorkflowVersion)
+		
+		// 比较版本差异
+		workflowGroup.GET("/:id/diff", 
+			middleware.RequirePermission(models.PermissionWorkflowRead), 
+			workflowHandler.DiffWorkflowVersions)
+		
+		// 发布版本 - 需要部署权限
+		workflowGroup.POST("/:id/publish", 
+			middleware.RequirePermission(models.PermissionWorkflowDeploy), 
+			workflowHandler.PublishWorkflow)
+		
+		// 设为默认版本 - 需要部署权限
+		workflowGroup.PUT("/:id/default", 
+			middleware.RequirePermission(models.PermissionWorkflowDeploy), 
+			workflowHandler.PromoteWorkflowVersion)
 	}
 
 	// 工作流实例路由
@@ -176,15 +233,45 @@ func SetupWorkflowRoutes(r *gin.Engine, cfg *config.Config) {
 			middleware.CheckWorkflowInstancePermission("view_instance"), 
 			workflowHandler.GetInstanceFormData)
 		
+		// 获取实例表单数据各字段的变更历史
+		instanceGroup.GET("/:id/form-changes", 
+			middleware.CheckWorkflowInstancePermission("view_instance"), 
+			workflowHandler.GetInstanceFormChanges)
+		
 		// 取消实例 - 需要取消权限检查
 		instanceGroup.PUT("/:id/cancel", 
 			middleware.CheckWorkflowInstancePermission("cancel_instance"), 
 			workflowHandler.CancelInstance)
 		
+		// 撤回实例 - 仅发起人
+		instanceGroup.PUT("/:id/recall", 
+			middleware.CheckWorkflowInstancePermission("recall_instance"), 
+			workflowHandler.RecallInstance)
+		
+		// 重新提交已撤回的实例 - 仅发起人
+		instanceGroup.PUT("/:id/resubmit", 
+			middleware.CheckWorkflowInstancePermission("recall_instance"), 
+			workflowHandler.ResubmitInstance)
+		
+		// 挂起实例 - 需要挂起权限
+		instanceGroup.PUT("/:id/suspend", 
+			middleware.RequirePermission(models.PermissionInstanceSuspend), 
+			workflowHandler.SuspendInstance)
+		
+		// 恢复已挂起的实例 - 需要挂起权限
+		instanceGroup.PUT("/:id/resume", 
+			middleware.RequirePermission(models.PermissionInstanceSuspend), 
+			workflowHandler.ResumeInstance)
+		
 		// 获取实例历史记录
 		instanceGroup.GET("/:id/history", 
 			middleware.CheckWorkflowInstancePermission("view_instance"), 
 			workflowHandler.GetInstanceHistory)
+		
+		// 获取实例的服务调用记录
+		instanceGroup.GET("/:id/service-jobs", 
+			middleware.CheckWorkflowInstancePermission("view_instance"), 
+			workflowHandler.GetInstanceServiceJobs)
 	}
 
 	// 任务路由
@@ -193,6 +280,11 @@ func SetupWorkflowRoutes(r *gin.Engine, cfg *config.Config) {
 		// 获取我的待办任务
 		taskGroup.GET("/my", workflowHandler.GetMyTasks)
 		
+		// 获取任务的表单（按节点字段权限）
+		taskGroup.GET("/:id/form", 
+			middleware.CheckTaskPermission(), 
+			workflowHandler.GetTaskForm)
+		
 		// 审批任务 - 需要审批权限
 		taskGroup.POST("/:id/approve", 
 			middleware.RequirePermission(models.PermissionTaskApprove), 
@@ -204,6 +296,42 @@ func SetupWorkflowRoutes(r *gin.Engine, cfg *config.Config) {
 			middleware.RequirePermission(models.PermissionTaskReject), 
 			middleware.CheckTaskPermission(), 
 			workflowHandler.RejectTask)
+		
+		// 获取可退回的节点
+		taskGroup.GET("/:id/reject-targets", 
+			middleware.RequirePermission(models.PermissionTaskReject), 
+			middleware.CheckTaskPermission(), 
+			workflowHandler.GetRejectTargets)
+		
+		// 认领任务
+		taskGroup.POST("/:id/claim", 
+			middleware.RequirePermission(models.PermissionTaskClaim), 
+			middleware.CheckTaskPermission(), 
+			workflowHandler.ClaimTask)
+		
+		// 释放已认领的任务
+		taskGroup.POST("/:id/release", 
+			middleware.RequirePermission(models.PermissionTaskClaim), 
+			middleware.CheckTaskPermission(), 
+			workflowHandler.ReleaseTask)
+		
+		// 转办任务
+		taskGroup.POST("/:id/transfer", 
+			middleware.RequirePermission(models.PermissionInstanceTransfer), 
+			middleware.CheckTaskPermission(), 
+			workflowHandler.TransferTask)
+		
+		// 委托任务
+		taskGroup.POST("/:id/delegate", 
+			middleware.RequirePermission(models.PermissionTaskDelegate), 
+			middleware.CheckTaskPermission(), 
+			workflowHandler.DelegateTask)
+		
+		// 加签
+		taskGroup.POST("/:id/add-signer", 
+			middleware.RequirePermission(models.PermissionTaskDelegate), 
+			middleware.CheckTaskPermission(), 
+			workflowHandler.AddSigner)
 	}
 
 	// 统计信息路由
@@ -243,6 +371,65 @@ func SetupWorkflowRoutes(r *gin.Engine, cfg *config.Config) {
 
 		// 系统初始化
 		adminGroup.POST("/initialize", permissionHandler.InitializeData)
+
+		// 实例版本迁移
+		adminGroup.POST("/instances/migrate", workflowHandler.MigrateInstances)
+
+		// 重试失败的服务调用
+		adminGroup.POST("/service-jobs/:id/retry", workflowHandler.RetryServiceJob)
+
+		// 事件订阅（Webhook）
+		webhookGroup := adminGroup.Group("/webhooks")
+		{
+			webhookGroup.GET("", webhookHandler.GetWebhooks)
+			webhookGroup.POST("", webhookHandler.CreateWebhook)
+			webhookGroup.GET("/:id", webhookHandler.GetWebhook)
+			webhookGroup.PUT("/:id", webhookHandler.UpdateWebhook)
+			webhookGroup.DELETE("/:id", webhookHandler.DeleteWebhook)
+			webhookGroup.POST("/:id/rotate-secret", webhookHandler.RotateWebhookSecret)
+			webhookGroup.GET("/:id/deliveries", webhookHandler.GetDeliveries)
+			webhookGroup.POST("/:id/redeliver", webhookHandler.RedeliverFailed)
+			webhookGroup.POST("/deliveries/:id/redeliver", webhookHandler.Redeliver)
+		}
+
+		// 通知模板（可按工作流、节点和渠道配置）
+		notificationTemplateGroup := adminGroup.Group("/notification-templates")
+		{
+			notificationTemplateGroup.GET("", notificationHandler.GetTemplates)
+			notificationTemplateGroup.POST("", notificationHandler.CreateTemplate)
+			notificationTemplateGroup.PUT("/:id", notificationHandler.UpdateTemplate)
+			notificationTemplateGroup.DELETE("/:id", notificationHandler.DeleteTemplate)
+		}
+
+		// 通知发送记录
+		adminGroup.GET("/notifications", notificationHandler.GetNotificationLog)
+	}
+
+	// 站内信和通知偏好路由
+	notificationGroup := api.Group("/notifications")
+	{
+		notificationGroup.GET("", notificationHandler.GetInbox)
+		notificationGroup.GET("/unread-count", notificationHandler.GetUnreadCount)
+		notificationGroup.PUT("/read-all", notificationHandler.MarkAllRead)
+		notificationGroup.PUT("/:id/read", notificationHandler.MarkRead)
+		notificationGroup.PUT("/:id/unread", notificationHandler.MarkUnread)
+		notificationGroup.GET("/preferences", notificationHandler.GetPreferences)
+		notificationGroup.PUT("/preferences", notificationHandler.UpdatePreferences)
+	}
+
+	// 文件上传和下载路由
+	fileGroup := api.Group("/files")
+	{
+		// 上传文件（multipart/form-data）
+		fileGroup.POST("", fileHandler.UploadFile)
+		
+		// 分片上传：创建、查询进度、上传分片
+		fileGroup.POST("/uploads", fileHandler.CreateUpload)
+		fileGroup.GET("/uploads/:key", fileHandler.GetUpload)
+		fileGroup.PATCH("/uploads/:key", fileHandler.UploadChunk)
+		
+		// 下载文件 - 需能查看文件所属的实例
+		fileGroup.GET("/:key", fileHandler.DownloadFile)
 	}
 
 	// 用户个人信息路由
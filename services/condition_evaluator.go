@@ -0,0 +1,621 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gin-web-api/models"
+
+	"gorm.io/gorm"
+)
+
+// 条件运算符
+const (
+	ConditionOpEq          = "eq"           // 等于
+	ConditionOpNe          = "ne"           // 不等于
+	ConditionOpGt          = "gt"           // 大于
+	ConditionOpGte         = "gte"          // 大于等于
+	ConditionOpLt          = "lt"           // 小于
+	ConditionOpLte         = "lte"          // 小于等于
+	ConditionOpIn          = "in"           // 属于
+	ConditionOpNotIn       = "not_in"       // 不属于
+	ConditionOpContains    = "contains"     // 包含
+	ConditionOpNotContains = "not_contains" // 不包含
+	ConditionOpEmpty       = "empty"        // 为空
+	ConditionOpNotEmpty    = "not_empty"    // 不为空
+)
+
+// 条件关键字作用域前缀
+const (
+	ConditionScopeForm      = "form"      // 表单值
+	ConditionScopeVariables = "variables" // 流程变量
+	ConditionScopeInitiator = "initiator" // 发起人属性
+)
+
+// 条件关键字的聚合函数，如 sum(items.amount) 对明细表 items 的 amount 列求和
+const (
+	ConditionFuncSum   = "sum"   // 求和
+	ConditionFuncAvg   = "avg"   // 平均值
+	ConditionFuncMin   = "min"   // 最小值
+	ConditionFuncMax   = "max"   // 最大值
+	ConditionFuncCount = "count" // 非空值的数量，对明细表本身为行数
+)
+
+// conditionOpAliases 运算符别名
+var conditionOpAliases = map[string]string{
+	"=":  ConditionOpEq,
+	"==": ConditionOpEq,
+	"!=": ConditionOpNe,
+	"<>": ConditionOpNe,
+	">":  ConditionOpGt,
+	">=": ConditionOpGte,
+	"<":  ConditionOpLt,
+	"<=": ConditionOpLte,
+}
+
+// ConditionContext 条件评估上下文
+type ConditionContext struct {
+	FormValues map[string]interface{} `json:"form_values"`
+	Variables  map[string]interface{} `json:"variables"`
+	Initiator  map[string]interface{} `json:"initiator"`
+}
+
+// ParseShowCondition 解析条件配置JSON，空配置返回nil
+func ParseShowCondition(data string) (*ShowConditionRequest, error) {
+	if strings.TrimSpace(data) == "" {
+		return nil, nil
+	}
+
+	var condition ShowConditionRequest
+	if err := json.Unmarshal([]byte(data), &condition); err != nil {
+		return nil, fmt.Errorf("解析条件配置失败: %w", err)
+	}
+	return &condition, nil
+}
+
+// IsEmptyCondition 判断条件是否为空（空条件视为默认分支）
+func IsEmptyCondition(condition *ShowConditionRequest) bool {
+	if condition == nil {
+		return true
+	}
+	for _, group := range condition.Groups {
+		if len(group.Conditions) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// EvaluateCondition 评估条件：条件组之间为或，组内条件为与；空条件返回true
+func EvaluateCondition(condition *ShowConditionRequest, ctx *ConditionContext) bool {
+	if IsEmptyCondition(condition) {
+		return true
+	}
+
+	for _, group := range condition.Groups {
+		if len(group.Conditions) == 0 {
+			continue
+		}
+		if evaluateConditionGroup(group, ctx) {
+			return true
+		}
+	}
+	return false
+}
+
+// evaluateConditionGroup 评估条件组，组内全部满足才为真
+func evaluateConditionGroup(group ConditionGroupRequest, ctx *ConditionContext) bool {
+	for _, cond := range group.Conditions {
+		if !EvaluateSingleCondition(cond, ctx) {
+			return false
+		}
+	}
+	return true
+}
+
+// EvaluateSingleCondition 评估单个条件
+func EvaluateSingleCondition(cond ConditionRequest, ctx *ConditionContext) bool {
+	actual, _ := ctx.Resolve(cond.Keyword)
+	return compareValues(normalizeConditionOp(cond.Condition), actual, cond.Value)
+}
+
+// Resolve 根据关键字取值，支持 form./variables./initiator. 前缀及点号路径；
+// 无前缀时依次查找表单值、流程变量
+func (ctx *ConditionContext) Resolve(keyword string) (interface{}, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+
+	keyword = strings.TrimSpace(keyword)
+	if fn, inner, ok := splitAggregateKeyword(keyword); ok {
+		value, _ := ctx.Resolve(inner)
+		return aggregateValues(fn, value)
+	}
+	scope, path := splitConditionKeyword(keyword)
+	switch scope {
+	case ConditionScopeForm:
+		return lookupPath(ctx.FormValues, path)
+	case ConditionScopeVariables, "var", "vars":
+		return lookupPath(ctx.Variables, path)
+	case ConditionScopeInitiator:
+		return lookupPath(ctx.Initiator, path)
+	}
+
+	if value, ok := lookupPath(ctx.FormValues, keyword); ok {
+		return value, true
+	}
+	return lookupPath(ctx.Variables, keyword)
+}
+
+// splitConditionKeyword 拆分关键字的作用域前缀
+func splitConditionKeyword(keyword string) (string, string) {
+	idx := strings.Index(keyword, ".")
+	if idx <= 0 {
+		return "", keyword
+	}
+	return keyword[:idx], keyword[idx+1:]
+}
+
+// splitAggregateKeyword 拆分聚合函数关键字，如 sum(items.amount) 返回 sum 和 items.amount
+func splitAggregateKeyword(keyword string) (string, string, bool) {
+	open := strings.Index(keyword, "(")
+	if open <= 0 || !strings.HasSuffix(keyword, ")") {
+		return "", "", false
+	}
+	fn := strings.ToLower(strings.TrimSpace(keyword[:open]))
+	switch fn {
+	case ConditionFuncSum, ConditionFuncAvg, ConditionFuncMin, ConditionFuncMax, ConditionFuncCount:
+		return fn, strings.TrimSpace(keyword[open+1 : len(keyword)-1]), true
+	}
+	return "", "", false
+}
+
+// aggregateValues 对数组中的值做聚合；求和、平均、最值只计数字，没有数字时平均和最值取不到值
+func aggregateValues(fn string, value interface{}) (interface{}, bool) {
+	items, ok := toList(value)
+	if !ok {
+		items = []interface{}{}
+		if !isEmptyValue(value) {
+			items = append(items, value)
+		}
+	}
+	if fn == ConditionFuncCount {
+		count := 0
+		for _, item := range items {
+			if !isEmptyValue(item) {
+				count++
+			}
+		}
+		return float64(count), true
+	}
+
+	numbers := make([]float64, 0, len(items))
+	for _, item := range items {
+		if number, ok := toFloat(item); ok {
+			numbers = append(numbers, number)
+		}
+	}
+	sum := 0.0
+	for _, number := range numbers {
+		sum += number
+	}
+	if fn == ConditionFuncSum {
+		// 去掉浮点运算的误差，如 0.1 + 0.2 的结果为 0.3
+		sum, _ = strconv.ParseFloat(strconv.FormatFloat(sum, 'g', 12, 64), 64)
+		return sum, true
+	}
+	if len(numbers) == 0 {
+		return nil, false
+	}
+	result := numbers[0]
+	for _, number := range numbers[1:] {
+		switch {
+		case fn == ConditionFuncMin && number < result, fn == ConditionFuncMax && number > result:
+			result = number
+		}
+	}
+	if fn == ConditionFuncAvg {
+		result = sum / float64(len(numbers))
+	}
+	return result, true
+}
+
+// lookupPath 按点号路径在map中取值
+func lookupPath(values map[string]interface{}, path string) (interface{}, bool) {
+	if values == nil || path == "" {
+		return nil, false
+	}
+	if value, ok := values[path]; ok {
+		return value, true
+	}
+
+	parts := strings.Split(path, ".")
+	var current interface{} = values
+	for i, part := range parts {
+		if list, ok := current.([]interface{}); ok {
+			// 数组按序号取元素，否则取出每个元素中的字段，如 items.amount 为明细表各行的金额
+			if index, err := strconv.Atoi(part); err == nil {
+				if index < 0 || index >= len(list) {
+					return nil, false
+				}
+				current = list[index]
+				continue
+			}
+			column := make([]interface{}, 0, len(list))
+			for _, item := range list {
+				if row, ok := item.(map[string]interface{}); ok {
+					if value, ok := lookupPath(row, strings.Join(parts[i:], ".")); ok {
+						column = append(column, value)
+					}
+				}
+			}
+			return column, true
+		}
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if current, ok = m[part]; !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// normalizeConditionOp 统一运算符写法
+func normalizeConditionOp(op string) string {
+	op = strings.ToLower(strings.TrimSpace(op))
+	if alias, ok := conditionOpAliases[op]; ok {
+		return alias
+	}
+	return op
+}
+
+// compareValues 按运算符比较实际值与期望值
+func compareValues(op string, actual, expected interface{}) bool {
+	switch op {
+	case ConditionOpEmpty:
+		return isEmptyValue(actual)
+	case ConditionOpNotEmpty:
+		return !isEmptyValue(actual)
+	case ConditionOpEq:
+		return anyValueMatches(actual, func(v interface{}) bool { return valuesEqual(v, expected) })
+	case ConditionOpNe:
+		return !anyValueMatches(actual, func(v interface{}) bool { return valuesEqual(v, expected) })
+	case ConditionOpGt, ConditionOpGte, ConditionOpLt, ConditionOpLte:
+		return anyValueMatches(actual, func(v interface{}) bool {
+			result, ok := orderValues(v, expected)
+			if !ok {
+				return false
+			}
+			switch op {
+			case ConditionOpGt:
+				return result > 0
+			case ConditionOpGte:
+				return result >= 0
+			case ConditionOpLt:
+				return result < 0
+			default:
+				return result <= 0
+			}
+		})
+	case ConditionOpIn:
+		return anyValueMatches(actual, func(v interface{}) bool { return listContains(expected, v) })
+	case ConditionOpNotIn:
+		return !anyValueMatches(actual, func(v interface{}) bool { return listContains(expected, v) })
+	case ConditionOpContains:
+		return valueContains(actual, expected)
+	case ConditionOpNotContains:
+		return !valueContains(actual, expected)
+	}
+	return false
+}
+
+// anyValueMatches 实际值为数组时任一元素满足即可（如发起人所属多个部门）
+func anyValueMatches(actual interface{}, match func(interface{}) bool) bool {
+	if list, ok := toList(actual); ok {
+		for _, item := range list {
+			if match(item) {
+				return true
+			}
+		}
+		return false
+	}
+	return match(actual)
+}
+
+// isEmptyValue 判断值是否为空
+func isEmptyValue(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	switch v := value.(type) {
+	case string:
+		return strings.TrimSpace(v) == ""
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return rv.Len() == 0
+	}
+	return false
+}
+
+// valuesEqual 比较两个值是否相等，数字按数值比较，其余按字符串比较
+func valuesEqual(a, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	if fa, ok := toFloat(a); ok {
+		if fb, ok := toFloat(b); ok {
+			return fa == fb
+		}
+	}
+	if ba, ok := a.(bool); ok {
+		if bb, ok := toBool(b); ok {
+			return ba == bb
+		}
+	}
+	return toString(a) == toString(b)
+}
+
+// orderValues 比较大小，依次尝试数值、日期、字符串
+func orderValues(a, b interface{}) (int, bool) {
+	if a == nil || b == nil {
+		return 0, false
+	}
+	if fa, ok := toFloat(a); ok {
+		if fb, ok := toFloat(b); ok {
+			switch {
+			case fa > fb:
+				return 1, true
+			case fa < fb:
+				return -1, true
+			}
+			return 0, true
+		}
+	}
+	if ta, ok := toTime(a); ok {
+		if tb, ok := toTime(b); ok {
+			return ta.Compare(tb), true
+		}
+	}
+	return strings.Compare(toString(a), toString(b)), true
+}
+
+// listContains 判断期望值列表中是否包含该值
+func listContains(list interface{}, value interface{}) bool {
+	items, ok := toList(list)
+	if !ok {
+		// 兼容逗号分隔的字符串
+		if s, isString := list.(string); isString {
+			for _, part := range strings.Split(s, ",") {
+				if valuesEqual(strings.TrimSpace(part), value) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	for _, item := range items {
+		if valuesEqual(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// valueContains 数组包含元素或字符串包含子串
+func valueContains(actual, expected interface{}) bool {
+	if actual == nil {
+		return false
+	}
+	if items, ok := toList(actual); ok {
+		if expectedItems, ok := toList(expected); ok {
+			for _, e := range expectedItems {
+				if !listContains(items, e) {
+					return false
+				}
+			}
+			return true
+		}
+		return listContains(items, expected)
+	}
+	return strings.Contains(toString(actual), toString(expected))
+}
+
+// toList 将切片类型的值转换为[]interface{}
+func toList(value interface{}) ([]interface{}, bool) {
+	if value == nil {
+		return nil, false
+	}
+	if list, ok := value.([]interface{}); ok {
+		return list, true
+	}
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, false
+	}
+	list := make([]interface{}, rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		list[i] = rv.Index(i).Interface()
+	}
+	return list, true
+}
+
+// toFloat 尝试将值转换为数字
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// toBool 尝试将值转换为布尔值
+func toBool(value interface{}) (bool, bool) {
+	switch v := value.(type) {
+	case bool:
+		return v, true
+	case string:
+		b, err := strconv.ParseBool(strings.TrimSpace(v))
+		return b, err == nil
+	}
+	return false, false
+}
+
+// conditionTimeLayouts 条件中支持的日期格式
+var conditionTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// toTime 尝试将值解析为时间
+func toTime(value interface{}) (time.Time, bool) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, true
+	case string:
+		for _, layout := range conditionTimeLayouts {
+			if t, err := time.ParseInLocation(layout, strings.TrimSpace(v), time.Local); err == nil {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// toString 将值转换为字符串
+func toString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case json.Number:
+		return v.String()
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// buildConditionContext 构建实例的条件评估上下文（表单值、流程变量、发起人属性）
+func (s *WorkflowService) buildConditionContext(tx *gorm.DB, instance *models.WorkflowInstance) *ConditionContext {
+	ctx := &ConditionContext{
+		FormValues: make(map[string]interface{}),
+		Variables:  make(map[string]interface{}),
+		Initiator:  make(map[string]interface{}),
+	}
+
+	if instance.FormDataID != nil {
+		var formData models.FormData
+		if err := tx.First(&formData, *instance.FormDataID).Error; err == nil && formData.FormValues != "" {
+			json.Unmarshal([]byte(formData.FormValues), &ctx.FormValues)
+		}
+	}
+
+	if s.simulation != nil {
+		// 流程模拟不保存表单数据，直接使用传入的表单值
+		for key, value := range s.simulation.formValues {
+			ctx.FormValues[key] = value
+		}
+	}
+
+	if instance.Variables != "" {
+		json.Unmarshal([]byte(instance.Variables), &ctx.Variables)
+	}
+
+	ctx.Initiator = s.loadInitiatorAttributes(tx, instance.InitiatorID)
+	return ctx
+}
+
+// loadInitiatorAttributes 加载发起人属性（部门、角色等）供条件使用
+func (s *WorkflowService) loadInitiatorAttributes(tx *gorm.DB, userID uint) map[string]interface{} {
+	attrs := map[string]interface{}{"id": userID}
+
+	var user models.User
+	if err := tx.First(&user, userID).Error; err == nil {
+		attrs["username"] = user.Username
+		attrs["full_name"] = user.FullName
+		attrs["email"] = user.Email
+	}
+
+	var departments []struct {
+		ID     uint
+		Name   string
+		Code   string
+		IsMain bool
+	}
+	tx.Table("departments").
+		Select("departments.id, departments.name, departments.code, user_departments.is_main").
+		Joins("JOIN user_departments ON departments.id = user_departments.department_id").
+		Where("user_departments.user_id = ? AND user_departments.deleted_at IS NULL AND departments.deleted_at IS NULL", userID).
+		Scan(&departments)
+
+	departmentIDs := make([]interface{}, 0, len(departments))
+	departmentNames := make([]interface{}, 0, len(departments))
+	departmentCodes := make([]interface{}, 0, len(departments))
+	for _, dept := range departments {
+		departmentIDs = append(departmentIDs, dept.ID)
+		departmentNames = append(departmentNames, dept.Name)
+		departmentCodes = append(departmentCodes, dept.Code)
+		if dept.IsMain {
+			attrs["main_department"] = dept.Name
+			attrs["main_department_id"] = dept.ID
+		}
+	}
+	attrs["department"] = departmentNames
+	attrs["department_id"] = departmentIDs
+	attrs["department_code"] = departmentCodes
+
+	var roles []models.Role
+	tx.Table("roles").
+		Joins("JOIN user_roles ON roles.id = user_roles.role_id").
+		Where("user_roles.user_id = ? AND user_roles.deleted_at IS NULL", userID).
+		Find(&roles)
+
+	roleIDs := make([]interface{}, 0, len(roles))
+	roleCodes := make([]interface{}, 0, len(roles))
+	roleNames := make([]interface{}, 0, len(roles))
+	for _, role := range roles {
+		roleIDs = append(roleIDs, role.ID)
+		roleCodes = append(roleCodes, role.Code)
+		roleNames = append(roleNames, role.Name)
+	}
+	attrs["role"] = roleCodes
+	attrs["role_id"] = roleIDs
+	attrs["role_name"] = roleNames
+
+	return attrs
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompareValues(t *testing.T) {
+	tests := []struct {
+		name     string
+		op       string
+		actual   interface{}
+		expected interface{}
+		want     bool
+	}{
+		{"数字相等", ConditionOpEq, float64(100), "100", true},
+		{"字符串相等", ConditionOpEq, "hz", "hz", true},
+		{"布尔值与字符串相等", ConditionOpEq, true, "true", true},
+		{"nil 不等于空字符串", ConditionOpEq, nil, "", false},
+		{"不等于", ConditionOpNe, "sh", "hz", true},
+		{"数组任一元素等于", ConditionOpEq, []interface{}{"研发部", "财务部"}, "财务部", true},
+		{"数组不等于要求全部元素不等", ConditionOpNe, []interface{}{"研发部", "财务部"}, "财务部", false},
+		{"大于", ConditionOpGt, float64(5001), 5000, true},
+		{"大于等于边界", ConditionOpGte, "5000", float64(5000), true},
+		{"小于", ConditionOpLt, 3, "10", true},
+		{"数字按数值而不是字符串比较", ConditionOpLt, "9", "10", true},
+		{"小于等于边界", ConditionOpLte, float64(10), 10, true},
+		{"日期比较", ConditionOpGt, "2024-03-02", "2024-03-01 23:59", true},
+		{"缺少值时不比较大小", ConditionOpGt, nil, 0, false},
+		{"属于列表", ConditionOpIn, "hz", []interface{}{"hz", "sh"}, true},
+		{"属于逗号分隔的字符串", ConditionOpIn, float64(2), "1, 2, 3", true},
+		{"不属于", ConditionOpNotIn, "bj", []interface{}{"hz", "sh"}, true},
+		{"数组包含元素", ConditionOpContains, []interface{}{"a", "b"}, "b", true},
+		{"数组包含全部元素", ConditionOpContains, []interface{}{"a", "b"}, []interface{}{"a", "c"}, false},
+		{"字符串包含子串", ConditionOpContains, "紧急采购", "紧急", true},
+		{"不包含", ConditionOpNotContains, "常规采购", "紧急", true},
+		{"为空", ConditionOpEmpty, "  ", nil, true},
+		{"空数组为空", ConditionOpEmpty, []interface{}{}, nil, true},
+		{"不为空", ConditionOpNotEmpty, float64(0), nil, true},
+		{"未知运算符", "between", float64(1), float64(1), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := compareValues(tt.op, tt.actual, tt.expected); got != tt.want {
+				t.Errorf("compareValues(%q, %v, %v) = %v, want %v", tt.op, tt.actual, tt.expected, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeConditionOp(t *testing.T) {
+	for op, want := range map[string]string{">=": ConditionOpGte, "==": ConditionOpEq, "<>": ConditionOpNe, " EQ ": ConditionOpEq, "not_in": ConditionOpNotIn} {
+		if got := normalizeConditionOp(op); got != want {
+			t.Errorf("normalizeConditionOp(%q) = %q, want %q", op, got, want)
+		}
+	}
+}
+
+func TestConditionContextResolve(t *testing.T) {
+	ctx := &ConditionContext{
+		FormValues: map[string]interface{}{
+			"amount":      float64(3000),
+			"applicant":   map[string]interface{}{"dept": "研发部"},
+			"travel.mode": "car",
+			"items": []interface{}{
+				map[string]interface{}{"name": "笔记本", "price": float64(5000), "qty": float64(2)},
+				map[string]interface{}{"name": "显示器", "price": float64(1500.5), "qty": float64(1)},
+				map[string]interface{}{"name": "赠品"},
+			},
+		},
+		Variables: map[string]interface{}{"amount": float64(100), "level": "P7"},
+		Initiator: map[string]interface{}{"department_ids": []interface{}{float64(1), float64(3)}},
+	}
+
+	tests := []struct {
+		keyword string
+		want    interface{}
+		wantOK  bool
+	}{
+		{"form.amount", float64(3000), true},
+		{"variables.amount", float64(100), true},
+		{"var.level", "P7", true},
+		{"vars.level", "P7", true},
+		{"initiator.department_ids", []interface{}{float64(1), float64(3)}, true},
+		{"amount", float64(3000), true},
+		{"level", "P7", true},
+		{" form.applicant.dept ", "研发部", true},
+		{"form.travel.mode", "car", true},
+		{"form.items.1.name", "显示器", true},
+		{"form.items.5.name", nil, false},
+		{"form.items.price", []interface{}{float64(5000), float64(1500.5)}, true},
+		{"sum(form.items.price)", 6500.5, true},
+		{"SUM(items.qty)", float64(3), true},
+		{"count(form.items)", float64(3), true},
+		{"count(form.items.price)", float64(2), true},
+		{"max(form.items.price)", float64(5000), true},
+		{"avg(form.items.qty)", 1.5, true},
+		{"min(form.items.name)", nil, false},
+		{"form.missing", nil, false},
+		{"initiator.level", nil, false},
+		{"unknown(form.amount)", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.keyword, func(t *testing.T) {
+			got, ok := ctx.Resolve(tt.keyword)
+			if ok != tt.wantOK || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Resolve(%q) = %v, %v, want %v, %v", tt.keyword, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+
+	var nilCtx *ConditionContext
+	if value, ok := nilCtx.Resolve("form.amount"); ok || value != nil {
+		t.Errorf("nil 上下文 Resolve() = %v, %v", value, ok)
+	}
+}
+
+func TestAggregateValues(t *testing.T) {
+	tests := []struct {
+		name   string
+		fn     string
+		value  interface{}
+		want   interface{}
+		wantOK bool
+	}{
+		{"求和", ConditionFuncSum, []interface{}{float64(1), "2", float64(3.5)}, 6.5, true},
+		{"求和去掉浮点误差", ConditionFuncSum, []interface{}{0.1, 0.2}, 0.3, true},
+		{"求和忽略非数字", ConditionFuncSum, []interface{}{float64(1), "abc", nil}, float64(1), true},
+		{"空列表求和为0", ConditionFuncSum, []interface{}{}, float64(0), true},
+		{"单个值求和", ConditionFuncSum, float64(7), float64(7), true},
+		{"平均值", ConditionFuncAvg, []interface{}{float64(1), float64(2), float64(6)}, float64(3), true},
+		{"没有数字时没有平均值", ConditionFuncAvg, []interface{}{"a"}, nil, false},
+		{"最小值", ConditionFuncMin, []interface{}{float64(3), float64(-1), float64(2)}, float64(-1), true},
+		{"最大值", ConditionFuncMax, []int{3, 9, 2}, float64(9), true},
+		{"nil 没有最大值", ConditionFuncMax, nil, nil, false},
+		{"计数忽略空值", ConditionFuncCount, []interface{}{"a", "", nil, float64(0)}, float64(2), true},
+		{"nil 计数为0", ConditionFuncCount, nil, float64(0), true},
+		{"单个值计数为1", ConditionFuncCount, "x", float64(1), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := aggregateValues(tt.fn, tt.value)
+			if ok != tt.wantOK || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("aggregateValues(%q, %v) = %v, %v, want %v, %v", tt.fn, tt.value, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"gin-web-api/database"
+	"gin-web-api/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// fileCleanupBatchSize 每轮清理的最大数量
+const fileCleanupBatchSize = 100
+
+// expiredUploadStatuses 过期后需要清理的分片上传状态；合并中的上传在合并中断（如服务重启）时会停留在此状态
+var expiredUploadStatuses = []string{models.FileUploadStatusUploading, models.FileUploadStatusMerging, models.FileUploadStatusFailed}
+
+// FileCleanupWorker 文件清理后台任务：删除上传后超过保留时长仍未关联到表单数据的文件，
+// 以及过期未完成的分片上传；通过条件删除认领，多副本部署时不会重复删除
+type FileCleanupWorker struct {
+	db        *gorm.DB
+	interval  time.Duration
+	orphanTTL time.Duration
+	stop      chan struct{}
+}
+
+// NewFileCleanupWorker 创建文件清理任务
+func NewFileCleanupWorker(interval, orphanTTL time.Duration) *FileCleanupWorker {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	if orphanTTL <= 0 {
+		orphanTTL = 24 * time.Hour
+	}
+	return &FileCleanupWorker{
+		db:        database.GetDB(),
+		interval:  interval,
+		orphanTTL: orphanTTL,
+		stop:      make(chan struct{}),
+	}
+}
+
+// Start 在后台启动文件清理任务
+func (w *FileCleanupWorker) Start() {
+	go func() {
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				w.RunOnce()
+			case <-w.stop:
+				return
+			}
+		}
+	}()
+	log.Printf("文件清理任务已启动，间隔 %s，未关联文件保留 %s", w.interval, w.orphanTTL)
+}
+
+// Stop 停止文件清理任务
+func (w *FileCleanupWorker) Stop() {
+	close(w.stop)
+}
+
+// RunOnce 执行一轮清理
+func (w *FileCleanupWorker) RunOnce() {
+	defer func() {
+		if rec := recover(); rec != nil {
+			log.Printf("文件清理任务异常: %v", rec)
+		}
+	}()
+
+	now := time.Now()
+	if count, err := w.CleanupOrphanFiles(now); err != nil {
+		log.Printf("清理未关联文件失败: %v", err)
+	} else if count > 0 {
+		log.Printf("已清理 %d 个未关联的文件", count)
+	}
+	if count, err := w.CleanupExpiredUploads(now); err != nil {
+		log.Printf("清理过期分片上传失败: %v", err)
+	} else if count > 0 {
+		log.Printf("已清理 %d 个过期的分片上传", count)
+	}
+}
+
+// CleanupOrphanFiles 删除上传时间早于 now 减保留时长且未关联表单数据的文件，返回删除的数量
+func (w *FileCleanupWorker) CleanupOrphanFiles(now time.Time) (int, error) {
+	storage, _, err := getFileStorage()
+	if err != nil {
+		return 0, err
+	}
+	var files []models.FileObject
+	if err := w.db.Where("form_data_id IS NULL AND created_at < ?", now.Add(-w.orphanTTL)).
+		Order("id").Limit(fileCleanupBatchSize).Find(&files).Error; err != nil {
+		return 0, fmt.Errorf("查询未关联文件失败: %w", err)
+	}
+
+	deleted := 0
+	for _, file := range files {
+		// 先删除记录，与此同时提交表单引用该文件时会因文件不存在而失败，不会引用到已删除的对象
+		result := w.db.Where("id = ? AND form_data_id IS NULL", file.ID).Delete(&models.FileObject{})
+		if result.Error != nil {
+			return deleted, fmt.Errorf("删除文件记录 %d 失败: %w", file.ID, result.Error)
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+		if err := w.db.Where("file_id = ?", file.ID).Delete(&models.FileUpload{}).Error; err != nil {
+			log.Printf("删除文件 %d 的分片上传记录失败: %v", file.ID, err)
+		}
+		if err := storage.Delete(file.StorageKey); err != nil {
+			log.Printf("删除文件 %s 失败: %v", file.StorageKey, err)
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+// CleanupExpiredUploads 删除过期未完成（上传中、合并中断或失败）的分片上传及已上传的分片，返回删除的数量
+func (w *FileCleanupWorker) CleanupExpiredUploads(now time.Time) (int, error) {
+	storage, _, err := getFileStorage()
+	if err != nil {
+		return 0, err
+	}
+	var uploads []models.FileUpload
+	if err := w.db.Where("status IN ? AND expires_at < ?", expiredUploadStatuses, now).
+		Order("id").Limit(fileCleanupBatchSize).Find(&uploads).Error; err != nil {
+		return 0, fmt.Errorf("查询过期分片上传失败: %w", err)
+	}
+
+	deleted := 0
+	for _, upload := range uploads {
+		// 锁定后再次检查，上传分片会延长有效期
+		var locked models.FileUpload
+		claimed := false
+		err := w.db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&locked, upload.ID).Error; err != nil {
+				return nil
+			}
+			if locked.Status == models.FileUploadStatusCompleted || !locked.ExpiresAt.Before(now) {
+				return nil
+			}
+			claimed = true
+			return tx.Delete(&locked).Error
+		})
+		if err != nil {
+			return deleted, fmt.Errorf("删除分片上传 %d 失败: %w", upload.ID, err)
+		}
+		if !claimed {
+			continue
+		}
+		for i := 0; i < locked.Parts; i++ {
+			if err := storage.Delete(uploadPartKey(locked.UploadKey, i)); err != nil {
+				log.Printf("删除分片 %s 失败: %v", uploadPartKey(locked.UploadKey, i), err)
+			}
+		}
+		deleted++
+	}
+	return deleted, nil
+}
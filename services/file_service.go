@@ -0,0 +1,655 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gin-web-api/database"
+	"gin-web-api/models"
+
+	"gorm.io/gorm"
+)
+
+// 上传的默认设置
+const (
+	FileChunkMaxSize   = 8 << 20        // 分片上传时单个分片的大小上限
+	fileUploadLifetime = 24 * time.Hour // 分片上传最后一次上传分片后的有效期
+	fileSniffLength    = 512            // 识别文件类型读取的字节数
+)
+
+// 文件访问错误
+var (
+	ErrFileForbidden        = errors.New("无权限访问此文件")
+	ErrUploadOffsetMismatch = errors.New("分片位置与已上传的字节数不一致")
+)
+
+// zipBasedTypes 内容识别为zip时按扩展名细分的文档类型
+var zipBasedTypes = map[string]string{
+	".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	".pptx": "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	".odt":  "application/vnd.oasis.opendocument.text",
+	".ods":  "application/vnd.oasis.opendocument.spreadsheet",
+}
+
+// textBasedTypes 内容识别为纯文本时按扩展名细分的类型
+var textBasedTypes = map[string]string{
+	".csv": "text/csv",
+}
+
+// FileService 文件字段的上传、下载和关联
+type FileService struct {
+	db *gorm.DB
+}
+
+// NewFileService 创建文件服务
+func NewFileService() *FileService {
+	return &FileService{
+		db: database.GetDB(),
+	}
+}
+
+// UploadFileRequest 上传文件请求（multipart/form-data，文件为 file）
+type UploadFileRequest struct {
+	FormID   uint   `form:"form_id" binding:"required"`
+	FieldKey string `form:"field_key" binding:"required"`
+}
+
+// CreateUploadRequest 创建分片上传请求
+type CreateUploadRequest struct {
+	FormID   uint   `json:"form_id" binding:"required"`
+	FieldKey string `json:"field_key" binding:"required"`
+	FileName string `json:"file_name" binding:"required"`
+	Size     int64  `json:"size" binding:"required"`
+}
+
+// fileField 文件字段及其验证规则
+type fileField struct {
+	attr       models.FormAttribute
+	validation *FieldValidation
+}
+
+// maxSize 字段允许的单个文件大小上限，未设置时为全局上限
+func (f *fileField) maxSize(globalMax int64) int64 {
+	if f.validation != nil && f.validation.MaxFileSize > 0 && f.validation.MaxFileSize < globalMax {
+		return f.validation.MaxFileSize
+	}
+	return globalMax
+}
+
+// checkSize 检查文件大小
+func (f *fileField) checkSize(size, globalMax int64) error {
+	if size <= 0 {
+		return errors.New("文件不能为空")
+	}
+	if max := f.maxSize(globalMax); size > max {
+		return fmt.Errorf("%s 的文件大小不能超过 %s", f.attr.Name, formatFileSize(max))
+	}
+	return nil
+}
+
+// checkType 检查按内容识别的文件类型是否在字段允许的范围内，支持 image/* 形式的通配
+func (f *fileField) checkType(contentType string) error {
+	if f.validation == nil || len(f.validation.Accept) == 0 {
+		return nil
+	}
+	for _, accept := range f.validation.Accept {
+		accept = strings.ToLower(strings.TrimSpace(accept))
+		if accept == contentType || (strings.HasSuffix(accept, "/*") && strings.HasPrefix(contentType, strings.TrimSuffix(accept, "*"))) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s 不支持 %s 类型的文件", f.attr.Name, contentType)
+}
+
+// formFieldInTx 按字段标识获取表单中的字段
+func formFieldInTx(tx *gorm.DB, formID uint, fieldKey string) (*models.FormAttribute, error) {
+	var attr models.FormAttribute
+	if err := tx.Preload("Attribute").
+		Joins("JOIN form_cards ON form_cards.id = form_attributes.card_id AND form_cards.deleted_at IS NULL").
+		Joins("JOIN field_attributes ON field_attributes.id = form_attributes.attribute_id").
+		Where("form_cards.form_id = ? AND field_attributes.field_key = ?", formID, fieldKey).
+		First(&attr).Error; err != nil {
+		return nil, fmt.Errorf("表单字段 %s 不存在: %w", fieldKey, err)
+	}
+	return &attr, nil
+}
+
+// fileFieldInTx 获取表单中的文件字段
+func fileFieldInTx(tx *gorm.DB, formID uint, fieldKey string) (*fileField, error) {
+	attr, err := formFieldInTx(tx, formID, fieldKey)
+	if err != nil {
+		return nil, err
+	}
+	if attr.Element != models.ElementTypeFile {
+		return nil, fmt.Errorf("字段 %s 不是文件字段", attr.Name)
+	}
+	validation, err := ParseFieldValidation(attr.Validation)
+	if err != nil {
+		return nil, fmt.Errorf("字段 %s 的%w", attr.Name, err)
+	}
+	return &fileField{attr: *attr, validation: validation}, nil
+}
+
+// UploadFile 上传文件到表单的文件字段，按内容识别文件类型并检查字段的大小和类型限制
+func (s *FileService) UploadFile(req *UploadFileRequest, fileName string, size int64, content io.Reader, userID uint) (*models.FileObject, error) {
+	storage, globalMax, err := getFileStorage()
+	if err != nil {
+		return nil, err
+	}
+	field, err := fileFieldInTx(s.db, req.FormID, req.FieldKey)
+	if err != nil {
+		return nil, err
+	}
+	if err := field.checkSize(size, globalMax); err != nil {
+		return nil, err
+	}
+	return saveFileObject(s.db, storage, field, req.FormID, fileName, size, content, userID)
+}
+
+// saveFileObject 识别文件类型并检查后写入存储，创建文件记录；记录创建失败时删除已写入的对象
+func saveFileObject(tx *gorm.DB, storage FileStorage, field *fileField, formID uint, fileName string, size int64, content io.Reader, userID uint) (*models.FileObject, error) {
+	reader := bufio.NewReaderSize(content, fileSniffLength)
+	head, err := reader.Peek(fileSniffLength)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, fmt.Errorf("读取文件失败: %w", err)
+	}
+	contentType := detectContentType(head, fileName)
+	if err := field.checkType(contentType); err != nil {
+		return nil, err
+	}
+
+	file := &models.FileObject{
+		FileKey:     generateFileKey(),
+		FileName:    filepath.Base(fileName),
+		ContentType: contentType,
+		Size:        size,
+		FormID:      formID,
+		FieldKey:    field.attr.Attribute.FieldKey,
+		UploadedBy:  userID,
+	}
+	file.StorageKey = fmt.Sprintf("files/%s/%s", time.Now().Format("2006/01/02"), file.FileKey)
+
+	hash := sha256.New()
+	if err := storage.Put(file.StorageKey, io.TeeReader(io.LimitReader(reader, size+1), hash), size, contentType); err != nil {
+		return nil, fmt.Errorf("保存文件失败: %w", err)
+	}
+	file.Checksum = hex.EncodeToString(hash.Sum(nil))
+	if err := tx.Create(file).Error; err != nil {
+		if deleteErr := storage.Delete(file.StorageKey); deleteErr != nil {
+			log.Printf("删除文件 %s 失败: %v", file.StorageKey, deleteErr)
+		}
+		return nil, fmt.Errorf("创建文件记录失败: %w", err)
+	}
+	return file, nil
+}
+
+// detectContentType 按文件内容识别MIME类型；zip和纯文本按扩展名细分为对应的文档类型，
+// 其余情况不采信扩展名，避免通过修改扩展名绕过类型限制
+func detectContentType(head []byte, fileName string) string {
+	contentType, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+	ext := strings.ToLower(filepath.Ext(fileName))
+	switch contentType {
+	case "application/zip":
+		if refined, ok := zipBasedTypes[ext]; ok {
+			return refined
+		}
+	case "text/plain":
+		if refined, ok := textBasedTypes[ext]; ok {
+			return refined
+		}
+	}
+	return contentType
+}
+
+// CreateUpload 创建分片上传，检查字段的大小限制，文件类型在全部分片上传后识别
+func (s *FileService) CreateUpload(req *CreateUploadRequest, userID uint) (*models.FileUpload, error) {
+	_, globalMax, err := getFileStorage()
+	if err != nil {
+		return nil, err
+	}
+	field, err := fileFieldInTx(s.db, req.FormID, req.FieldKey)
+	if err != nil {
+		return nil, err
+	}
+	if err := field.checkSize(req.Size, globalMax); err != nil {
+		return nil, err
+	}
+
+	upload := &models.FileUpload{
+		UploadKey:  generateFileKey(),
+		FormID:     req.FormID,
+		FieldKey:   req.FieldKey,
+		FileName:   filepath.Base(req.FileName),
+		Size:       req.Size,
+		Status:     models.FileUploadStatusUploading,
+		UploadedBy: userID,
+		ExpiresAt:  time.Now().Add(fileUploadLifetime),
+	}
+	if err := s.db.Create(upload).Error; err != nil {
+		return nil, fmt.Errorf("创建分片上传失败: %w", err)
+	}
+	return upload, nil
+}
+
+// GetUpload 获取分片上传的进度，用于中断后从已上传的位置继续
+func (s *FileService) GetUpload(uploadKey string, userID uint) (*models.FileUpload, error) {
+	var upload models.FileUpload
+	if err := s.db.Preload("File").Where("upload_key = ?", uploadKey).First(&upload).Error; err != nil {
+		return nil, fmt.Errorf("分片上传不存在: %w", err)
+	}
+	if upload.UploadedBy != userID {
+		return nil, ErrFileForbidden
+	}
+	return &upload, nil
+}
+
+// UploadChunk 从 offset 处上传一个分片，offset 必须等于已上传的字节数；
+// 上传完最后一个分片时合并保存为文件，返回的上传记录中包含文件。
+// 分片的写入和合并不在事务中进行，写入后按上传前的进度条件更新记录，并发上传同一位置的分片只有一个成功
+func (s *FileService) UploadChunk(uploadKey string, offset int64, chunk []byte, userID uint) (*models.FileUpload, error) {
+	storage, _, err := getFileStorage()
+	if err != nil {
+		return nil, err
+	}
+
+	var upload models.FileUpload
+	if err := s.db.Where("upload_key = ?", uploadKey).First(&upload).Error; err != nil {
+		return &upload, fmt.Errorf("分片上传不存在: %w", err)
+	}
+	if upload.UploadedBy != userID {
+		return &upload, ErrFileForbidden
+	}
+	if err := checkUploadStatus(&upload); err != nil {
+		return &upload, err
+	}
+	if time.Now().After(upload.ExpiresAt) {
+		return &upload, errors.New("分片上传已过期，请重新上传")
+	}
+	if offset != upload.Offset {
+		return &upload, ErrUploadOffsetMismatch
+	}
+	if len(chunk) == 0 || int64(len(chunk)) > FileChunkMaxSize {
+		return &upload, fmt.Errorf("分片大小必须在 1 到 %s 之间", formatFileSize(FileChunkMaxSize))
+	}
+	if offset+int64(len(chunk)) > upload.Size {
+		return &upload, errors.New("分片超出了文件大小")
+	}
+
+	// 同一位置的分片写入同一个对象键，失败的一方不删除分片，以免删除成功一方写入的分片；
+	// 续传同一文件时同一位置的内容相同，长度不同时合并会因大小不一致而失败
+	if err := storage.Put(uploadPartKey(upload.UploadKey, upload.Parts), bytes.NewReader(chunk), int64(len(chunk)), ""); err != nil {
+		return &upload, fmt.Errorf("保存分片失败: %w", err)
+	}
+
+	previous := upload
+	upload.Parts++
+	upload.Offset += int64(len(chunk))
+	upload.ExpiresAt = time.Now().Add(fileUploadLifetime)
+	updates := map[string]interface{}{"parts": upload.Parts, "offset": upload.Offset, "expires_at": upload.ExpiresAt}
+	if upload.Offset == upload.Size {
+		// 先标记为合并中，并发上传的最后一个分片不会重复合并
+		upload.Status = models.FileUploadStatusMerging
+		updates["status"] = upload.Status
+	}
+	result := s.db.Model(&models.FileUpload{}).
+		Where(map[string]interface{}{"id": upload.ID, "status": models.FileUploadStatusUploading, "offset": previous.Offset, "parts": previous.Parts}).
+		Updates(updates)
+	if result.Error != nil {
+		return &previous, fmt.Errorf("更新分片上传失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		// 进度已被其他请求更新，返回最新的进度
+		if err := s.db.Where("id = ?", upload.ID).First(&upload).Error; err != nil {
+			return &previous, fmt.Errorf("分片上传不存在: %w", err)
+		}
+		if err := checkUploadStatus(&upload); err != nil {
+			return &upload, err
+		}
+		return &upload, ErrUploadOffsetMismatch
+	}
+	if upload.Status != models.FileUploadStatusMerging {
+		return &upload, nil
+	}
+
+	if err := s.mergeUpload(storage, &upload, previous); err != nil {
+		return &upload, err
+	}
+	return &upload, nil
+}
+
+// checkUploadStatus 检查分片上传是否还可以继续上传分片
+func checkUploadStatus(upload *models.FileUpload) error {
+	switch upload.Status {
+	case models.FileUploadStatusUploading:
+		return nil
+	case models.FileUploadStatusMerging:
+		return errors.New("分片上传正在合并，请稍后查询结果")
+	case models.FileUploadStatusFailed:
+		return errors.New("分片上传已失败，请重新上传")
+	}
+	return errors.New("分片上传已完成")
+}
+
+// mergeUpload 合并已标记为合并中的分片上传并保存为文件。
+// 文件类型检查未通过时标记为失败并删除分片；其他错误时恢复到上传最后一个分片前的进度，可以重新上传最后一个分片
+func (s *FileService) mergeUpload(storage FileStorage, upload *models.FileUpload, previous models.FileUpload) error {
+	partKeys := make([]string, 0, upload.Parts)
+	for i := 0; i < upload.Parts; i++ {
+		partKeys = append(partKeys, uploadPartKey(upload.UploadKey, i))
+	}
+	deleteParts := func() {
+		for _, key := range partKeys {
+			if err := storage.Delete(key); err != nil {
+				log.Printf("删除分片 %s 失败: %v", key, err)
+			}
+		}
+	}
+	updateMerging := func(updates map[string]interface{}) *gorm.DB {
+		return s.db.Model(&models.FileUpload{}).
+			Where("id = ? AND status = ?", upload.ID, models.FileUploadStatusMerging).Updates(updates)
+	}
+
+	field, err := fileFieldInTx(s.db, upload.FormID, upload.FieldKey)
+	var contentType string
+	if err == nil {
+		contentType, err = sniffContentType(&partsReader{storage: storage, keys: partKeys}, upload.FileName)
+	}
+	if err == nil {
+		if typeErr := field.checkType(contentType); typeErr != nil {
+			if err := updateMerging(map[string]interface{}{"status": models.FileUploadStatusFailed}).Error; err != nil {
+				log.Printf("标记分片上传 %s 为失败时出错: %v", upload.UploadKey, err)
+			}
+			upload.Status = models.FileUploadStatusFailed
+			deleteParts()
+			return typeErr
+		}
+	}
+
+	var file *models.FileObject
+	if err == nil {
+		file, err = saveFileObject(s.db, storage, field, upload.FormID, upload.FileName, upload.Size, &partsReader{storage: storage, keys: partKeys}, upload.UploadedBy)
+	}
+	if err != nil {
+		if updateErr := updateMerging(map[string]interface{}{
+			"status": models.FileUploadStatusUploading, "parts": previous.Parts, "offset": previous.Offset,
+		}).Error; updateErr != nil {
+			log.Printf("恢复分片上传 %s 的进度失败: %v", upload.UploadKey, updateErr)
+		}
+		*upload = previous
+		return err
+	}
+
+	result := updateMerging(map[string]interface{}{"status": models.FileUploadStatusCompleted, "file_id": file.ID})
+	if result.Error != nil || result.RowsAffected == 0 {
+		// 上传记录已不在合并中（如已被清理），删除刚保存的文件
+		if err := s.db.Delete(file).Error; err != nil {
+			log.Printf("删除文件记录 %d 失败: %v", file.ID, err)
+		} else if err := storage.Delete(file.StorageKey); err != nil {
+			log.Printf("删除文件 %s 失败: %v", file.StorageKey, err)
+		}
+		if result.Error != nil {
+			return fmt.Errorf("更新分片上传失败: %w", result.Error)
+		}
+		return errors.New("分片上传已失效，请重新上传")
+	}
+	upload.Status = models.FileUploadStatusCompleted
+	upload.FileID = &file.ID
+	upload.File = file
+
+	// 合并后删除分片，删除失败不影响上传结果
+	deleteParts()
+	return nil
+}
+
+// sniffContentType 读取文件开头的内容识别文件类型
+func sniffContentType(content io.ReadCloser, fileName string) (string, error) {
+	defer content.Close()
+
+	head := make([]byte, fileSniffLength)
+	n, err := io.ReadFull(content, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", fmt.Errorf("读取文件失败: %w", err)
+	}
+	return detectContentType(head[:n], fileName), nil
+}
+
+// uploadPartKey 分片在存储中的对象键
+func uploadPartKey(uploadKey string, part int) string {
+	return fmt.Sprintf("uploads/%s/%06d", uploadKey, part)
+}
+
+// partsReader 按顺序读取各分片，读完一个分片后再打开下一个
+type partsReader struct {
+	storage FileStorage
+	keys    []string
+	current io.ReadCloser
+}
+
+func (r *partsReader) Read(p []byte) (int, error) {
+	for {
+		if r.current == nil {
+			if len(r.keys) == 0 {
+				return 0, io.EOF
+			}
+			part, err := r.storage.Get(r.keys[0])
+			if err != nil {
+				return 0, fmt.Errorf("读取分片失败: %w", err)
+			}
+			r.current = part
+			r.keys = r.keys[1:]
+		}
+		n, err := r.current.Read(p)
+		if err == io.EOF {
+			r.current.Close()
+			r.current = nil
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+// Close 关闭正在读取的分片
+func (r *partsReader) Close() error {
+	if r.current == nil {
+		return nil
+	}
+	err := r.current.Close()
+	r.current = nil
+	return err
+}
+
+// OpenFile 打开文件供下载：未关联表单数据的文件仅上传人可下载，已关联的文件需能查看所属实例且文件字段对其可见
+func (s *FileService) OpenFile(fileKey string, userID uint) (*models.FileObject, io.ReadCloser, error) {
+	storage, _, err := getFileStorage()
+	if err != nil {
+		return nil, nil, err
+	}
+	var file models.FileObject
+	if err := s.db.Where("file_key = ?", fileKey).First(&file).Error; err != nil {
+		return nil, nil, ErrFileNotFound
+	}
+	if err := s.checkFileAccess(&file, userID); err != nil {
+		return nil, nil, err
+	}
+
+	content, err := storage.Get(file.StorageKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &file, content, nil
+}
+
+// checkFileAccess 检查用户能否下载文件
+func (s *FileService) checkFileAccess(file *models.FileObject, userID uint) error {
+	if file.FormDataID == nil {
+		if file.UploadedBy != userID {
+			return ErrFileForbidden
+		}
+		return nil
+	}
+
+	var formData models.FormData
+	if err := s.db.First(&formData, *file.FormDataID).Error; err != nil {
+		return ErrFileNotFound
+	}
+	// 未发起流程的表单数据仅提交人和上传人可查看
+	if formData.InstanceID == 0 {
+		if formData.SubmittedBy != userID && file.UploadedBy != userID {
+			return ErrFileForbidden
+		}
+		return nil
+	}
+
+	allowed, err := NewPermissionService().CheckWorkflowPermission(userID, "view_instance", formData.InstanceID)
+	if err != nil {
+		return fmt.Errorf("权限检查失败: %w", err)
+	}
+	if !allowed {
+		return ErrFileForbidden
+	}
+	var instance models.WorkflowInstance
+	if err := s.db.First(&instance, formData.InstanceID).Error; err != nil {
+		return ErrFileNotFound
+	}
+	permissions, err := NewWorkflowService().viewerFieldPermissions(&instance, userID)
+	if err != nil {
+		return err
+	}
+	if permissions != nil && permissions.Permission(file.FieldKey) == models.FieldPermissionHidden {
+		return ErrFileForbidden
+	}
+	return nil
+}
+
+// fileKeysFromValue 解析文件字段的值：文件引用的列表，引用为文件标识或带 file_key 的对象
+func fileKeysFromValue(value interface{}) ([]string, bool) {
+	items, ok := toList(value)
+	if !ok {
+		items = []interface{}{value}
+	}
+	keys := make([]string, 0, len(items))
+	for _, item := range items {
+		switch ref := item.(type) {
+		case string:
+			if ref == "" {
+				return nil, false
+			}
+			keys = append(keys, ref)
+		case map[string]interface{}:
+			key, _ := ref["file_key"].(string)
+			if key == "" {
+				return nil, false
+			}
+			keys = append(keys, key)
+		default:
+			return nil, false
+		}
+	}
+	return keys, true
+}
+
+// attachFormFilesInTx 将表单值中引用的文件关联到表单数据：文件须上传到该字段，
+// 尚未关联的文件只能由上传人引用，已关联的文件只能在原表单数据中继续引用
+func attachFormFilesInTx(tx *gorm.DB, formData *models.FormData, userID uint) error {
+	var attrs []models.FormAttribute
+	if err := tx.Preload("Attribute").
+		Joins("JOIN form_cards ON form_cards.id = form_attributes.card_id AND form_cards.deleted_at IS NULL").
+		Where("form_cards.form_id = ? AND form_attributes.element = ?", formData.FormID, models.ElementTypeFile).
+		Find(&attrs).Error; err != nil {
+		return fmt.Errorf("查询文件字段失败: %w", err)
+	}
+	if len(attrs) == 0 || formData.FormValues == "" {
+		return nil
+	}
+	var values map[string]interface{}
+	if err := json.Unmarshal([]byte(formData.FormValues), &values); err != nil {
+		return fmt.Errorf("表单数据格式错误: %w", err)
+	}
+
+	fieldErrors := make([]FieldError, 0)
+	attachIDs := make([]uint, 0)
+	for _, attr := range attrs {
+		key := attr.Attribute.FieldKey
+		if isEmptyValue(values[key]) {
+			continue
+		}
+		fail := func(message string) {
+			fieldErrors = append(fieldErrors, FieldError{Field: key, Name: attr.Name, Rule: FieldRuleFile, Message: message})
+		}
+		fileKeys, ok := fileKeysFromValue(values[key])
+		if !ok {
+			fail(fmt.Sprintf("%s 必须是文件列表", attr.Name))
+			continue
+		}
+		var files []models.FileObject
+		if err := tx.Where("file_key IN ?", fileKeys).Find(&files).Error; err != nil {
+			return fmt.Errorf("查询文件失败: %w", err)
+		}
+		found := make(map[string]models.FileObject)
+		for _, file := range files {
+			found[file.FileKey] = file
+		}
+		for _, fileKey := range fileKeys {
+			file, exists := found[fileKey]
+			switch {
+			case !exists:
+				fail(fmt.Sprintf("%s 引用的文件 %s 不存在", attr.Name, fileKey))
+			case file.FormID != formData.FormID || file.FieldKey != key:
+				fail(fmt.Sprintf("文件 %s 不是上传到 %s 的文件", file.FileName, attr.Name))
+			case file.FormDataID == nil && file.UploadedBy != userID:
+				fail(fmt.Sprintf("无权使用文件 %s", file.FileName))
+			case file.FormDataID != nil && *file.FormDataID != formData.ID:
+				fail(fmt.Sprintf("文件 %s 已被其他表单使用", file.FileName))
+			case file.FormDataID == nil:
+				attachIDs = append(attachIDs, file.ID)
+			}
+		}
+	}
+	if len(fieldErrors) > 0 {
+		return &FormValidationError{Errors: fieldErrors}
+	}
+	if len(attachIDs) == 0 {
+		return nil
+	}
+	if err := tx.Model(&models.FileObject{}).Where("id IN ? AND form_data_id IS NULL", attachIDs).
+		Update("form_data_id", formData.ID).Error; err != nil {
+		return fmt.Errorf("关联文件失败: %w", err)
+	}
+	return nil
+}
+
+// generateFileKey 生成随机的文件或上传标识
+func generateFileKey() string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// formatFileSize 格式化文件大小
+func formatFileSize(size int64) string {
+	switch {
+	case size >= 1<<20 && size%(1<<20) == 0:
+		return fmt.Sprintf("%dMB", size>>20)
+	case size >= 1<<10 && size%(1<<10) == 0:
+		return fmt.Sprintf("%dKB", size>>10)
+	default:
+		return fmt.Sprintf("%d字节", size)
+	}
+}
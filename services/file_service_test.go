@@ -0,0 +1,147 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"gin-web-api/models"
+)
+
+// failingFileStorage 保存文件（非分片）时返回错误的存储，用于模拟合并失败
+type failingFileStorage struct {
+	FileStorage
+	failFiles bool
+}
+
+func (s *failingFileStorage) Put(key string, content io.Reader, size int64, contentType string) error {
+	if s.failFiles && strings.HasPrefix(key, "files/") {
+		return errors.New("存储不可用")
+	}
+	return s.FileStorage.Put(key, content, size, contentType)
+}
+
+// setupTestFileUpload 创建只接受图片的文件字段和本地存储，返回表单ID和存储
+func setupTestFileUpload(t *testing.T) (uint, *failingFileStorage) {
+	t.Helper()
+
+	local, err := NewLocalFileStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("创建文件存储失败: %v", err)
+	}
+	storage := &failingFileStorage{FileStorage: local}
+	SetFileStorage(storage, 1<<20)
+	t.Cleanup(func() { SetFileStorage(nil, 0) })
+
+	form, err := NewFormService().CreateFormDefinition(&CreateFormRequest{Object: "t", Name: "报销", Key: "expense", Cards: []CreateCardRequest{{
+		Name: "附件",
+		Attributes: []CreateAttributeRequest{{
+			Attribute: CreateFieldAttrRequest{Object: "t", Name: "发票", Key: "invoice", Type: models.DataTypeJSON, Element: models.ElementTypeFile},
+			Element:   models.ElementTypeFile, Name: "发票", Show: true,
+			Validation: map[string]interface{}{"accept": []string{"image/*"}},
+		}},
+	}}}, 1)
+	if err != nil {
+		t.Fatalf("创建表单失败: %v", err)
+	}
+	return form.ID, storage
+}
+
+// testPNG 600字节、按内容识别为 image/png 的文件
+func testPNG() []byte {
+	return append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{1}, 592)...)
+}
+
+func TestUploadChunk(t *testing.T) {
+	setupTestDB(t)
+	formID, storage := setupTestFileUpload(t)
+	s := NewFileService()
+	content := testPNG()
+
+	upload, err := s.CreateUpload(&CreateUploadRequest{FormID: formID, FieldKey: "invoice", FileName: "invoice.png", Size: int64(len(content))}, 1)
+	if err != nil {
+		t.Fatalf("CreateUpload() error = %v", err)
+	}
+	if _, err := s.UploadChunk(upload.UploadKey, 0, content[:300], 2); !errors.Is(err, ErrFileForbidden) {
+		t.Fatalf("其他用户上传分片 error = %v, want ErrFileForbidden", err)
+	}
+	if _, err := s.UploadChunk(upload.UploadKey, 0, content[:300], 1); err != nil {
+		t.Fatalf("UploadChunk() error = %v", err)
+	}
+	// 重复上传同一位置的分片返回已上传的字节数
+	if current, err := s.UploadChunk(upload.UploadKey, 0, content[:300], 1); !errors.Is(err, ErrUploadOffsetMismatch) || current.Offset != 300 {
+		t.Fatalf("重复上传 = %d, %v, want 300, ErrUploadOffsetMismatch", current.Offset, err)
+	}
+
+	// 合并失败时恢复到上传最后一个分片前的进度
+	storage.failFiles = true
+	current, err := s.UploadChunk(upload.UploadKey, 300, content[300:], 1)
+	if err == nil || current.Status != models.FileUploadStatusUploading || current.Offset != 300 || current.Parts != 1 {
+		t.Fatalf("合并失败后 = %+v, %v", current, err)
+	}
+	if saved, _ := s.GetUpload(upload.UploadKey, 1); saved.Status != models.FileUploadStatusUploading || saved.Offset != 300 {
+		t.Fatalf("合并失败后记录 status = %s, offset = %d", saved.Status, saved.Offset)
+	}
+	var files int64
+	s.db.Model(&models.FileObject{}).Count(&files)
+	if files != 0 {
+		t.Fatalf("合并失败不应创建文件记录, got %d", files)
+	}
+
+	storage.failFiles = false
+	current, err = s.UploadChunk(upload.UploadKey, 300, content[300:], 1)
+	if err != nil || current.Status != models.FileUploadStatusCompleted || current.File == nil || current.File.ContentType != "image/png" {
+		t.Fatalf("UploadChunk() = %+v, %v", current, err)
+	}
+	file, reader, err := s.OpenFile(current.File.FileKey, 1)
+	if err != nil {
+		t.Fatalf("OpenFile() error = %v", err)
+	}
+	data, _ := io.ReadAll(reader)
+	reader.Close()
+	if !bytes.Equal(data, content) || file.Size != int64(len(content)) {
+		t.Errorf("合并后的文件内容不一致, size = %d", file.Size)
+	}
+	for i := 0; i < current.Parts; i++ {
+		if _, err := storage.Get(uploadPartKey(upload.UploadKey, i)); !errors.Is(err, ErrFileNotFound) {
+			t.Errorf("合并后应删除分片 %d, error = %v", i, err)
+		}
+	}
+	if _, err := s.UploadChunk(upload.UploadKey, int64(len(content)), content[:1], 1); err == nil {
+		t.Error("已完成的上传不能继续上传分片")
+	}
+}
+
+func TestUploadChunkTypeCheckFailed(t *testing.T) {
+	setupTestDB(t)
+	formID, storage := setupTestFileUpload(t)
+	s := NewFileService()
+	content := []byte(strings.Repeat("这不是图片", 40))
+
+	upload, err := s.CreateUpload(&CreateUploadRequest{FormID: formID, FieldKey: "invoice", FileName: "invoice.png", Size: int64(len(content))}, 1)
+	if err != nil {
+		t.Fatalf("CreateUpload() error = %v", err)
+	}
+	if _, err := s.UploadChunk(upload.UploadKey, 0, content[:100], 1); err != nil {
+		t.Fatalf("UploadChunk() error = %v", err)
+	}
+	current, err := s.UploadChunk(upload.UploadKey, 100, content[100:], 1)
+	if err == nil || !strings.Contains(err.Error(), "text/plain") || current.Status != models.FileUploadStatusFailed {
+		t.Fatalf("类型检查未通过 = %s, %v", current.Status, err)
+	}
+
+	saved, err := s.GetUpload(upload.UploadKey, 1)
+	if err != nil || saved.Status != models.FileUploadStatusFailed || saved.FileID != nil {
+		t.Fatalf("GetUpload() = %+v, %v", saved, err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := storage.Get(uploadPartKey(upload.UploadKey, i)); !errors.Is(err, ErrFileNotFound) {
+			t.Errorf("类型检查未通过时应删除分片 %d, error = %v", i, err)
+		}
+	}
+	if _, err := s.UploadChunk(upload.UploadKey, 100, content[100:], 1); err == nil {
+		t.Error("失败的上传不能继续上传分片")
+	}
+}
@@ -0,0 +1,315 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gin-web-api/config"
+)
+
+// ErrFileNotFound 存储中不存在该对象
+var ErrFileNotFound = errors.New("文件不存在")
+
+// FileStorage 文件存储，对象键由上传服务生成，只包含字母、数字和 / - _ .
+type FileStorage interface {
+	// Put 保存对象，size 为内容长度
+	Put(key string, content io.Reader, size int64, contentType string) error
+	// Get 读取对象，不存在时返回 ErrFileNotFound
+	Get(key string) (io.ReadCloser, error)
+	// Delete 删除对象，不存在时不报错
+	Delete(key string) error
+}
+
+var (
+	fileStorageMu sync.RWMutex
+	fileStorage   FileStorage
+	fileMaxSize   int64 = 50 << 20
+)
+
+// SetFileStorage 设置文件存储和单个文件的大小上限（字节）
+func SetFileStorage(storage FileStorage, maxSize int64) {
+	fileStorageMu.Lock()
+	defer fileStorageMu.Unlock()
+	fileStorage = storage
+	if maxSize > 0 {
+		fileMaxSize = maxSize
+	}
+}
+
+// getFileStorage 获取文件存储和单个文件的大小上限
+func getFileStorage() (FileStorage, int64, error) {
+	fileStorageMu.RLock()
+	defer fileStorageMu.RUnlock()
+	if fileStorage == nil {
+		return nil, 0, errors.New("未配置文件存储")
+	}
+	return fileStorage, fileMaxSize, nil
+}
+
+// NewFileStorage 按配置创建文件存储：local 保存到本地目录，s3 保存到S3兼容的对象存储（如MinIO）
+func NewFileStorage(cfg config.StorageConfig) (FileStorage, error) {
+	switch cfg.Driver {
+	case "", "local":
+		return NewLocalFileStorage(cfg.LocalDir)
+	case "s3":
+		if cfg.S3Endpoint == "" || cfg.S3Bucket == "" || cfg.S3AccessKey == "" || cfg.S3SecretKey == "" {
+			return nil, errors.New("S3存储需要配置地址、存储桶和访问密钥")
+		}
+		return NewS3FileStorage(cfg), nil
+	default:
+		return nil, fmt.Errorf("不支持的文件存储类型: %s", cfg.Driver)
+	}
+}
+
+// LocalFileStorage 本地目录存储，适用于单副本部署或挂载了共享目录的多副本部署
+type LocalFileStorage struct {
+	root string
+}
+
+// NewLocalFileStorage 创建本地目录存储，目录不存在时自动创建
+func NewLocalFileStorage(root string) (*LocalFileStorage, error) {
+	if root == "" {
+		root = "./uploads"
+	}
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("创建文件存储目录失败: %w", err)
+	}
+	return &LocalFileStorage{root: root}, nil
+}
+
+// path 对象键对应的本地路径，拒绝跳出存储目录的键
+func (s *LocalFileStorage) path(key string) (string, error) {
+	cleaned := filepath.Clean("/" + key)
+	if key == "" || cleaned != "/"+key {
+		return "", fmt.Errorf("无效的对象键: %s", key)
+	}
+	return filepath.Join(s.root, filepath.FromSlash(cleaned)), nil
+}
+
+// Put 先写入临时文件再重命名，读取方不会看到写了一半的文件
+func (s *LocalFileStorage) Put(key string, content io.Reader, size int64, contentType string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("创建目录失败: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("创建文件失败: %w", err)
+	}
+	written, err := io.Copy(tmp, content)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && written != size {
+		err = fmt.Errorf("文件大小 %d 与声明的 %d 不一致", written, size)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("写入文件失败: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("保存文件失败: %w", err)
+	}
+	return nil
+}
+
+// Get 打开文件
+func (s *LocalFileStorage) Get(key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrFileNotFound
+	}
+	return file, err
+}
+
+// Delete 删除文件
+func (s *LocalFileStorage) Delete(key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// S3FileStorage S3兼容的对象存储，使用路径风格的地址和 Signature V4 签名
+type S3FileStorage struct {
+	endpoint  string
+	region    string
+	bucket    string
+	accessKey string
+	secretKey string
+	scheme    string
+	client    *http.Client
+}
+
+// NewS3FileStorage 创建S3存储，存储桶需预先创建
+func NewS3FileStorage(cfg config.StorageConfig) *S3FileStorage {
+	scheme := "http"
+	if cfg.S3UseSSL {
+		scheme = "https"
+	}
+	region := cfg.S3Region
+	if region == "" {
+		region = "us-east-1"
+	}
+	return &S3FileStorage{
+		endpoint:  strings.TrimSuffix(cfg.S3Endpoint, "/"),
+		region:    region,
+		bucket:    cfg.S3Bucket,
+		accessKey: cfg.S3AccessKey,
+		secretKey: cfg.S3SecretKey,
+		scheme:    scheme,
+		client:    &http.Client{Timeout: 10 * time.Minute},
+	}
+}
+
+// s3EmptyPayloadHash 空请求体的SHA-256
+const s3EmptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// Put 上传对象，内容不参与签名（UNSIGNED-PAYLOAD），以便流式上传
+func (s *S3FileStorage) Put(key string, content io.Reader, size int64, contentType string) error {
+	req, err := s.newRequest(http.MethodPut, key, content, "UNSIGNED-PAYLOAD")
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("上传到对象存储失败: %w", err)
+	}
+	defer resp.Body.Close()
+	return s3ResponseError(resp)
+}
+
+// Get 下载对象
+func (s *S3FileStorage) Get(key string) (io.ReadCloser, error) {
+	req, err := s.newRequest(http.MethodGet, key, nil, s3EmptyPayloadHash)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("从对象存储下载失败: %w", err)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrFileNotFound
+	}
+	if err := s3ResponseError(resp); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Delete 删除对象
+func (s *S3FileStorage) Delete(key string) error {
+	req, err := s.newRequest(http.MethodDelete, key, nil, s3EmptyPayloadHash)
+	if err != nil {
+		return err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("从对象存储删除失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	return s3ResponseError(resp)
+}
+
+// newRequest 创建签名后的请求
+func (s *S3FileStorage) newRequest(method, key string, body io.Reader, payloadHash string) (*http.Request, error) {
+	path := "/" + s3URIEncode(s.bucket) + "/" + s3URIEncode(key)
+	req, err := http.NewRequest(method, s.scheme+"://"+s.endpoint+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("创建对象存储请求失败: %w", err)
+	}
+
+	now := time.Now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		method,
+		path,
+		"",
+		"host:" + req.URL.Host,
+		"x-amz-content-sha256:" + payloadHash,
+		"x-amz-date:" + amzDate,
+		"",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := date + "/" + s.region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	signingKey := []byte("AWS4" + s.secretKey)
+	for _, part := range []string{date, s.region, "s3", "aws4_request"} {
+		signingKey = hmacSHA256(signingKey, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey, scope, signedHeaders, signature))
+	return req, nil
+}
+
+// hmacSHA256 计算HMAC-SHA256
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// s3URIEncode 按S3签名规则编码路径：保留字母、数字、- _ . ~ 和 /，其余字节编码为 %XX
+func s3URIEncode(path string) string {
+	var builder strings.Builder
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		if ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z') || ('0' <= c && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' || c == '/' {
+			builder.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&builder, "%%%02X", c)
+	}
+	return builder.String()
+}
+
+// s3ResponseError 非2xx响应转换为错误，附带响应内容的开头部分
+func s3ResponseError(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("对象存储返回 %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+}
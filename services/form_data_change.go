@@ -0,0 +1,174 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"gin-web-api/models"
+
+	"gorm.io/gorm"
+)
+
+// ErrFormDataConflict 表单数据在审批人查看后已被他人修改
+var ErrFormDataConflict = errors.New("表单数据已被修改，请刷新后重试")
+
+// ErrFormVersionRequired 修改表单数据时没有携带读取时的表单数据版本
+var ErrFormVersionRequired = errors.New("修改表单数据时必须提供 form_version")
+
+// formChangeSource 表单值修改的来源，发起和重新提交时没有任务和节点
+type formChangeSource struct {
+	TaskID  *uint
+	NodeKey string
+	UserID  uint
+}
+
+// FieldChangeHistory 一个字段的变更历史，按修改时间排列
+type FieldChangeHistory struct {
+	Field   string                  `json:"field"`   // 字段标识
+	Name    string                  `json:"name"`    // 字段名称，字段已从表单定义中移除时为字段标识
+	Changes []models.FormDataChange `json:"changes"` // 变更记录
+}
+
+// saveFormValuesInTx 以乐观锁保存表单值，关联新引用的文件并记录变化的字段：
+// 仅当版本号仍为读取时的版本时更新，否则返回 ErrFormDataConflict
+func saveFormValuesInTx(tx *gorm.DB, formData *models.FormData, formValues string, source formChangeSource) error {
+	oldValues := formData.FormValues
+	result := tx.Model(&models.FormData{}).
+		Where("id = ? AND version = ?", formData.ID, formData.Version).
+		Updates(map[string]interface{}{
+			"form_values": formValues,
+			"version":     gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		return fmt.Errorf("更新表单数据失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrFormDataConflict
+	}
+
+	formData.FormValues = formValues
+	formData.Version++
+	if err := attachFormFilesInTx(tx, formData, source.UserID); err != nil {
+		return err
+	}
+	return recordFormDataChangesInTx(tx, formData, oldValues, formValues, source)
+}
+
+// recordFormDataChangesInTx 比较修改前后的表单值，为每个变化的字段记录一条变更，版本为修改后的表单数据版本
+func recordFormDataChangesInTx(tx *gorm.DB, formData *models.FormData, oldValues, newValues string, source formChangeSource) error {
+	before, err := parseFormValues(oldValues)
+	if err != nil {
+		return err
+	}
+	after, err := parseFormValues(newValues)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(before)+len(after))
+	for key := range before {
+		keys = append(keys, key)
+	}
+	for key := range after {
+		if _, exists := before[key]; !exists {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	changes := make([]models.FormDataChange, 0)
+	for _, key := range keys {
+		oldValue, hadOld := before[key]
+		newValue, hasNew := after[key]
+		if hadOld && hasNew && reflect.DeepEqual(oldValue, newValue) {
+			continue
+		}
+		change := models.FormDataChange{
+			FormDataID: formData.ID,
+			InstanceID: formData.InstanceID,
+			TaskID:     source.TaskID,
+			NodeKey:    source.NodeKey,
+			FieldKey:   key,
+			Version:    formData.Version,
+			ChangedBy:  source.UserID,
+		}
+		if hadOld {
+			data, _ := json.Marshal(oldValue)
+			change.OldValue = string(data)
+		}
+		if hasNew {
+			data, _ := json.Marshal(newValue)
+			change.NewValue = string(data)
+		}
+		changes = append(changes, change)
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	if err := tx.Create(&changes).Error; err != nil {
+		return fmt.Errorf("记录表单字段变更失败: %w", err)
+	}
+	return nil
+}
+
+// parseFormValues 解析表单值JSON，空值返回空表
+func parseFormValues(formValues string) (map[string]interface{}, error) {
+	values := make(map[string]interface{})
+	if formValues == "" {
+		return values, nil
+	}
+	if err := json.Unmarshal([]byte(formValues), &values); err != nil {
+		return nil, fmt.Errorf("解析表单值失败: %w", err)
+	}
+	return values, nil
+}
+
+// GetFormDataChanges 获取实例表单数据各字段的变更历史，按表单定义的字段顺序返回，不包含查看人不可见的字段
+func (s *WorkflowService) GetFormDataChanges(instanceID, userID uint) ([]FieldChangeHistory, error) {
+	var instance models.WorkflowInstance
+	if err := s.db.Preload("FormData.Form.Cards.Attributes.Attribute").First(&instance, instanceID).Error; err != nil {
+		return nil, fmt.Errorf("实例不存在: %w", err)
+	}
+	if instance.FormData == nil {
+		return nil, errors.New("该实例没有关联的表单数据")
+	}
+	permissions, err := s.viewerFieldPermissions(&instance, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []models.FormDataChange
+	if err := s.db.Preload("Changer").Where("form_data_id = ?", instance.FormData.ID).
+		Order("id").Find(&changes).Error; err != nil {
+		return nil, fmt.Errorf("查询表单字段变更失败: %w", err)
+	}
+
+	histories := make([]FieldChangeHistory, 0)
+	index := make(map[string]int)
+	for _, card := range instance.FormData.Form.Cards {
+		for _, attr := range card.Attributes {
+			key := attr.Attribute.FieldKey
+			if _, exists := index[key]; exists || permissions.Permission(key) == models.FieldPermissionHidden {
+				continue
+			}
+			index[key] = len(histories)
+			histories = append(histories, FieldChangeHistory{Field: key, Name: attr.Name, Changes: make([]models.FormDataChange, 0)})
+		}
+	}
+	for _, change := range changes {
+		if permissions.Permission(change.FieldKey) == models.FieldPermissionHidden {
+			continue
+		}
+		i, exists := index[change.FieldKey]
+		if !exists {
+			i = len(histories)
+			index[change.FieldKey] = i
+			histories = append(histories, FieldChangeHistory{Field: change.FieldKey, Name: change.FieldKey})
+		}
+		histories[i].Changes = append(histories[i].Changes, change)
+	}
+	return histories, nil
+}
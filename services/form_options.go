@@ -0,0 +1,667 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"gin-web-api/models"
+	redisClient "gin-web-api/redis"
+
+	"gorm.io/gorm"
+)
+
+// 选项数据源类型
+const (
+	OptionSourceStatic    = "static"    // 字段的 options 配置
+	OptionSourceEntity    = "entity"    // 系统内的用户、部门、角色
+	OptionSourceReference = "reference" // 其他对象已提交的表单数据中的字段
+	OptionSourceRemote    = "remote"    // 远程HTTP接口，响应缓存在Redis
+)
+
+// 选项数据源的默认设置
+const (
+	optionRemoteCacheSeconds = 300
+	optionRemoteTimeout      = 10 * time.Second
+	optionRemoteMaxBody      = 5 << 20
+	optionReferenceLimit     = 10000 // 引用其他对象时读取的表单数据上限
+	optionCacheKeyPrefix     = "form:options:"
+)
+
+// OptionSource 字段的选项数据源（FormAttribute.DataSource）。未配置时，字段属性设置了 parentObject
+// 则按 parentObject/joinColumn/joinColumnZh 查找：parentObject 为系统实体时查实体，否则引用该对象的表单数据
+type OptionSource struct {
+	Type          string            `json:"type"`                     // 数据源类型
+	Entity        string            `json:"entity,omitempty"`         // entity：users/departments/roles
+	Object        string            `json:"object,omitempty"`         // reference：对象标识（表单定义的 object）
+	ValueField    string            `json:"value_field,omitempty"`    // 选项值取自的字段，entity 默认 id，remote 默认 value
+	LabelField    string            `json:"label_field,omitempty"`    // 选项名称取自的字段，entity 默认 name，remote 默认 label
+	URL           string            `json:"url,omitempty"`            // remote：接口地址，以GET请求获取全部选项
+	Headers       map[string]string `json:"headers,omitempty"`        // remote：请求头，如认证信息
+	ItemsPath     string            `json:"items_path,omitempty"`     // remote：响应中选项列表的路径，如 data.items，为空时响应本身是列表
+	ChildrenField string            `json:"children_field,omitempty"` // remote：下级选项所在的字段，用于级联和树形选择
+	CacheSeconds  int               `json:"cache_seconds,omitempty"`  // remote：缓存时长，默认300秒
+}
+
+// Option 选项，树形选项按层级加载，Path 为搜索结果从根到该选项的值
+type Option struct {
+	Value    interface{}   `json:"value"`
+	Label    string        `json:"label"`
+	Path     []interface{} `json:"path,omitempty"`
+	IsLeaf   bool          `json:"is_leaf"`
+	Children []Option      `json:"-"`
+}
+
+// OptionQuery 选项查询条件
+type OptionQuery struct {
+	Keyword  string // 按名称模糊搜索，树形选项返回全部层级中匹配的选项
+	Parent   string // 返回该选项的下级选项，为空时返回第一级
+	Page     int
+	PageSize int
+}
+
+// optionEntity 可作为选项数据源的系统实体
+type optionEntity struct {
+	table   string
+	columns map[string]string // 可用字段 -> 数据库列
+	parent  string            // 上级列，树形实体按树加载
+}
+
+// optionEntities 可作为选项数据源的系统实体
+var optionEntities = map[string]*optionEntity{
+	"users": {
+		table:   "users",
+		columns: map[string]string{"id": "id", "username": "username", "email": "email", "name": "full_name", "full_name": "full_name"},
+	},
+	"departments": {
+		table:   "departments",
+		columns: map[string]string{"id": "id", "name": "name", "code": "code"},
+		parent:  "parent_id",
+	},
+	"roles": {
+		table:   "roles",
+		columns: map[string]string{"id": "id", "name": "name", "code": "code"},
+	},
+}
+
+// optionEntityAliases 字段属性 parentObject 中常用的实体名称
+var optionEntityAliases = map[string]string{
+	"user": "users", "staff": "users",
+	"department": "departments", "hrdepartment": "departments",
+	"role": "roles",
+}
+
+// optionElements 支持选项的表单元素
+var optionElements = map[string]bool{
+	models.ElementTypeSelect:     true,
+	models.ElementTypeRadio:      true,
+	models.ElementTypeCheckbox:   true,
+	models.ElementTypeCascader:   true,
+	models.ElementTypeTreeSelect: true,
+}
+
+// lookupOptionEntity 按名称或别名查找系统实体
+func lookupOptionEntity(name string) (string, *optionEntity) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if alias, ok := optionEntityAliases[name]; ok {
+		name = alias
+	}
+	return name, optionEntities[name]
+}
+
+// ParseOptionSource 解析并检查选项数据源，空配置返回nil
+func ParseOptionSource(data string) (*OptionSource, error) {
+	if strings.TrimSpace(data) == "" || data == "null" {
+		return nil, nil
+	}
+	var source OptionSource
+	if err := json.Unmarshal([]byte(data), &source); err != nil {
+		return nil, fmt.Errorf("选项数据源格式错误: %w", err)
+	}
+	if err := source.validate(); err != nil {
+		return nil, err
+	}
+	return &source, nil
+}
+
+// validate 检查数据源配置
+func (s *OptionSource) validate() error {
+	switch s.Type {
+	case OptionSourceEntity:
+		name, entity := lookupOptionEntity(s.Entity)
+		if entity == nil {
+			return fmt.Errorf("不支持的选项实体: %s", s.Entity)
+		}
+		s.Entity = name
+		for _, field := range []string{s.ValueField, s.LabelField} {
+			if _, ok := entity.columns[field]; field != "" && !ok {
+				return fmt.Errorf("实体 %s 没有字段 %s", name, field)
+			}
+		}
+	case OptionSourceReference:
+		if s.Object == "" || s.ValueField == "" {
+			return errors.New("引用其他对象的选项需要配置对象和值字段")
+		}
+	case OptionSourceRemote:
+		parsed, err := url.Parse(s.URL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("远程选项地址无效: %s", s.URL)
+		}
+		if s.CacheSeconds < 0 {
+			return errors.New("远程选项的缓存时长不能为负数")
+		}
+	case OptionSourceStatic:
+	default:
+		return fmt.Errorf("不支持的选项数据源类型: %s", s.Type)
+	}
+	return nil
+}
+
+// resolveOptionSource 字段生效的选项数据源：配置的数据源、parentObject 查找或 options 配置，字段不支持选项时返回nil
+func resolveOptionSource(attr *models.FormAttribute) (*OptionSource, error) {
+	if !optionElements[attr.Element] {
+		return nil, nil
+	}
+	source, err := ParseOptionSource(attr.DataSource)
+	if err != nil || source != nil {
+		return source, err
+	}
+
+	if parentObject := attr.Attribute.ParentObject; parentObject != "" {
+		if name, entity := lookupOptionEntity(parentObject); entity != nil {
+			source = &OptionSource{Type: OptionSourceEntity, Entity: name, ValueField: attr.Attribute.JoinColumn, LabelField: attr.Attribute.JoinColumnZh}
+			for _, field := range []*string{&source.ValueField, &source.LabelField} {
+				if _, ok := entity.columns[*field]; !ok {
+					*field = ""
+				}
+			}
+			return source, nil
+		}
+		if attr.Attribute.JoinColumn != "" {
+			return &OptionSource{Type: OptionSourceReference, Object: parentObject, ValueField: attr.Attribute.JoinColumn, LabelField: attr.Attribute.JoinColumnZh}, nil
+		}
+	}
+	if strings.TrimSpace(attr.Options) != "" {
+		return &OptionSource{Type: OptionSourceStatic}, nil
+	}
+	return nil, nil
+}
+
+// optionProvider 选项数据源的查询
+type optionProvider interface {
+	// search 查询选项，返回当前页和总数
+	search(query OptionQuery) ([]Option, int64, error)
+	// missing 返回不在数据源中的值
+	missing(values []interface{}) ([]interface{}, error)
+}
+
+// newOptionProvider 创建数据源的查询，fetchRemote 为 false 时远程选项只读取缓存
+func newOptionProvider(db *gorm.DB, attr *models.FormAttribute, source *OptionSource, fetchRemote bool) (optionProvider, error) {
+	switch source.Type {
+	case OptionSourceStatic:
+		var items []interface{}
+		if err := json.Unmarshal([]byte(attr.Options), &items); err != nil {
+			return nil, fmt.Errorf("字段 %s 的选项格式错误: %w", attr.Name, err)
+		}
+		return memoryOptions(buildOptions(items, "value", "label", "children")), nil
+	case OptionSourceEntity:
+		_, entity := lookupOptionEntity(source.Entity)
+		if entity == nil {
+			return nil, fmt.Errorf("不支持的选项实体: %s", source.Entity)
+		}
+		provider := &entityOptions{db: db, entity: entity, valueColumn: entity.columns["id"], labelColumn: entity.columns["name"]}
+		if source.ValueField != "" {
+			provider.valueColumn = entity.columns[source.ValueField]
+		}
+		if source.LabelField != "" {
+			provider.labelColumn = entity.columns[source.LabelField]
+		}
+		if entity.parent != "" {
+			return provider.loadTree()
+		}
+		return provider, nil
+	case OptionSourceReference:
+		return loadReferenceOptions(db, source)
+	case OptionSourceRemote:
+		return loadRemoteOptions(source, fetchRemote)
+	default:
+		return nil, fmt.Errorf("不支持的选项数据源类型: %s", source.Type)
+	}
+}
+
+// SearchFieldOptions 查询表单字段的选项，支持关键字搜索、分页和树形选项的逐级加载
+func (s *FormService) SearchFieldOptions(formID uint, fieldKey string, query OptionQuery) ([]Option, int64, error) {
+	attr, err := formFieldInTx(s.db, formID, fieldKey)
+	if err != nil {
+		return nil, 0, err
+	}
+	source, err := resolveOptionSource(attr)
+	if err != nil {
+		return nil, 0, fmt.Errorf("字段 %s 的%w", attr.Name, err)
+	}
+	if source == nil {
+		return nil, 0, fmt.Errorf("字段 %s 没有配置选项", attr.Name)
+	}
+	provider, err := newOptionProvider(s.db, attr, source, true)
+	if err != nil {
+		return nil, 0, err
+	}
+	return provider.search(query)
+}
+
+// fieldOptionChecker 在一次表单验证中检查提交的值在字段的选项数据源中，每个数据源只加载一次（明细表各行共用）。
+// 验证在事务中执行时不请求远程接口，只使用Redis中缓存的远程选项，未缓存时字段验证不通过
+type fieldOptionChecker struct {
+	db          *gorm.DB
+	fetchRemote bool
+	providers   map[string]optionProvider
+}
+
+// newFieldOptionChecker 创建选项检查，fetchRemote 为 false 时不请求远程接口
+func newFieldOptionChecker(db *gorm.DB, fetchRemote bool) *fieldOptionChecker {
+	return &fieldOptionChecker{db: db, fetchRemote: fetchRemote, providers: make(map[string]optionProvider)}
+}
+
+// check 检查字段的值，key 为字段标识（明细表的列为 明细表标识.列标识），同一标识复用已加载的数据源；
+// 单选、多选和下拉的静态选项已由 optionValues 检查
+func (c *fieldOptionChecker) check(key string, attr *models.FormAttribute, value interface{}) (*FieldError, error) {
+	source, err := resolveOptionSource(attr)
+	if err != nil {
+		return nil, fmt.Errorf("字段 %s 的%w", attr.Name, err)
+	}
+	if source == nil {
+		return nil, nil
+	}
+	if _, checked := optionValues(attr); checked && source.Type == OptionSourceStatic {
+		return nil, nil
+	}
+
+	values, ok := toList(value)
+	if !ok {
+		values = []interface{}{value}
+	}
+	provider, loaded := c.providers[key]
+	if !loaded {
+		provider, err = newOptionProvider(c.db, attr, source, c.fetchRemote)
+		if errors.Is(err, errRemoteOptionsNotCached) {
+			return &FieldError{
+				Field: attr.Attribute.FieldKey, Name: attr.Name, Rule: FieldRuleOption,
+				Message: fmt.Sprintf("无法验证 %s 的选项：%s", attr.Name, err.Error()),
+			}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		c.providers[key] = provider
+	}
+	missing, err := provider.missing(values)
+	if err != nil {
+		return nil, fmt.Errorf("获取字段 %s 的选项失败: %w", attr.Name, err)
+	}
+	if len(missing) == 0 {
+		return nil, nil
+	}
+	return &FieldError{
+		Field: attr.Attribute.FieldKey, Name: attr.Name, Rule: FieldRuleOption,
+		Message: fmt.Sprintf("%s 的值 %s 不在可选范围内", attr.Name, toString(missing[0])),
+	}, nil
+}
+
+// buildOptions 将JSON列表转换为选项，列表项可以是值本身或带值、名称和下级选项的对象
+func buildOptions(items []interface{}, valueField, labelField, childrenField string) []Option {
+	options := make([]Option, 0, len(items))
+	for _, item := range items {
+		object, ok := item.(map[string]interface{})
+		if !ok {
+			options = append(options, Option{Value: item, Label: toString(item), IsLeaf: true})
+			continue
+		}
+		value, _ := lookupPath(object, valueField)
+		label, _ := lookupPath(object, labelField)
+		option := Option{Value: value, Label: toString(label)}
+		if label == nil {
+			option.Label = toString(value)
+		}
+		if children, _ := lookupPath(object, childrenField); children != nil {
+			if items, ok := children.([]interface{}); ok {
+				option.Children = buildOptions(items, valueField, labelField, childrenField)
+			}
+		}
+		option.IsLeaf = len(option.Children) == 0
+		options = append(options, option)
+	}
+	return options
+}
+
+// memoryOptions 已全部加载的选项（静态、引用、远程和树形实体）
+type memoryOptions []Option
+
+func (o memoryOptions) search(query OptionQuery) ([]Option, int64, error) {
+	matched := make([]Option, 0)
+	switch {
+	case query.Keyword != "":
+		keyword := strings.ToLower(query.Keyword)
+		var walk func(options []Option, path []interface{})
+		walk = func(options []Option, path []interface{}) {
+			for _, option := range options {
+				current := append(append([]interface{}{}, path...), option.Value)
+				if strings.Contains(strings.ToLower(option.Label), keyword) || toString(option.Value) == query.Keyword {
+					match := Option{Value: option.Value, Label: option.Label, IsLeaf: option.IsLeaf}
+					if len(current) > 1 {
+						match.Path = current
+					}
+					matched = append(matched, match)
+				}
+				walk(option.Children, current)
+			}
+		}
+		walk(o, nil)
+	case query.Parent != "":
+		if parent := findOption(o, query.Parent); parent != nil {
+			matched = append(matched, parent.Children...)
+		}
+	default:
+		matched = append(matched, o...)
+	}
+	return paginateOptions(matched, query), int64(len(matched)), nil
+}
+
+func (o memoryOptions) missing(values []interface{}) ([]interface{}, error) {
+	known := make(map[string]bool)
+	var walk func(options []Option)
+	walk = func(options []Option) {
+		for _, option := range options {
+			known[toString(option.Value)] = true
+			walk(option.Children)
+		}
+	}
+	walk(o)
+
+	missing := make([]interface{}, 0)
+	for _, value := range values {
+		if !known[toString(value)] {
+			missing = append(missing, value)
+		}
+	}
+	return missing, nil
+}
+
+// findOption 在树形选项中查找值对应的选项
+func findOption(options []Option, value string) *Option {
+	for i := range options {
+		if toString(options[i].Value) == value {
+			return &options[i]
+		}
+		if found := findOption(options[i].Children, value); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// paginateOptions 截取当前页
+func paginateOptions(options []Option, query OptionQuery) []Option {
+	start := (query.Page - 1) * query.PageSize
+	if query.Page < 1 || query.PageSize < 1 {
+		return options
+	}
+	if start >= len(options) {
+		return []Option{}
+	}
+	end := start + query.PageSize
+	if end > len(options) {
+		end = len(options)
+	}
+	return options[start:end]
+}
+
+// entityOptions 系统实体的选项，在数据库中搜索和分页，只包含启用的记录
+type entityOptions struct {
+	db          *gorm.DB
+	entity      *optionEntity
+	valueColumn string
+	labelColumn string
+}
+
+// scope 实体中可选的记录
+func (e *entityOptions) scope() *gorm.DB {
+	return e.db.Table(e.entity.table).Where("deleted_at IS NULL AND is_active = ?", true)
+}
+
+// rows 读取选项值和名称
+func (e *entityOptions) rows(query *gorm.DB) ([]map[string]interface{}, error) {
+	var rows []map[string]interface{}
+	selectColumns := fmt.Sprintf("%s AS option_value, %s AS option_label", e.valueColumn, e.labelColumn)
+	if e.entity.parent != "" {
+		selectColumns += fmt.Sprintf(", id AS option_id, %s AS option_parent", e.entity.parent)
+	}
+	if err := query.Select(selectColumns).Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("查询%s失败: %w", e.entity.table, err)
+	}
+	return rows, nil
+}
+
+func (e *entityOptions) search(query OptionQuery) ([]Option, int64, error) {
+	if query.Parent != "" {
+		return []Option{}, 0, nil
+	}
+	scope := e.scope()
+	if query.Keyword != "" {
+		scope = scope.Where(fmt.Sprintf("LOWER(%s) LIKE ?", e.labelColumn), "%"+strings.ToLower(query.Keyword)+"%")
+	}
+	var total int64
+	if err := scope.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("查询%s失败: %w", e.entity.table, err)
+	}
+	if query.Page > 0 && query.PageSize > 0 {
+		scope = scope.Offset((query.Page - 1) * query.PageSize).Limit(query.PageSize)
+	}
+	rows, err := e.rows(scope.Order(e.valueColumn))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	options := make([]Option, 0, len(rows))
+	for _, row := range rows {
+		options = append(options, Option{Value: row["option_value"], Label: toString(row["option_label"]), IsLeaf: true})
+	}
+	return options, total, nil
+}
+
+func (e *entityOptions) missing(values []interface{}) ([]interface{}, error) {
+	// 按ID查找时只接受整数，其他字段按文本比较
+	lookup := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		if e.valueColumn == "id" {
+			if number, ok := toFloat(value); ok && number == float64(int64(number)) {
+				lookup = append(lookup, int64(number))
+			}
+			continue
+		}
+		lookup = append(lookup, toString(value))
+	}
+
+	known := make(map[string]bool)
+	if len(lookup) > 0 {
+		rows, err := e.rows(e.scope().Where(fmt.Sprintf("%s IN ?", e.valueColumn), lookup))
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			known[toString(row["option_value"])] = true
+		}
+	}
+
+	missing := make([]interface{}, 0)
+	for _, value := range values {
+		if !known[toString(value)] {
+			missing = append(missing, value)
+		}
+	}
+	return missing, nil
+}
+
+// loadTree 加载树形实体（部门）的全部启用记录，按上级关系组成树
+func (e *entityOptions) loadTree() (memoryOptions, error) {
+	rows, err := e.rows(e.scope().Order("sort_order").Order("id"))
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[string]*Option, len(rows))
+	children := make(map[string][]string)
+	roots := make([]string, 0)
+	for _, row := range rows {
+		id := toString(row["option_id"])
+		nodes[id] = &Option{Value: row["option_value"], Label: toString(row["option_label"])}
+		parent := toString(row["option_parent"])
+		children[parent] = append(children[parent], id)
+	}
+	for _, row := range rows {
+		// 上级为空或已停用的部门作为第一级
+		id := toString(row["option_id"])
+		if parent := toString(row["option_parent"]); parent == "" || parent == "0" || nodes[parent] == nil {
+			roots = append(roots, id)
+		}
+	}
+
+	var build func(ids []string, visited map[string]bool) []Option
+	build = func(ids []string, visited map[string]bool) []Option {
+		options := make([]Option, 0, len(ids))
+		for _, id := range ids {
+			if visited[id] {
+				continue
+			}
+			visited[id] = true
+			option := *nodes[id]
+			option.Children = build(children[id], visited)
+			option.IsLeaf = len(option.Children) == 0
+			options = append(options, option)
+		}
+		return options
+	}
+	return memoryOptions(build(roots, make(map[string]bool))), nil
+}
+
+// loadReferenceOptions 加载引用对象的选项：该对象各表单已提交的数据（不含被拒绝和未发起流程的草稿）中
+// 值字段的取值，按值去重
+func loadReferenceOptions(db *gorm.DB, source *OptionSource) (memoryOptions, error) {
+	var rows []models.FormData
+	if err := db.Select("form_data.form_values").
+		Joins("JOIN form_definitions ON form_definitions.id = form_data.form_id AND form_definitions.deleted_at IS NULL").
+		Where("form_definitions.object_key = ? AND form_data.status <> ? AND (form_data.status <> ? OR form_data.instance_id <> 0)",
+			source.Object, models.FormStatusRejected, models.FormStatusDraft).
+		Order("form_data.id DESC").Limit(optionReferenceLimit).Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("查询对象 %s 的数据失败: %w", source.Object, err)
+	}
+
+	seen := make(map[string]bool)
+	options := make(memoryOptions, 0)
+	for _, row := range rows {
+		var values map[string]interface{}
+		if json.Unmarshal([]byte(row.FormValues), &values) != nil {
+			continue
+		}
+		value := values[source.ValueField]
+		if isEmptyValue(value) || seen[toString(value)] {
+			continue
+		}
+		seen[toString(value)] = true
+		option := Option{Value: value, Label: toString(values[source.LabelField]), IsLeaf: true}
+		if option.Label == "" {
+			option.Label = toString(value)
+		}
+		options = append(options, option)
+	}
+	sort.SliceStable(options, func(i, j int) bool { return options[i].Label < options[j].Label })
+	return options, nil
+}
+
+// errRemoteOptionsNotCached 不请求远程接口时，Redis中没有缓存的远程选项
+var errRemoteOptionsNotCached = errors.New("远程选项尚未缓存，请重新加载选项后再提交")
+
+// loadRemoteOptions 加载远程接口的选项，响应内容缓存在Redis，缓存失效前不重复请求；
+// fetch 为 false 时只读取缓存
+func loadRemoteOptions(source *OptionSource, fetch bool) (memoryOptions, error) {
+	config, _ := json.Marshal(source)
+	hash := sha256.Sum256(config)
+	cacheKey := optionCacheKeyPrefix + hex.EncodeToString(hash[:])
+
+	var body []byte
+	if redisClient.Client != nil {
+		if cached, err := redisClient.Get(cacheKey); err == nil {
+			body = []byte(cached)
+		}
+	}
+	if body == nil && !fetch {
+		return nil, errRemoteOptionsNotCached
+	}
+	if body == nil {
+		fetched, err := fetchRemoteOptions(source)
+		if err != nil {
+			return nil, err
+		}
+		body = fetched
+		if redisClient.Client != nil {
+			ttl := source.CacheSeconds
+			if ttl == 0 {
+				ttl = optionRemoteCacheSeconds
+			}
+			redisClient.Set(cacheKey, string(body), time.Duration(ttl)*time.Second)
+		}
+	}
+
+	var data interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil, fmt.Errorf("远程选项格式错误: %w", err)
+	}
+	if object, ok := data.(map[string]interface{}); ok && source.ItemsPath != "" {
+		data, _ = lookupPath(object, source.ItemsPath)
+	}
+	items, ok := data.([]interface{})
+	if !ok {
+		return nil, errors.New("远程选项的响应中没有选项列表")
+	}
+	valueField, labelField := source.ValueField, source.LabelField
+	if valueField == "" {
+		valueField = "value"
+	}
+	if labelField == "" {
+		labelField = "label"
+	}
+	return memoryOptions(buildOptions(items, valueField, labelField, source.ChildrenField)), nil
+}
+
+// fetchRemoteOptions 请求远程接口
+func fetchRemoteOptions(source *OptionSource) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, source.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建远程选项请求失败: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	for key, value := range source.Headers {
+		req.Header.Set(key, value)
+	}
+	client := &http.Client{Timeout: optionRemoteTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求远程选项失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("远程选项接口返回 %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, optionRemoteMaxBody))
+	if err != nil {
+		return nil, fmt.Errorf("读取远程选项失败: %w", err)
+	}
+	return body, nil
+}
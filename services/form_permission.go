@@ -0,0 +1,370 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"gin-web-api/models"
+
+	"gorm.io/gorm"
+)
+
+// FieldPermissions 节点的表单字段权限（WorkflowNode.FieldPermissions），字段标识 -> 权限
+type FieldPermissions map[string]string
+
+// ParseFieldPermissions 解析表单字段权限，空配置返回nil
+func ParseFieldPermissions(data string) (FieldPermissions, error) {
+	if strings.TrimSpace(data) == "" || data == "null" {
+		return nil, nil
+	}
+	var permissions FieldPermissions
+	if err := json.Unmarshal([]byte(data), &permissions); err != nil {
+		return nil, fmt.Errorf("表单字段权限格式错误: %w", err)
+	}
+	for field, permission := range permissions {
+		switch permission {
+		case models.FieldPermissionEditable, models.FieldPermissionReadonly, models.FieldPermissionHidden:
+		default:
+			return nil, fmt.Errorf("字段 %s 的权限 %s 不受支持", field, permission)
+		}
+	}
+	return permissions, nil
+}
+
+// Permission 字段在审批节点上的权限，未配置的字段只读
+func (p FieldPermissions) Permission(field string) string {
+	if permission, ok := p[field]; ok {
+		return permission
+	}
+	return models.FieldPermissionReadonly
+}
+
+// applyFieldPermissions 按字段权限处理渲染数据：隐藏的字段从表单定义、表单值和字段状态中去掉，
+// 只读的字段设为禁用；permissions 为nil时不限制
+func applyFieldPermissions(renderData *FormRenderData, permissions FieldPermissions) {
+	if permissions == nil {
+		return
+	}
+
+	renderData.Permissions = make(map[string]string)
+	for i := range renderData.Form.Cards {
+		card := &renderData.Form.Cards[i]
+		attributes := make([]models.FormAttribute, 0, len(card.Attributes))
+		for _, attr := range card.Attributes {
+			key := attr.Attribute.FieldKey
+			permission := permissions.Permission(key)
+			if permission == models.FieldPermissionHidden {
+				delete(renderData.Values, key)
+				delete(renderData.Fields, key)
+				delete(renderData.Totals, key)
+				continue
+			}
+			if permission == models.FieldPermissionReadonly {
+				state := renderData.Fields[key]
+				state.Disable = true
+				renderData.Fields[key] = state
+			}
+			renderData.Permissions[key] = permission
+			attributes = append(attributes, attr)
+		}
+		card.Attributes = attributes
+	}
+}
+
+// nodeFieldPermissionsInTx 获取任务所在节点的表单字段权限；开始节点（退回发起人后重新提交）不限制，返回nil
+func (s *WorkflowService) nodeFieldPermissionsInTx(tx *gorm.DB, workflowID uint, nodeKey string) (FieldPermissions, error) {
+	var node models.WorkflowNode
+	if err := tx.Where("workflow_id = ? AND node_key = ?", workflowID, nodeKey).First(&node).Error; err != nil {
+		return nil, fmt.Errorf("节点不存在: %w", err)
+	}
+	if node.Type == models.NodeTypeRoot || node.Type == models.NodeTypeStart {
+		return nil, nil
+	}
+
+	permissions, err := ParseFieldPermissions(node.FieldPermissions)
+	if err != nil {
+		return nil, err
+	}
+	if permissions == nil {
+		permissions = FieldPermissions{}
+	}
+	return permissions, nil
+}
+
+// RenderTaskForm 按任务所在节点的字段权限渲染实例表单，仅任务处理人和候选人可查看
+func (s *WorkflowService) RenderTaskForm(taskID, userID uint) (*FormRenderData, error) {
+	var task models.WorkflowTask
+	if err := s.db.Preload("Instance.FormData").First(&task, taskID).Error; err != nil {
+		return nil, fmt.Errorf("任务不存在: %w", err)
+	}
+	if task.AssigneeID != userID {
+		var count int64
+		if err := s.db.Model(&models.WorkflowTaskCandidate{}).
+			Where("task_id = ? AND user_id = ?", task.ID, userID).Count(&count).Error; err != nil {
+			return nil, fmt.Errorf("查询任务候选人失败: %w", err)
+		}
+		if count == 0 {
+			return nil, errors.New("无权限查看此任务")
+		}
+	}
+	if task.Instance.FormData == nil {
+		return nil, errors.New("该实例没有关联的表单数据")
+	}
+
+	permissions, err := s.nodeFieldPermissionsInTx(s.db, task.Instance.WorkflowID, task.NodeKey)
+	if err != nil {
+		return nil, err
+	}
+	renderData, err := s.formService.RenderFormWithData(task.Instance.FormData.FormID, task.Instance.FormData.FormValues)
+	if err != nil {
+		return nil, err
+	}
+	renderData.Version = task.Instance.FormData.Version
+	applyFieldPermissions(renderData, permissions)
+	return renderData, nil
+}
+
+// RenderInstanceForm 按查看人的字段权限渲染实例表单
+func (s *WorkflowService) RenderInstanceForm(instanceID, userID uint) (*FormRenderData, error) {
+	var instance models.WorkflowInstance
+	if err := s.db.Preload("FormData").First(&instance, instanceID).Error; err != nil {
+		return nil, fmt.Errorf("实例不存在: %w", err)
+	}
+	if instance.FormData == nil {
+		return nil, errors.New("该实例没有关联的表单数据")
+	}
+
+	permissions, err := s.viewerFieldPermissions(&instance, userID)
+	if err != nil {
+		return nil, err
+	}
+	renderData, err := s.formService.RenderFormWithData(instance.FormData.FormID, instance.FormData.FormValues)
+	if err != nil {
+		return nil, err
+	}
+	renderData.Version = instance.FormData.Version
+	applyFieldPermissions(renderData, permissions)
+	return renderData, nil
+}
+
+// FilterInstanceForViewer 去掉实例详情中查看人不可见的表单字段（表单定义、表单数据和任务提交的表单值）
+func (s *WorkflowService) FilterInstanceForViewer(instance *models.WorkflowInstance, userID uint) error {
+	permissions, err := s.viewerFieldPermissions(instance, userID)
+	if err != nil || permissions == nil {
+		return err
+	}
+
+	if instance.FormData != nil {
+		instance.FormData.FormValues = stripHiddenFormValues(instance.FormData.FormValues, permissions)
+		for i := range instance.FormData.Form.Cards {
+			card := &instance.FormData.Form.Cards[i]
+			attributes := make([]models.FormAttribute, 0, len(card.Attributes))
+			for _, attr := range card.Attributes {
+				if permissions.Permission(attr.Attribute.FieldKey) != models.FieldPermissionHidden {
+					attributes = append(attributes, attr)
+				}
+			}
+			card.Attributes = attributes
+		}
+	}
+	for i := range instance.Tasks {
+		instance.Tasks[i].FormValues = stripHiddenFormValues(instance.Tasks[i].FormValues, permissions)
+	}
+	return nil
+}
+
+// FilterHistoryForViewer 去掉审批历史中查看人不可见的表单字段
+func (s *WorkflowService) FilterHistoryForViewer(instanceID, userID uint, history []models.WorkflowHistory) error {
+	var instance models.WorkflowInstance
+	if err := s.db.First(&instance, instanceID).Error; err != nil {
+		return fmt.Errorf("实例不存在: %w", err)
+	}
+	permissions, err := s.viewerFieldPermissions(&instance, userID)
+	if err != nil || permissions == nil {
+		return err
+	}
+	for i := range history {
+		history[i].FormValues = stripHiddenFormValues(history[i].FormValues, permissions)
+	}
+	return nil
+}
+
+// viewerFieldPermissions 查看人的字段权限：发起人和有系统管理权限的用户不限制，返回nil；
+// 审批人只读查看，在其参与的全部节点上都隐藏的字段不可见；其他查看人（如有实例查看权限的用户）
+// 在流程任一节点上隐藏的字段都不可见
+func (s *WorkflowService) viewerFieldPermissions(instance *models.WorkflowInstance, userID uint) (FieldPermissions, error) {
+	if instance.InitiatorID == userID {
+		return nil, nil
+	}
+	isAdmin, err := NewPermissionService().CheckPermission(userID, models.PermissionSystemAdmin)
+	if err != nil {
+		return nil, err
+	}
+	if isAdmin {
+		return nil, nil
+	}
+
+	var nodeKeys []string
+	if err := s.db.Model(&models.WorkflowTask{}).
+		Where("instance_id = ? AND (assignee_id = ? OR id IN (?))", instance.ID, userID,
+			s.db.Model(&models.WorkflowTaskCandidate{}).Select("task_id").Where("user_id = ?", userID)).
+		Distinct().Pluck("node_key", &nodeKeys).Error; err != nil {
+		return nil, fmt.Errorf("查询审批任务失败: %w", err)
+	}
+	if len(nodeKeys) == 0 {
+		return s.nonParticipantFieldPermissions(instance.WorkflowID)
+	}
+
+	var hidden map[string]bool
+	for _, nodeKey := range nodeKeys {
+		permissions, err := s.nodeFieldPermissionsInTx(s.db, instance.WorkflowID, nodeKey)
+		if err != nil {
+			return nil, err
+		}
+		nodeHidden := make(map[string]bool)
+		for field, permission := range permissions {
+			if permission == models.FieldPermissionHidden && (hidden == nil || hidden[field]) {
+				nodeHidden[field] = true
+			}
+		}
+		hidden = nodeHidden
+	}
+
+	viewPermissions := make(FieldPermissions)
+	for field := range hidden {
+		viewPermissions[field] = models.FieldPermissionHidden
+	}
+	return viewPermissions, nil
+}
+
+// nonParticipantFieldPermissions 未参与审批的查看人的字段权限：在流程任一节点上隐藏的字段都不可见
+func (s *WorkflowService) nonParticipantFieldPermissions(workflowID uint) (FieldPermissions, error) {
+	var nodes []models.WorkflowNode
+	if err := s.db.Where("workflow_id = ?", workflowID).Find(&nodes).Error; err != nil {
+		return nil, fmt.Errorf("获取工作流节点失败: %w", err)
+	}
+
+	viewPermissions := make(FieldPermissions)
+	for _, node := range nodes {
+		permissions, err := ParseFieldPermissions(node.FieldPermissions)
+		if err != nil {
+			return nil, err
+		}
+		for field, permission := range permissions {
+			if permission == models.FieldPermissionHidden {
+				viewPermissions[field] = models.FieldPermissionHidden
+			}
+		}
+	}
+	return viewPermissions, nil
+}
+
+// stripHiddenFormValues 去掉表单值JSON中隐藏的字段
+func stripHiddenFormValues(formValues string, permissions FieldPermissions) string {
+	if formValues == "" {
+		return formValues
+	}
+	var values map[string]interface{}
+	if err := json.Unmarshal([]byte(formValues), &values); err != nil {
+		return formValues
+	}
+	for field, permission := range permissions {
+		if permission == models.FieldPermissionHidden {
+			delete(values, field)
+		}
+	}
+	stripped, _ := json.Marshal(values)
+	return string(stripped)
+}
+
+// mergeApproverFormValuesInTx 按节点字段权限将审批人修改的表单值合并到实例表单数据：
+// 只能修改可编辑的字段，提交的只读、隐藏字段与原值相同时忽略，合并后按表单定义验证并记录字段变更；
+// formVersion 为审批人读取表单时的版本，必须提供，表单数据已被他人修改时返回 ErrFormDataConflict
+func (s *WorkflowService) mergeApproverFormValuesInTx(tx *gorm.DB, task *models.WorkflowTask, formValues string, formVersion int) error {
+	if task.Instance.FormDataID == nil {
+		return nil
+	}
+	permissions, err := s.nodeFieldPermissionsInTx(tx, task.Instance.WorkflowID, task.NodeKey)
+	if err != nil {
+		return err
+	}
+
+	var formData models.FormData
+	if err := tx.Preload("Form.Cards.Attributes.Attribute").First(&formData, *task.Instance.FormDataID).Error; err != nil {
+		return fmt.Errorf("表单数据不存在: %w", err)
+	}
+	if formVersion == 0 {
+		return ErrFormVersionRequired
+	}
+	if formVersion != formData.Version {
+		return ErrFormDataConflict
+	}
+	var submitted map[string]interface{}
+	if err := json.Unmarshal([]byte(formValues), &submitted); err != nil {
+		return fmt.Errorf("表单数据格式错误: %w", err)
+	}
+	values := make(map[string]interface{})
+	if formData.FormValues != "" {
+		if err := json.Unmarshal([]byte(formData.FormValues), &values); err != nil {
+			return fmt.Errorf("解析表单值失败: %w", err)
+		}
+	}
+
+	names := make(map[string]string)
+	for _, card := range formData.Form.Cards {
+		for _, attr := range card.Attributes {
+			names[attr.Attribute.FieldKey] = attr.Name
+		}
+	}
+
+	keys := make([]string, 0, len(submitted))
+	for key := range submitted {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	fieldErrors := make([]FieldError, 0)
+	changed := false
+	for _, key := range keys {
+		value := submitted[key]
+		if reflect.DeepEqual(values[key], value) {
+			continue
+		}
+		name, exists := names[key]
+		if !exists || permissions.Permission(key) != models.FieldPermissionEditable {
+			if name == "" {
+				name = key
+			}
+			fieldErrors = append(fieldErrors, FieldError{
+				Field: key, Name: name, Rule: FieldRulePermission,
+				Message: fmt.Sprintf("%s 在当前节点不可修改", name),
+			})
+			continue
+		}
+		values[key] = value
+		changed = true
+	}
+	if len(fieldErrors) > 0 {
+		return &FormValidationError{Errors: fieldErrors}
+	}
+	if !changed {
+		return nil
+	}
+
+	validationErrors, err := validateFormValues(newFieldOptionChecker(tx, false), &formData.Form, values, time.Now())
+	if err != nil {
+		return err
+	}
+	if len(validationErrors) > 0 {
+		return &FormValidationError{Errors: validationErrors}
+	}
+
+	merged, _ := json.Marshal(values)
+	source := formChangeSource{TaskID: &task.ID, NodeKey: task.NodeKey, UserID: task.AssigneeID}
+	return saveFormValuesInTx(tx, &formData, string(merged), source)
+}
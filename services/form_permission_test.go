@@ -0,0 +1,113 @@
+package services
+
+import (
+	"encoding/json"
+	"testing"
+
+	"gin-web-api/models"
+)
+
+// hiddenFieldTestTree 开始 -> 审批节点 a1（salary 隐藏）-> 结束
+const hiddenFieldTestTree = `{"key":"root","name":"发起","type":"ROOT",
+	"child":{"key":"a1","name":"审批","type":"approval",
+		"props":{"assignees":{"type":"users","user_ids":[2]},"field_permissions":{"amount":"editable","salary":"hidden"}},
+		"child":{"key":"end","name":"结束","type":"END"}}}`
+
+// startHiddenFieldTestInstance 发起带 amount、salary 两个字段的表单流程，返回实例
+func startHiddenFieldTestInstance(t *testing.T, s *WorkflowService) *models.WorkflowInstance {
+	t.Helper()
+
+	var attributes []CreateAttributeRequest
+	for _, key := range []string{"amount", "salary"} {
+		attributes = append(attributes, CreateAttributeRequest{
+			Attribute: CreateFieldAttrRequest{Object: "t", Name: key, Key: key, Type: "NUMERIC", Element: "number"},
+			Element:   "number", Name: key, Show: true,
+		})
+	}
+	form, err := NewFormService().CreateFormDefinition(&CreateFormRequest{Object: "t", Name: "调薪单", Key: "salary",
+		Cards: []CreateCardRequest{{Name: "基本信息", Attributes: attributes}}}, 1)
+	if err != nil {
+		t.Fatalf("创建表单失败: %v", err)
+	}
+	workflow := createTestWorkflow(t, s, hiddenFieldTestTree)
+	if err := s.db.Model(workflow).Update("form_id", form.ID).Error; err != nil {
+		t.Fatalf("关联表单失败: %v", err)
+	}
+
+	instance, err := s.StartWorkflowWithForm(&StartWorkflowWithFormRequest{
+		WorkflowID: workflow.ID, Title: "调薪申请", FormValues: `{"amount":1000,"salary":20000}`,
+	}, 1)
+	if err != nil {
+		t.Fatalf("发起流程失败: %v", err)
+	}
+	return instance
+}
+
+// formValueKeys 解析表单值JSON，返回其中的字段集合
+func formValueKeys(t *testing.T, formData *models.FormData) map[string]bool {
+	t.Helper()
+
+	if formData == nil {
+		t.Fatal("缺少表单数据")
+	}
+	var values map[string]interface{}
+	if err := json.Unmarshal([]byte(formData.FormValues), &values); err != nil {
+		t.Fatalf("表单数据格式错误: %v", err)
+	}
+	keys := make(map[string]bool)
+	for key := range values {
+		keys[key] = true
+	}
+	return keys
+}
+
+func TestListMyTasksHidesHiddenFields(t *testing.T) {
+	setupTestDB(t)
+	s := NewWorkflowService()
+	startHiddenFieldTestInstance(t, s)
+
+	tasks, err := s.ListMyTasks(2, false)
+	if err != nil {
+		t.Fatalf("ListMyTasks() error = %v", err)
+	}
+	if len(tasks) != 1 {
+		t.Fatalf("ListMyTasks() 返回 %d 个任务, want 1", len(tasks))
+	}
+	keys := formValueKeys(t, tasks[0].Instance.FormData)
+	if keys["salary"] || !keys["amount"] {
+		t.Errorf("审批人可见字段 = %v, want 只有 amount", keys)
+	}
+}
+
+func TestListInstancesHidesHiddenFields(t *testing.T) {
+	tests := []struct {
+		name       string
+		userID     uint
+		readAll    bool
+		wantSalary bool
+	}{
+		{name: "发起人", userID: 1, wantSalary: true},
+		{name: "审批人", userID: 2, wantSalary: false},
+		{name: "有查看权限的非参与人", userID: 3, readAll: true, wantSalary: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTestDB(t)
+			s := NewWorkflowService()
+			startHiddenFieldTestInstance(t, s)
+
+			instances, err := s.ListInstances(tt.userID, tt.readAll)
+			if err != nil {
+				t.Fatalf("ListInstances() error = %v", err)
+			}
+			if len(instances) != 1 {
+				t.Fatalf("ListInstances() 返回 %d 个实例, want 1", len(instances))
+			}
+			keys := formValueKeys(t, instances[0].FormData)
+			if keys["salary"] != tt.wantSalary || !keys["amount"] {
+				t.Errorf("可见字段 = %v, want salary = %v", keys, tt.wantSalary)
+			}
+		})
+	}
+}
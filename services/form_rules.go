@@ -0,0 +1,119 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gin-web-api/models"
+)
+
+// FieldRules 字段条件规则（FormAttribute.Rules），条件格式与分支条件一致，关键字为表单字段标识。
+// 配置了规则的状态以条件结果为准，未配置的沿用字段的 show/required/disable 设置
+type FieldRules struct {
+	Show     *ShowConditionRequest `json:"show,omitempty"`     // 满足条件时显示
+	Required *ShowConditionRequest `json:"required,omitempty"` // 满足条件时必填
+	Disable  *ShowConditionRequest `json:"disable,omitempty"`  // 满足条件时禁用
+}
+
+// FieldState 字段的生效状态，隐藏的字段不必填
+type FieldState struct {
+	Show     bool `json:"show"`
+	Required bool `json:"required"`
+	Disable  bool `json:"disable"`
+}
+
+// ParseFieldRules 解析字段条件规则，空配置返回nil
+func ParseFieldRules(data string) (*FieldRules, error) {
+	if strings.TrimSpace(data) == "" || data == "null" {
+		return nil, nil
+	}
+	var rules FieldRules
+	if err := json.Unmarshal([]byte(data), &rules); err != nil {
+		return nil, fmt.Errorf("条件规则格式错误: %w", err)
+	}
+	return &rules, nil
+}
+
+// conditions 规则中配置的全部条件
+func (r *FieldRules) conditions() []*ShowConditionRequest {
+	return []*ShowConditionRequest{r.Show, r.Required, r.Disable}
+}
+
+// ruleFieldKey 条件关键字引用的表单字段，支持 form. 前缀、点号路径和聚合函数
+func ruleFieldKey(keyword string) string {
+	keyword = strings.TrimSpace(keyword)
+	if _, inner, ok := splitAggregateKeyword(keyword); ok {
+		keyword = inner
+	}
+	if scope, path := splitConditionKeyword(keyword); scope == ConditionScopeForm {
+		keyword = path
+	}
+	return strings.SplitN(keyword, ".", 2)[0]
+}
+
+// validateFormRules 检查表单定义中的条件规则只引用本表单的字段
+func validateFormRules(req *CreateFormRequest) error {
+	fields := make(map[string]bool)
+	for _, card := range req.Cards {
+		for _, attr := range card.Attributes {
+			fields[attr.Attribute.Key] = true
+		}
+	}
+
+	for _, card := range req.Cards {
+		for _, attr := range card.Attributes {
+			if attr.Rules == nil {
+				continue
+			}
+			for _, condition := range attr.Rules.conditions() {
+				if condition == nil {
+					continue
+				}
+				for _, group := range condition.Groups {
+					for _, cond := range group.Conditions {
+						if field := ruleFieldKey(cond.Keyword); !fields[field] {
+							return fmt.Errorf("字段 %s 的条件规则引用的字段 %s 不在表单中", attr.Name, field)
+						}
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// evaluateFieldStates 按表单值计算每个字段的生效状态，以字段标识为键
+func evaluateFieldStates(form *models.FormDefinition, values map[string]interface{}) (map[string]FieldState, error) {
+	ctx := &ConditionContext{FormValues: values}
+	if ctx.FormValues == nil {
+		ctx.FormValues = make(map[string]interface{})
+	}
+
+	states := make(map[string]FieldState)
+	for _, card := range form.Cards {
+		for _, attr := range card.Attributes {
+			state := FieldState{Show: attr.Show, Required: attr.Required, Disable: attr.Disable}
+			rules, err := ParseFieldRules(attr.Rules)
+			if err != nil {
+				return nil, fmt.Errorf("字段 %s 的%w", attr.Name, err)
+			}
+			if rules != nil {
+				if rules.Show != nil {
+					state.Show = EvaluateCondition(rules.Show, ctx)
+				}
+				if rules.Required != nil {
+					state.Required = EvaluateCondition(rules.Required, ctx)
+				}
+				if rules.Disable != nil {
+					state.Disable = EvaluateCondition(rules.Disable, ctx)
+				}
+			}
+			if !state.Show {
+				state.Required = false
+			}
+			states[attr.Attribute.FieldKey] = state
+		}
+	}
+	return states, nil
+}
@@ -24,6 +24,10 @@ func NewFormService() *FormService {
 
 // CreateFormDefinition 创建表单定义
 func (s *FormService) CreateFormDefinition(req *CreateFormRequest, creatorID uint) (*models.FormDefinition, error) {
+	if err := validateFormRules(req); err != nil {
+		return nil, err
+	}
+
 	return s.db.Transaction(func(tx *gorm.DB) (*models.FormDefinition, error) {
 		// 创建表单定义
 		form := &models.FormDefinition{
@@ -104,6 +108,27 @@ func (s *FormService) CreateFormDefinition(req *CreateFormRequest, creatorID uin
 				if attrReq.Validation != nil {
 					validationJson, _ := json.Marshal(attrReq.Validation)
 					formAttr.Validation = string(validationJson)
+					if _, err := ParseFieldValidation(formAttr.Validation); err != nil {
+						return nil, fmt.Errorf("字段 %s 的%w", attrReq.Name, err)
+					}
+				}
+				if attrReq.Rules != nil {
+					rulesJson, _ := json.Marshal(attrReq.Rules)
+					formAttr.Rules = string(rulesJson)
+				}
+				if attrReq.DataSource != nil {
+					if err := attrReq.DataSource.validate(); err != nil {
+						return nil, fmt.Errorf("字段 %s 的%w", attrReq.Name, err)
+					}
+					dataSourceJson, _ := json.Marshal(attrReq.DataSource)
+					formAttr.DataSource = string(dataSourceJson)
+				}
+				if attrReq.Element == models.ElementTypeTable {
+					if err := validateTableColumns(attrReq.Columns); err != nil {
+						return nil, fmt.Errorf("字段 %s 的%w", attrReq.Name, err)
+					}
+					columnsJson, _ := json.Marshal(attrReq.Columns)
+					formAttr.Columns = string(columnsJson)
 				}
 
 				formAttr.LocationX = attrIndex + 1
@@ -194,14 +219,20 @@ func (s *FormService) ListFormDefinitions(page, pageSize int) ([]models.FormDefi
 
 // CreateFormData 创建表单数据
 func (s *FormService) CreateFormData(req *CreateFormDataRequest, userID uint) (*models.FormData, error) {
+	return s.createFormDataInTx(s.db, req, userID)
+}
+
+// createFormDataInTx 在事务中创建表单数据（如发起流程时与实例一同创建）
+func (s *FormService) createFormDataInTx(tx *gorm.DB, req *CreateFormDataRequest, userID uint) (*models.FormData, error) {
 	// 验证表单定义是否存在
 	var form models.FormDefinition
-	if err := s.db.First(&form, req.FormID).Error; err != nil {
+	if err := tx.First(&form, req.FormID).Error; err != nil {
 		return nil, fmt.Errorf("表单定义不存在: %w", err)
 	}
 
 	// 验证表单数据
-	if err := s.validateFormData(req.FormID, req.FormValues); err != nil {
+	formValues, err := s.validateFormData(newFieldOptionChecker(tx, false), req.FormID, req.FormValues)
+	if err != nil {
 		return nil, fmt.Errorf("表单数据验证失败: %w", err)
 	}
 
@@ -209,14 +240,18 @@ func (s *FormService) CreateFormData(req *CreateFormDataRequest, userID uint) (*
 		FormID:      req.FormID,
 		InstanceID:  req.InstanceID,
 		BusinessKey: req.BusinessKey,
-		FormValues:  req.FormValues,
+		FormValues:  formValues,
+		Version:     1,
 		Status:      models.FormStatusDraft,
 		SubmittedBy: userID,
 	}
 
-	if err := s.db.Create(formData).Error; err != nil {
+	if err := tx.Create(formData).Error; err != nil {
 		return nil, fmt.Errorf("创建表单数据失败: %w", err)
 	}
+	if err := attachFormFilesInTx(tx, formData, userID); err != nil {
+		return nil, err
+	}
 
 	return formData, nil
 }
@@ -240,10 +275,11 @@ func (s *FormService) UpdateFormData(formDataID uint, req *UpdateFormDataRequest
 
 	// 验证表单数据
 	if req.FormValues != "" {
-		if err := s.validateFormData(formData.FormID, req.FormValues); err != nil {
+		formValues, err := s.validateFormData(newFieldOptionChecker(s.db, true), formData.FormID, req.FormValues)
+		if err != nil {
 			return nil, fmt.Errorf("表单数据验证失败: %w", err)
 		}
-		formData.FormValues = req.FormValues
+		formData.FormValues = formValues
 	}
 
 	if req.Status != "" {
@@ -254,8 +290,14 @@ func (s *FormService) UpdateFormData(formDataID uint, req *UpdateFormDataRequest
 		}
 	}
 
-	if err := s.db.Save(&formData).Error; err != nil {
-		return nil, fmt.Errorf("更新表单数据失败: %w", err)
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&formData).Error; err != nil {
+			return fmt.Errorf("更新表单数据失败: %w", err)
+		}
+		return attachFormFilesInTx(tx, &formData, userID)
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return &formData, nil
@@ -293,45 +335,56 @@ func (s *FormService) RenderFormWithData(formID uint, formValues string) (*FormR
 		}
 	}
 
+	// 计算明细表的公式列，按条件规则计算字段的显示、必填和禁用状态
+	if err := computeTableValues(form, values); err != nil {
+		return nil, err
+	}
+	fields, err := evaluateFieldStates(form, values)
+	if err != nil {
+		return nil, err
+	}
+	totals, err := computeTableTotals(form, values)
+	if err != nil {
+		return nil, err
+	}
+
 	// 构建渲染数据
 	renderData := &FormRenderData{
 		Form:   *form,
 		Values: values,
+		Fields: fields,
+		Totals: totals,
 	}
 
 	return renderData, nil
 }
 
-// validateFormData 验证表单数据
-func (s *FormService) validateFormData(formID uint, formValues string) error {
-	// 获取表单定义
-	form, err := s.GetFormDefinition(formID)
-	if err != nil {
-		return err
+// validateFormData 验证表单数据，字段未通过验证时返回 *FormValidationError；
+// 表单有明细表时返回计算公式列后的表单值，否则原样返回。options 决定验证使用的数据库连接（事务）和是否请求远程选项
+func (s *FormService) validateFormData(options *fieldOptionChecker, formID uint, formValues string) (string, error) {
+	var form models.FormDefinition
+	if err := options.db.Preload("Cards.Attributes.Attribute").First(&form, formID).Error; err != nil {
+		return "", fmt.Errorf("获取表单定义失败: %w", err)
 	}
 
-	// 解析表单值
 	var values map[string]interface{}
 	if err := json.Unmarshal([]byte(formValues), &values); err != nil {
-		return fmt.Errorf("表单数据格式错误: %w", err)
+		return "", fmt.Errorf("表单数据格式错误: %w", err)
 	}
-
-	// 验证必填字段
-	for _, card := range form.Cards {
-		for _, attr := range card.Attributes {
-			if attr.Required {
-				fieldKey := attr.Attribute.FieldKey
-				if _, exists := values[fieldKey]; !exists {
-					return fmt.Errorf("必填字段 %s 不能为空", attr.Name)
-				}
-			}
-
-			// 可以在这里添加更多的验证逻辑
-			// 比如数据类型验证、格式验证等
-		}
+	fieldErrors, err := validateFormValues(options, &form, values, time.Now())
+	if err != nil {
+		return "", err
+	}
+	if len(fieldErrors) > 0 {
+		return "", &FormValidationError{Errors: fieldErrors}
 	}
 
-	return nil
+	tables, err := formTableFields(&form)
+	if err != nil || len(tables) == 0 {
+		return formValues, err
+	}
+	computed, _ := json.Marshal(values)
+	return string(computed), nil
 }
 
 // 请求结构体
@@ -363,6 +416,9 @@ type CreateAttributeRequest struct {
 	DefaultValue string                `json:"default_value"`
 	Options      interface{}           `json:"options"`
 	Validation   interface{}           `json:"validation"`
+	Rules        *FieldRules           `json:"rules"`
+	DataSource   *OptionSource         `json:"data_source"`
+	Columns      []TableColumn         `json:"columns"`
 }
 
 type CreateFieldAttrRequest struct {
@@ -423,12 +479,20 @@ type UpdateFormDataRequest struct {
 }
 
 type FormRenderData struct {
-	Form   models.FormDefinition  `json:"form"`
-	Values map[string]interface{} `json:"values"`
+	Form        models.FormDefinition         `json:"form"`
+	Values      map[string]interface{}        `json:"values"`
+	Fields      map[string]FieldState         `json:"fields"`                // 字段生效状态，以字段标识为键
+	Permissions map[string]string             `json:"permissions,omitempty"` // 审批节点或查看人的字段权限，不限制时为空
+	Version     int                           `json:"version,omitempty"`     // 实例表单数据的版本，审批时随修改提交
+	Totals      map[string]map[string]float64 `json:"totals,omitempty"`      // 明细表汇总列的合计，以字段标识和列标识为键
 }
 
 // UpdateFormDefinition 更新表单定义
 func (s *FormService) UpdateFormDefinition(formID uint, req *CreateFormRequest, userID uint) (*models.FormDefinition, error) {
+	if err := validateFormRules(req); err != nil {
+		return nil, err
+	}
+
 	return s.db.Transaction(func(tx *gorm.DB) (*models.FormDefinition, error) {
 		// 获取现有表单定义
 		var form models.FormDefinition
@@ -517,6 +581,27 @@ func (s *FormService) UpdateFormDefinition(formID uint, req *CreateFormRequest,
 				if attrReq.Validation != nil {
 					validationJson, _ := json.Marshal(attrReq.Validation)
 					formAttr.Validation = string(validationJson)
+					if _, err := ParseFieldValidation(formAttr.Validation); err != nil {
+						return nil, fmt.Errorf("字段 %s 的%w", attrReq.Name, err)
+					}
+				}
+				if attrReq.Rules != nil {
+					rulesJson, _ := json.Marshal(attrReq.Rules)
+					formAttr.Rules = string(rulesJson)
+				}
+				if attrReq.DataSource != nil {
+					if err := attrReq.DataSource.validate(); err != nil {
+						return nil, fmt.Errorf("字段 %s 的%w", attrReq.Name, err)
+					}
+					dataSourceJson, _ := json.Marshal(attrReq.DataSource)
+					formAttr.DataSource = string(dataSourceJson)
+				}
+				if attrReq.Element == models.ElementTypeTable {
+					if err := validateTableColumns(attrReq.Columns); err != nil {
+						return nil, fmt.Errorf("字段 %s 的%w", attrReq.Name, err)
+					}
+					columnsJson, _ := json.Marshal(attrReq.Columns)
+					formAttr.Columns = string(columnsJson)
 				}
 
 				if err := tx.Create(formAttr).Error; err != nil {
@@ -651,6 +736,15 @@ func (s *FormService) ExportFormDefinition(formID uint) (*CreateFormRequest, err
 			if attr.Validation != "" {
 				json.Unmarshal([]byte(attr.Validation), &attrReq.Validation)
 			}
+			if attr.Rules != "" {
+				attrReq.Rules, _ = ParseFieldRules(attr.Rules)
+			}
+			if attr.DataSource != "" {
+				attrReq.DataSource, _ = ParseOptionSource(attr.DataSource)
+			}
+			if attr.Columns != "" {
+				attrReq.Columns, _ = ParseTableColumns(attr.Columns)
+			}
 
 			cardReq.Attributes = append(cardReq.Attributes, attrReq)
 		}
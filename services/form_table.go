@@ -0,0 +1,469 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"gin-web-api/models"
+)
+
+// TableColumn 明细表的列（FormAttribute.Columns），每行是以列标识为键的对象。
+// 配置了公式的列由服务端按同一行的其他列计算，提交的值会被覆盖
+type TableColumn struct {
+	Key          string        `json:"key"`                     // 列标识
+	Name         string        `json:"name"`                    // 列名称
+	Type         string        `json:"type,omitempty"`          // 数据类型，取值同字段属性
+	Element      string        `json:"element"`                 // 表单元素类型
+	Width        string        `json:"width,omitempty"`         // 宽度
+	Required     bool          `json:"required,omitempty"`      // 是否必填
+	Placeholder  string        `json:"placeholder,omitempty"`   // 占位符
+	DefaultValue string        `json:"default_value,omitempty"` // 新增行的默认值
+	Options      interface{}   `json:"options,omitempty"`       // 选项配置
+	Validation   interface{}   `json:"validation,omitempty"`    // 验证规则，格式同字段验证规则
+	DataSource   *OptionSource `json:"data_source,omitempty"`   // 选项数据源
+	Formula      string        `json:"formula,omitempty"`       // 行计算公式，如 price * quantity
+	Summary      bool          `json:"summary,omitempty"`       // 是否计算列合计
+}
+
+// tableColumnElements 明细表的列支持的表单元素，不支持文件和嵌套明细表
+var tableColumnElements = map[string]bool{
+	models.ElementTypeInput:      true,
+	models.ElementTypeNumber:     true,
+	models.ElementTypeSelect:     true,
+	models.ElementTypeCheckbox:   true,
+	models.ElementTypeRadio:      true,
+	models.ElementTypeTextarea:   true,
+	models.ElementTypeDate:       true,
+	models.ElementTypeDatetime:   true,
+	models.ElementTypeCascader:   true,
+	models.ElementTypeTreeSelect: true,
+}
+
+// tableField 表单中的明细表字段
+type tableField struct {
+	attr     *models.FormAttribute
+	columns  []TableColumn
+	formulas map[string]*tableFormula // 列标识 -> 公式
+}
+
+// ParseTableColumns 解析明细表的列定义，空配置返回nil
+func ParseTableColumns(data string) ([]TableColumn, error) {
+	if strings.TrimSpace(data) == "" || data == "null" {
+		return nil, nil
+	}
+	var columns []TableColumn
+	if err := json.Unmarshal([]byte(data), &columns); err != nil {
+		return nil, fmt.Errorf("明细表列定义格式错误: %w", err)
+	}
+	return columns, nil
+}
+
+// validateTableColumns 检查明细表的列定义：列标识唯一、元素类型支持、验证规则和数据源有效，
+// 公式只能引用本表中在它之前的列或非公式列
+func validateTableColumns(columns []TableColumn) error {
+	if len(columns) == 0 {
+		return errors.New("明细表至少需要一列")
+	}
+	_, err := newTableField(&models.FormAttribute{Element: models.ElementTypeTable}, columns)
+	return err
+}
+
+// newTableField 解析明细表字段的列和公式
+func newTableField(attr *models.FormAttribute, columns []TableColumn) (*tableField, error) {
+	table := &tableField{attr: attr, columns: columns, formulas: make(map[string]*tableFormula)}
+	positions := make(map[string]int, len(columns))
+	for i := range columns {
+		column := &columns[i]
+		if column.Key == "" || column.Name == "" {
+			return nil, fmt.Errorf("明细表第 %d 列缺少标识或名称", i+1)
+		}
+		if _, exists := positions[column.Key]; exists {
+			return nil, fmt.Errorf("明细表的列标识 %s 重复", column.Key)
+		}
+		positions[column.Key] = i
+		if !tableColumnElements[column.Element] {
+			return nil, fmt.Errorf("明细表的列 %s 不支持 %s 类型", column.Name, column.Element)
+		}
+		if column.Validation != nil {
+			validationJson, _ := json.Marshal(column.Validation)
+			if _, err := ParseFieldValidation(string(validationJson)); err != nil {
+				return nil, fmt.Errorf("明细表的列 %s 的%w", column.Name, err)
+			}
+		}
+		if column.DataSource != nil {
+			if err := column.DataSource.validate(); err != nil {
+				return nil, fmt.Errorf("明细表的列 %s 的%w", column.Name, err)
+			}
+		}
+	}
+
+	for i, column := range columns {
+		if column.Formula == "" {
+			continue
+		}
+		formula, err := parseTableFormula(column.Formula)
+		if err != nil {
+			return nil, fmt.Errorf("明细表的列 %s 的公式%w", column.Name, err)
+		}
+		for _, ref := range formula.refs() {
+			position, exists := positions[ref]
+			if !exists {
+				return nil, fmt.Errorf("明细表的列 %s 的公式引用的列 %s 不存在", column.Name, ref)
+			}
+			if columns[position].Formula != "" && position >= i {
+				return nil, fmt.Errorf("明细表的列 %s 的公式只能引用在它之前的公式列", column.Name)
+			}
+		}
+		table.formulas[column.Key] = formula
+	}
+	return table, nil
+}
+
+// formTableFields 表单中的明细表字段
+func formTableFields(form *models.FormDefinition) ([]*tableField, error) {
+	tables := make([]*tableField, 0)
+	for i := range form.Cards {
+		for j := range form.Cards[i].Attributes {
+			attr := &form.Cards[i].Attributes[j]
+			if attr.Element != models.ElementTypeTable {
+				continue
+			}
+			columns, err := ParseTableColumns(attr.Columns)
+			if err != nil {
+				return nil, fmt.Errorf("字段 %s 的%w", attr.Name, err)
+			}
+			table, err := newTableField(attr, columns)
+			if err != nil {
+				return nil, fmt.Errorf("字段 %s 的%w", attr.Name, err)
+			}
+			tables = append(tables, table)
+		}
+	}
+	return tables, nil
+}
+
+// tableRows 将明细表的值转换为行列表，值不是对象列表时返回false
+func tableRows(value interface{}) ([]map[string]interface{}, bool) {
+	items, ok := toList(value)
+	if !ok {
+		return nil, false
+	}
+	rows := make([]map[string]interface{}, 0, len(items))
+	for _, item := range items {
+		row, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		rows = append(rows, row)
+	}
+	return rows, true
+}
+
+// computeTableValues 按公式计算表单中各明细表每一行的公式列，直接修改表单值；
+// 引用的列为空时按0计算，不是数字或除数为0时公式列为空
+func computeTableValues(form *models.FormDefinition, values map[string]interface{}) error {
+	tables, err := formTableFields(form)
+	if err != nil {
+		return err
+	}
+	for _, table := range tables {
+		if len(table.formulas) == 0 {
+			continue
+		}
+		rows, ok := tableRows(values[table.attr.Attribute.FieldKey])
+		if !ok {
+			continue
+		}
+		for _, row := range rows {
+			for _, column := range table.columns {
+				formula := table.formulas[column.Key]
+				if formula == nil {
+					continue
+				}
+				if result, ok := formula.eval(row); ok {
+					row[column.Key] = result
+				} else {
+					row[column.Key] = nil
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// computeTableTotals 计算各明细表汇总列的合计，以字段标识和列标识为键，没有汇总列时返回nil
+func computeTableTotals(form *models.FormDefinition, values map[string]interface{}) (map[string]map[string]float64, error) {
+	tables, err := formTableFields(form)
+	if err != nil {
+		return nil, err
+	}
+	var totals map[string]map[string]float64
+	for _, table := range tables {
+		rows, _ := tableRows(values[table.attr.Attribute.FieldKey])
+		for _, column := range table.columns {
+			if !column.Summary {
+				continue
+			}
+			if totals == nil {
+				totals = make(map[string]map[string]float64)
+			}
+			key := table.attr.Attribute.FieldKey
+			if totals[key] == nil {
+				totals[key] = make(map[string]float64)
+			}
+			cells := make([]interface{}, 0, len(rows))
+			for _, row := range rows {
+				cells = append(cells, row[column.Key])
+			}
+			total, _ := aggregateValues(ConditionFuncSum, cells)
+			totals[key][column.Key], _ = total.(float64)
+		}
+	}
+	return totals, nil
+}
+
+// validateTableRows 逐行验证明细表的每一列，返回全部未通过的单元格，字段标识为 明细表标识.行号.列标识（行号从0开始）
+func (t *tableField) validateTableRows(options *fieldOptionChecker, rows []map[string]interface{}, now time.Time) ([]FieldError, error) {
+	names := make(map[string]string, len(t.columns))
+	for _, column := range t.columns {
+		names[column.Key] = column.Name
+	}
+
+	fieldErrors := make([]FieldError, 0)
+	for i, row := range rows {
+		for _, column := range t.columns {
+			attr := t.columnAttribute(&column, i)
+			validation, err := ParseFieldValidation(attr.Validation)
+			if err != nil {
+				return nil, fmt.Errorf("%s的%w", attr.Name, err)
+			}
+			fieldErr := validateFieldValue(attr, column.Required, validation, row, names, now)
+			if fieldErr == nil && !isEmptyValue(row[column.Key]) {
+				if fieldErr, err = options.check(t.attr.Attribute.FieldKey+"."+column.Key, attr, row[column.Key]); err != nil {
+					return nil, err
+				}
+			}
+			if fieldErr != nil {
+				fieldErr.Field = fmt.Sprintf("%s.%d.%s", t.attr.Attribute.FieldKey, i, column.Key)
+				fieldErrors = append(fieldErrors, *fieldErr)
+			}
+		}
+	}
+	return fieldErrors, nil
+}
+
+// columnAttribute 将明细表的列转换为字段，名称带明细表名称和行号，便于复用字段验证
+func (t *tableField) columnAttribute(column *TableColumn, row int) *models.FormAttribute {
+	attr := &models.FormAttribute{
+		Attribute: models.FieldAttribute{FieldKey: column.Key, DataType: column.Type, Element: column.Element},
+		Element:   column.Element,
+		Name:      fmt.Sprintf("%s第%d行%s", t.attr.Name, row+1, column.Name),
+		Required:  column.Required,
+	}
+	if column.Options != nil {
+		optionsJson, _ := json.Marshal(column.Options)
+		attr.Options = string(optionsJson)
+	}
+	if column.Validation != nil {
+		validationJson, _ := json.Marshal(column.Validation)
+		attr.Validation = string(validationJson)
+	}
+	if column.DataSource != nil {
+		dataSourceJson, _ := json.Marshal(column.DataSource)
+		attr.DataSource = string(dataSourceJson)
+	}
+	return attr
+}
+
+// tableFormula 明细表的行计算公式，支持数字、列标识、四则运算和括号
+type tableFormula struct {
+	op          byte // 0 数字，'c' 列，'n' 取负，其余为运算符 + - * /
+	value       float64
+	column      string
+	left, right *tableFormula
+}
+
+// parseTableFormula 解析行计算公式
+func parseTableFormula(expr string) (*tableFormula, error) {
+	parser := &formulaParser{tokens: tokenizeFormula(expr)}
+	formula, err := parser.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if parser.pos < len(parser.tokens) {
+		return nil, fmt.Errorf(" %s 无效：多余的 %s", expr, parser.tokens[parser.pos])
+	}
+	return formula, nil
+}
+
+// refs 公式引用的列
+func (f *tableFormula) refs() []string {
+	switch {
+	case f == nil:
+		return nil
+	case f.op == 'c':
+		return []string{f.column}
+	}
+	return append(f.left.refs(), f.right.refs()...)
+}
+
+// eval 按行的值计算公式
+func (f *tableFormula) eval(row map[string]interface{}) (float64, bool) {
+	switch f.op {
+	case 0:
+		return f.value, true
+	case 'c':
+		value := row[f.column]
+		if isEmptyValue(value) {
+			return 0, true
+		}
+		return toFloat(value)
+	case 'n':
+		value, ok := f.left.eval(row)
+		return -value, ok
+	}
+
+	left, ok := f.left.eval(row)
+	if !ok {
+		return 0, false
+	}
+	right, ok := f.right.eval(row)
+	if !ok {
+		return 0, false
+	}
+	var result float64
+	switch f.op {
+	case '+':
+		result = left + right
+	case '-':
+		result = left - right
+	case '*':
+		result = left * right
+	case '/':
+		if right == 0 {
+			return 0, false
+		}
+		result = left / right
+	}
+	// 去掉浮点运算的误差，如 0.1 * 3 的结果为 0.3
+	result, _ = strconv.ParseFloat(strconv.FormatFloat(result, 'g', 12, 64), 64)
+	return result, true
+}
+
+// tokenizeFormula 拆分公式：数字、标识符和单个字符的运算符
+func tokenizeFormula(expr string) []string {
+	tokens := make([]string, 0)
+	runes := []rune(expr)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsDigit(r) || r == '.':
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, string(runes[start:i]))
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, string(runes[start:i]))
+		default:
+			tokens = append(tokens, string(r))
+			i++
+		}
+	}
+	return tokens
+}
+
+// formulaParser 行计算公式的递归下降解析
+type formulaParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *formulaParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+// parseExpr 加减
+func (p *formulaParser) parseExpr() (*tableFormula, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "+" || p.peek() == "-" {
+		op := p.tokens[p.pos][0]
+		p.pos++
+		right, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		left = &tableFormula{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+// parseTerm 乘除
+func (p *formulaParser) parseTerm() (*tableFormula, error) {
+	left, err := p.parseFactor()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "*" || p.peek() == "/" {
+		op := p.tokens[p.pos][0]
+		p.pos++
+		right, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		left = &tableFormula{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+// parseFactor 数字、列、括号和负号
+func (p *formulaParser) parseFactor() (*tableFormula, error) {
+	token := p.peek()
+	p.pos++
+	switch {
+	case token == "":
+		return nil, errors.New("不完整")
+	case token == "-":
+		operand, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		return &tableFormula{op: 'n', left: operand}, nil
+	case token == "(":
+		inner, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, errors.New("缺少右括号")
+		}
+		p.pos++
+		return inner, nil
+	case unicode.IsDigit([]rune(token)[0]) || token[0] == '.':
+		value, err := strconv.ParseFloat(token, 64)
+		if err != nil {
+			return nil, fmt.Errorf("中的数字 %s 无效", token)
+		}
+		return &tableFormula{value: value}, nil
+	case unicode.IsLetter([]rune(token)[0]) || token[0] == '_':
+		return &tableFormula{op: 'c', column: token}, nil
+	}
+	return nil, fmt.Errorf("中的 %s 无效", token)
+}
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTableFormula(t *testing.T) {
+	row := map[string]interface{}{"price": "12.5", "qty": float64(4), "discount": 0.1, "memo": "赠品", "empty": ""}
+
+	tests := []struct {
+		expr     string
+		wantRefs []string
+		want     float64
+		wantOK   bool
+	}{
+		{"price * qty", []string{"price", "qty"}, 50, true},
+		{"price*qty*(1-discount)", []string{"price", "qty", "discount"}, 45, true},
+		{"1 + 2 * 3", nil, 7, true},
+		{"(1 + 2) * 3", nil, 9, true},
+		{"10 - 4 - 3", nil, 3, true},
+		{"12 / 4 / 3", nil, 1, true},
+		{"-qty + 10", []string{"qty"}, 6, true},
+		{"--qty", []string{"qty"}, 4, true},
+		{"0.1 * 3", nil, 0.3, true},
+		{".5 * qty", []string{"qty"}, 2, true},
+		{"empty + missing + 1", []string{"empty", "missing"}, 1, true},
+		{"qty / (price - 12.5)", []string{"qty", "price"}, 0, false},
+		{"memo * 2", []string{"memo"}, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			formula, err := parseTableFormula(tt.expr)
+			if err != nil {
+				t.Fatalf("parseTableFormula(%q) error = %v", tt.expr, err)
+			}
+			if refs := formula.refs(); !reflect.DeepEqual(refs, tt.wantRefs) {
+				t.Errorf("refs() = %v, want %v", refs, tt.wantRefs)
+			}
+			got, ok := formula.eval(row)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("eval() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestParseTableFormulaInvalid(t *testing.T) {
+	for _, expr := range []string{"", "price *", "(price + 1", "price qty", "price + 1)", "1.2.3 * qty", "price % 2", "price * #"} {
+		if _, err := parseTableFormula(expr); err == nil {
+			t.Errorf("parseTableFormula(%q) 应返回错误", expr)
+		}
+	}
+}
@@ -0,0 +1,427 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gin-web-api/models"
+)
+
+// 字段验证规则类型，用于标识未通过的规则
+const (
+	FieldRuleRequired   = "required"   // 必填
+	FieldRuleType       = "type"       // 数据类型
+	FieldRuleMin        = "min"        // 最小值
+	FieldRuleMax        = "max"        // 最大值
+	FieldRuleMinLength  = "min_length" // 最小长度
+	FieldRuleMaxLength  = "max_length" // 最大长度
+	FieldRulePattern    = "pattern"    // 正则表达式
+	FieldRuleFormat     = "format"     // 内置格式
+	FieldRuleOption     = "option"     // 可选值
+	FieldRuleMinDate    = "min_date"   // 最早日期
+	FieldRuleMaxDate    = "max_date"   // 最晚日期
+	FieldRuleCompare    = "compare"    // 跨字段比较
+	FieldRulePermission = "permission" // 字段权限
+	FieldRuleFile       = "file"       // 文件引用
+	FieldRuleMinRows    = "min_rows"   // 明细表最少行数
+	FieldRuleMaxRows    = "max_rows"   // 明细表最多行数
+)
+
+// 内置格式
+const (
+	FieldFormatEmail = "email" // 邮箱
+	FieldFormatPhone = "phone" // 手机号或固定电话
+)
+
+// fieldFormatPatterns 内置格式的正则表达式
+var fieldFormatPatterns = map[string]*regexp.Regexp{
+	FieldFormatEmail: regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`),
+	FieldFormatPhone: regexp.MustCompile(`^((\+?86)?1[3-9]\d{9}|0\d{2,3}-?\d{7,8})$`),
+}
+
+// dateBoundToday 日期范围中表示当天的关键字
+const dateBoundToday = "today"
+
+// compareOpNames 跨字段比较运算符的中文描述
+var compareOpNames = map[string]string{
+	ConditionOpEq:  "等于",
+	ConditionOpNe:  "不等于",
+	ConditionOpGt:  "大于",
+	ConditionOpGte: "大于等于",
+	ConditionOpLt:  "小于",
+	ConditionOpLte: "小于等于",
+}
+
+// FieldValidation 字段验证规则（FormAttribute.Validation），未设置的规则不检查
+type FieldValidation struct {
+	Min         *float64           `json:"min,omitempty"`           // 最小值
+	Max         *float64           `json:"max,omitempty"`           // 最大值
+	MinLength   *int               `json:"min_length,omitempty"`    // 最小长度：文本为字符数，多选为选项数，文件为文件数
+	MaxLength   *int               `json:"max_length,omitempty"`    // 最大长度
+	Pattern     string             `json:"pattern,omitempty"`       // 正则表达式
+	Format      string             `json:"format,omitempty"`        // 内置格式 email/phone
+	MinDate     string             `json:"min_date,omitempty"`      // 最早日期，today 表示当天
+	MaxDate     string             `json:"max_date,omitempty"`      // 最晚日期，today 表示当天
+	Compare     []FieldCompareRule `json:"compare,omitempty"`       // 与其他字段比较，如结束日期晚于开始日期
+	MaxFileSize int64              `json:"max_file_size,omitempty"` // 文件字段单个文件的大小上限（字节）
+	Accept      []string           `json:"accept,omitempty"`        // 文件字段允许的MIME类型，支持 image/* 形式的通配
+	MinRows     *int               `json:"min_rows,omitempty"`      // 明细表最少行数
+	MaxRows     *int               `json:"max_rows,omitempty"`      // 明细表最多行数
+	Message     string             `json:"message,omitempty"`       // 自定义错误信息，替代除必填和类型外的默认信息
+
+	pattern *regexp.Regexp
+}
+
+// FieldCompareRule 跨字段比较规则，数字按数值比较，日期按时间比较
+type FieldCompareRule struct {
+	Operator string `json:"operator"`          // 运算符 gt/gte/lt/lte/eq/ne，也可写作 >、>= 等
+	Field    string `json:"field"`             // 比较的字段标识
+	Message  string `json:"message,omitempty"` // 自定义错误信息
+}
+
+// FieldError 字段验证错误
+type FieldError struct {
+	Field   string `json:"field"`   // 字段标识
+	Name    string `json:"name"`    // 字段名称
+	Rule    string `json:"rule"`    // 未通过的规则
+	Message string `json:"message"` // 错误信息
+}
+
+// FormValidationError 表单验证错误，包含全部未通过的字段，便于前端一次标出所有问题
+type FormValidationError struct {
+	Errors []FieldError `json:"errors"`
+}
+
+func (e *FormValidationError) Error() string {
+	messages := make([]string, 0, len(e.Errors))
+	for _, fieldErr := range e.Errors {
+		messages = append(messages, fieldErr.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+// ParseFieldValidation 解析并检查字段验证规则，空配置返回nil
+func ParseFieldValidation(data string) (*FieldValidation, error) {
+	if strings.TrimSpace(data) == "" || data == "null" {
+		return nil, nil
+	}
+	var validation FieldValidation
+	if err := json.Unmarshal([]byte(data), &validation); err != nil {
+		return nil, fmt.Errorf("验证规则格式错误: %w", err)
+	}
+	if validation.Pattern != "" {
+		pattern, err := regexp.Compile(validation.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("正则表达式 %s 无效: %w", validation.Pattern, err)
+		}
+		validation.pattern = pattern
+	}
+	if validation.Format != "" && fieldFormatPatterns[validation.Format] == nil {
+		return nil, fmt.Errorf("不支持的格式: %s", validation.Format)
+	}
+	for _, bound := range []string{validation.MinDate, validation.MaxDate} {
+		if _, ok := dateBound(bound, time.Now()); bound != "" && !ok {
+			return nil, fmt.Errorf("日期范围 %s 无效", bound)
+		}
+	}
+	for _, rule := range validation.Compare {
+		if rule.Field == "" || compareOpNames[normalizeConditionOp(rule.Operator)] == "" {
+			return nil, fmt.Errorf("跨字段比较规则无效: %s %s", rule.Operator, rule.Field)
+		}
+	}
+	if validation.MaxFileSize < 0 {
+		return nil, errors.New("文件大小上限不能为负数")
+	}
+	if validation.MinRows != nil && validation.MaxRows != nil && *validation.MinRows > *validation.MaxRows {
+		return nil, errors.New("明细表最少行数不能大于最多行数")
+	}
+	for _, accept := range validation.Accept {
+		if !strings.Contains(accept, "/") {
+			return nil, fmt.Errorf("文件类型 %s 无效，应为MIME类型如 application/pdf 或 image/*", accept)
+		}
+	}
+	return &validation, nil
+}
+
+// ValidateFormValues 按表单定义验证表单值，返回全部未通过的字段
+func (s *FormService) ValidateFormValues(formID uint, formValues string) ([]FieldError, error) {
+	form, err := s.GetFormDefinition(formID)
+	if err != nil {
+		return nil, err
+	}
+
+	var values map[string]interface{}
+	if err := json.Unmarshal([]byte(formValues), &values); err != nil {
+		return nil, fmt.Errorf("表单数据格式错误: %w", err)
+	}
+	return validateFormValues(newFieldOptionChecker(s.db, true), form, values, time.Now())
+}
+
+// validateFormValues 逐字段验证：必填、数据类型、验证规则、可选值和跨字段比较，明细表逐行验证每一列；
+// 按条件规则隐藏的字段只是不必填。验证前先计算明细表的公式列，计算结果写回 values
+func validateFormValues(options *fieldOptionChecker, form *models.FormDefinition, values map[string]interface{}, now time.Time) ([]FieldError, error) {
+	if err := computeTableValues(form, values); err != nil {
+		return nil, err
+	}
+	states, err := evaluateFieldStates(form, values)
+	if err != nil {
+		return nil, err
+	}
+	tables, err := formTableFields(form)
+	if err != nil {
+		return nil, err
+	}
+	tableIndex := make(map[string]*tableField, len(tables))
+	for _, table := range tables {
+		tableIndex[table.attr.Attribute.FieldKey] = table
+	}
+
+	names := make(map[string]string)
+	for _, card := range form.Cards {
+		for _, attr := range card.Attributes {
+			names[attr.Attribute.FieldKey] = attr.Name
+		}
+	}
+
+	fieldErrors := make([]FieldError, 0)
+	for _, card := range form.Cards {
+		for _, attr := range card.Attributes {
+			// 隐藏的字段不必填，但提交的值仍会保存，其它规则照常验证
+			state := states[attr.Attribute.FieldKey]
+			required := state.Show && state.Required
+			validation, err := ParseFieldValidation(attr.Validation)
+			if err != nil {
+				return nil, fmt.Errorf("字段 %s 的%w", attr.Name, err)
+			}
+			if fieldErr := validateFieldValue(&attr, required, validation, values, names, now); fieldErr != nil {
+				fieldErrors = append(fieldErrors, *fieldErr)
+				continue
+			}
+			if value := values[attr.Attribute.FieldKey]; !isEmptyValue(value) {
+				fieldErr, err := options.check(attr.Attribute.FieldKey, &attr, value)
+				if err != nil {
+					return nil, err
+				}
+				if fieldErr != nil {
+					fieldErrors = append(fieldErrors, *fieldErr)
+				}
+			}
+			if table := tableIndex[attr.Attribute.FieldKey]; table != nil {
+				rows, _ := tableRows(values[attr.Attribute.FieldKey])
+				rowErrors, err := table.validateTableRows(options, rows, now)
+				if err != nil {
+					return nil, err
+				}
+				fieldErrors = append(fieldErrors, rowErrors...)
+			}
+		}
+	}
+	return fieldErrors, nil
+}
+
+// validateFieldValue 验证单个字段，返回第一个未通过的规则；空值只检查必填
+func validateFieldValue(attr *models.FormAttribute, required bool, validation *FieldValidation, values map[string]interface{}, names map[string]string, now time.Time) *FieldError {
+	key := attr.Attribute.FieldKey
+	value := values[key]
+	fail := func(rule, message string) *FieldError {
+		if validation != nil && validation.Message != "" && rule != FieldRuleRequired && rule != FieldRuleType {
+			message = validation.Message
+		}
+		return &FieldError{Field: key, Name: attr.Name, Rule: rule, Message: message}
+	}
+
+	if isEmptyValue(value) {
+		if required {
+			return fail(FieldRuleRequired, fmt.Sprintf("必填字段 %s 不能为空", attr.Name))
+		}
+		return nil
+	}
+	if attr.Element == models.ElementTypeFile {
+		// 文件是否存在及归属在保存表单数据时检查
+		if _, ok := fileKeysFromValue(value); !ok {
+			return fail(FieldRuleType, fmt.Sprintf("%s 必须是文件列表", attr.Name))
+		}
+	} else if attr.Element == models.ElementTypeTable {
+		// 每一行的列在 validateTableRows 中验证
+		if _, ok := tableRows(value); !ok {
+			return fail(FieldRuleType, fmt.Sprintf("%s 必须是明细行列表", attr.Name))
+		}
+	} else if message := checkDataType(attr.Attribute.DataType, value); message != "" {
+		return fail(FieldRuleType, fmt.Sprintf("%s %s", attr.Name, message))
+	}
+	if allowed, ok := optionValues(attr); ok {
+		items, isList := toList(value)
+		if !isList {
+			items = []interface{}{value}
+		}
+		for _, item := range items {
+			if !listContains(allowed, item) {
+				return fail(FieldRuleOption, fmt.Sprintf("%s 的值 %s 不在可选范围内", attr.Name, toString(item)))
+			}
+		}
+	}
+	if validation == nil {
+		return nil
+	}
+
+	if number, ok := toFloat(value); ok {
+		if validation.Min != nil && number < *validation.Min {
+			return fail(FieldRuleMin, fmt.Sprintf("%s 不能小于 %s", attr.Name, toString(*validation.Min)))
+		}
+		if validation.Max != nil && number > *validation.Max {
+			return fail(FieldRuleMax, fmt.Sprintf("%s 不能大于 %s", attr.Name, toString(*validation.Max)))
+		}
+	}
+
+	if rows, ok := tableRows(value); ok && attr.Element == models.ElementTypeTable {
+		if validation.MinRows != nil && len(rows) < *validation.MinRows {
+			return fail(FieldRuleMinRows, fmt.Sprintf("%s 至少需要 %d 行", attr.Name, *validation.MinRows))
+		}
+		if validation.MaxRows != nil && len(rows) > *validation.MaxRows {
+			return fail(FieldRuleMaxRows, fmt.Sprintf("%s 最多 %d 行", attr.Name, *validation.MaxRows))
+		}
+	}
+
+	length := -1
+	if items, ok := toList(value); ok {
+		length = len(items)
+	} else if text, ok := value.(string); ok {
+		length = utf8.RuneCountInString(text)
+	}
+	if length >= 0 {
+		if validation.MinLength != nil && length < *validation.MinLength {
+			return fail(FieldRuleMinLength, fmt.Sprintf("%s 长度不能少于 %d", attr.Name, *validation.MinLength))
+		}
+		if validation.MaxLength != nil && length > *validation.MaxLength {
+			return fail(FieldRuleMaxLength, fmt.Sprintf("%s 长度不能超过 %d", attr.Name, *validation.MaxLength))
+		}
+	}
+
+	if text, ok := value.(string); ok {
+		if validation.pattern != nil && !validation.pattern.MatchString(text) {
+			return fail(FieldRulePattern, fmt.Sprintf("%s 格式不正确", attr.Name))
+		}
+		switch validation.Format {
+		case FieldFormatEmail:
+			if !fieldFormatPatterns[FieldFormatEmail].MatchString(text) {
+				return fail(FieldRuleFormat, fmt.Sprintf("%s 不是有效的邮箱地址", attr.Name))
+			}
+		case FieldFormatPhone:
+			if !fieldFormatPatterns[FieldFormatPhone].MatchString(text) {
+				return fail(FieldRuleFormat, fmt.Sprintf("%s 不是有效的电话号码", attr.Name))
+			}
+		}
+	}
+
+	// 日期范围按天比较
+	if date, ok := toTime(value); ok {
+		day := truncateDay(date)
+		if bound, ok := dateBound(validation.MinDate, now); ok && day.Before(bound) {
+			return fail(FieldRuleMinDate, fmt.Sprintf("%s 不能早于 %s", attr.Name, bound.Format("2006-01-02")))
+		}
+		if bound, ok := dateBound(validation.MaxDate, now); ok && day.After(bound) {
+			return fail(FieldRuleMaxDate, fmt.Sprintf("%s 不能晚于 %s", attr.Name, bound.Format("2006-01-02")))
+		}
+	}
+
+	for _, rule := range validation.Compare {
+		other := values[rule.Field]
+		if isEmptyValue(other) {
+			continue
+		}
+		op := normalizeConditionOp(rule.Operator)
+		if !compareValues(op, value, other) {
+			otherName := names[rule.Field]
+			if otherName == "" {
+				otherName = rule.Field
+			}
+			message := fmt.Sprintf("%s 必须%s %s", attr.Name, compareOpNames[op], otherName)
+			if rule.Message != "" {
+				message = rule.Message
+			}
+			return &FieldError{Field: key, Name: attr.Name, Rule: FieldRuleCompare, Message: message}
+		}
+	}
+	return nil
+}
+
+// checkDataType 按字段数据类型检查值，通过时返回空字符串
+func checkDataType(dataType string, value interface{}) string {
+	switch dataType {
+	case models.DataTypeString:
+		if _, ok := value.(string); !ok {
+			return "必须是文本"
+		}
+	case models.DataTypeNumber:
+		if _, ok := toFloat(value); !ok {
+			return "必须是数字"
+		}
+	case models.DataTypeInteger:
+		if number, ok := toFloat(value); !ok || number != math.Trunc(number) {
+			return "必须是整数"
+		}
+	case models.DataTypeBoolean:
+		if _, ok := toBool(value); !ok {
+			return "必须是布尔值"
+		}
+	case models.DataTypeDate:
+		if _, ok := toTime(value); !ok {
+			return "必须是有效的日期"
+		}
+	}
+	return ""
+}
+
+// optionValues 获取单选、多选和下拉字段配置的可选值；选项可以是值本身或带 value 的对象
+func optionValues(attr *models.FormAttribute) ([]interface{}, bool) {
+	switch attr.Element {
+	case models.ElementTypeSelect, models.ElementTypeRadio, models.ElementTypeCheckbox:
+	default:
+		return nil, false
+	}
+	if strings.TrimSpace(attr.Options) == "" {
+		return nil, false
+	}
+	var options []interface{}
+	if err := json.Unmarshal([]byte(attr.Options), &options); err != nil || len(options) == 0 {
+		return nil, false
+	}
+
+	values := make([]interface{}, 0, len(options))
+	for _, option := range options {
+		if item, ok := option.(map[string]interface{}); ok {
+			values = append(values, item["value"])
+			continue
+		}
+		values = append(values, option)
+	}
+	return values, true
+}
+
+// dateBound 解析日期范围的边界，未设置或无效时返回false
+func dateBound(bound string, now time.Time) (time.Time, bool) {
+	bound = strings.TrimSpace(bound)
+	if bound == "" {
+		return time.Time{}, false
+	}
+	if strings.EqualFold(bound, dateBoundToday) {
+		return truncateDay(now), true
+	}
+	t, ok := toTime(bound)
+	if !ok {
+		return time.Time{}, false
+	}
+	return truncateDay(t), true
+}
+
+// truncateDay 截取到当天零点
+func truncateDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
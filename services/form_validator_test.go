@@ -0,0 +1,108 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"gin-web-api/models"
+)
+
+func TestValidateFieldValue(t *testing.T) {
+	now := time.Date(2024, 5, 10, 15, 0, 0, 0, time.Local)
+	names := map[string]string{"start": "开始日期", "budget": "预算"}
+
+	tests := []struct {
+		name       string
+		element    string
+		dataType   string
+		options    string
+		required   bool
+		validation string
+		values     map[string]interface{}
+		wantRule   string
+	}{
+		{"必填为空", models.ElementTypeInput, models.DataTypeString, "", true, "", map[string]interface{}{"v": "  "}, FieldRuleRequired},
+		{"非必填为空不检查规则", models.ElementTypeInput, models.DataTypeString, "", false, `{"min_length":2}`, map[string]interface{}{}, ""},
+		{"文本类型", models.ElementTypeInput, models.DataTypeString, "", false, "", map[string]interface{}{"v": float64(1)}, FieldRuleType},
+		{"数字类型", models.ElementTypeNumber, models.DataTypeNumber, "", false, "", map[string]interface{}{"v": "abc"}, FieldRuleType},
+		{"整数类型", models.ElementTypeNumber, models.DataTypeInteger, "", false, "", map[string]interface{}{"v": 1.5}, FieldRuleType},
+		{"日期类型", models.ElementTypeDate, models.DataTypeDate, "", false, "", map[string]interface{}{"v": "下周一"}, FieldRuleType},
+		{"文件字段必须是列表", models.ElementTypeFile, models.DataTypeJSON, "", false, "", map[string]interface{}{"v": float64(3)}, FieldRuleType},
+		{"明细表必须是行列表", models.ElementTypeTable, models.DataTypeJSON, "", false, "", map[string]interface{}{"v": "行"}, FieldRuleType},
+		{"单选不在可选值中", models.ElementTypeRadio, models.DataTypeString, `[{"label":"飞机","value":"plane"},"car"]`, false, "", map[string]interface{}{"v": "boat"}, FieldRuleOption},
+		{"单选在可选值中", models.ElementTypeRadio, models.DataTypeString, `[{"label":"飞机","value":"plane"},"car"]`, false, "", map[string]interface{}{"v": "plane"}, ""},
+		{"多选有值不在可选值中", models.ElementTypeCheckbox, models.DataTypeJSON, `["a","b"]`, false, "", map[string]interface{}{"v": []interface{}{"a", "c"}}, FieldRuleOption},
+		{"小于最小值", models.ElementTypeNumber, models.DataTypeNumber, "", false, `{"min":0}`, map[string]interface{}{"v": float64(-1)}, FieldRuleMin},
+		{"大于最大值", models.ElementTypeNumber, models.DataTypeNumber, "", false, `{"max":1000}`, map[string]interface{}{"v": "1000.5"}, FieldRuleMax},
+		{"等于边界值", models.ElementTypeNumber, models.DataTypeNumber, "", false, `{"min":0,"max":1000}`, map[string]interface{}{"v": float64(1000)}, ""},
+		{"文本按字符数计算长度", models.ElementTypeInput, models.DataTypeString, "", false, `{"max_length":2}`, map[string]interface{}{"v": "出差"}, ""},
+		{"文本过长", models.ElementTypeInput, models.DataTypeString, "", false, `{"max_length":2}`, map[string]interface{}{"v": "出差补贴"}, FieldRuleMaxLength},
+		{"多选数量过少", models.ElementTypeCheckbox, models.DataTypeJSON, "", false, `{"min_length":2}`, map[string]interface{}{"v": []interface{}{"a"}}, FieldRuleMinLength},
+		{"明细表行数过少", models.ElementTypeTable, models.DataTypeJSON, "", false, `{"min_rows":2}`, map[string]interface{}{"v": []interface{}{map[string]interface{}{}}}, FieldRuleMinRows},
+		{"明细表行数过多", models.ElementTypeTable, models.DataTypeJSON, "", false, `{"max_rows":1}`, map[string]interface{}{"v": []interface{}{map[string]interface{}{}, map[string]interface{}{}}}, FieldRuleMaxRows},
+		{"正则不匹配", models.ElementTypeInput, models.DataTypeString, "", false, `{"pattern":"^[A-Z]{3}$"}`, map[string]interface{}{"v": "ab"}, FieldRulePattern},
+		{"邮箱格式", models.ElementTypeInput, models.DataTypeString, "", false, `{"format":"email"}`, map[string]interface{}{"v": "user@"}, FieldRuleFormat},
+		{"手机号格式", models.ElementTypeInput, models.DataTypeString, "", false, `{"format":"phone"}`, map[string]interface{}{"v": "13800138000"}, ""},
+		{"固定电话格式", models.ElementTypeInput, models.DataTypeString, "", false, `{"format":"phone"}`, map[string]interface{}{"v": "0571-88886666"}, ""},
+		{"早于今天", models.ElementTypeDate, models.DataTypeDate, "", false, `{"min_date":"today"}`, map[string]interface{}{"v": "2024-05-09"}, FieldRuleMinDate},
+		{"今天按天比较", models.ElementTypeDate, models.DataTypeDate, "", false, `{"min_date":"today","max_date":"today"}`, map[string]interface{}{"v": "2024-05-10 08:00"}, ""},
+		{"晚于最晚日期", models.ElementTypeDate, models.DataTypeDate, "", false, `{"max_date":"2024-12-31"}`, map[string]interface{}{"v": "2025-01-01"}, FieldRuleMaxDate},
+		{"结束日期早于开始日期", models.ElementTypeDate, models.DataTypeDate, "", false, `{"compare":[{"operator":">","field":"start"}]}`, map[string]interface{}{"v": "2024-05-01", "start": "2024-05-03"}, FieldRuleCompare},
+		{"比较的字段为空时不比较", models.ElementTypeDate, models.DataTypeDate, "", false, `{"compare":[{"operator":">","field":"start"}]}`, map[string]interface{}{"v": "2024-05-01"}, ""},
+		{"金额不超过预算", models.ElementTypeNumber, models.DataTypeNumber, "", false, `{"compare":[{"operator":"lte","field":"budget"}]}`, map[string]interface{}{"v": float64(900), "budget": "1000"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validation, err := ParseFieldValidation(tt.validation)
+			if err != nil {
+				t.Fatalf("ParseFieldValidation(%q) error = %v", tt.validation, err)
+			}
+			attr := &models.FormAttribute{
+				Name:      "字段",
+				Element:   tt.element,
+				Options:   tt.options,
+				Attribute: models.FieldAttribute{FieldKey: "v", DataType: tt.dataType},
+			}
+
+			fieldErr := validateFieldValue(attr, tt.required, validation, tt.values, names, now)
+			gotRule := ""
+			if fieldErr != nil {
+				gotRule = fieldErr.Rule
+				if fieldErr.Field != "v" || fieldErr.Name != "字段" || fieldErr.Message == "" {
+					t.Errorf("FieldError = %+v", fieldErr)
+				}
+			}
+			if gotRule != tt.wantRule {
+				t.Errorf("validateFieldValue() rule = %q, want %q (%+v)", gotRule, tt.wantRule, fieldErr)
+			}
+		})
+	}
+}
+
+func TestValidateFieldValueMessage(t *testing.T) {
+	attr := &models.FormAttribute{Name: "编码", Element: models.ElementTypeInput, Attribute: models.FieldAttribute{FieldKey: "code", DataType: models.DataTypeString}}
+	validation, err := ParseFieldValidation(`{"pattern":"^[A-Z]{3}$","message":"编码为三位大写字母"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		required    bool
+		value       interface{}
+		wantMessage string
+	}{
+		{"自定义信息替代规则的默认信息", false, "ab", "编码为三位大写字母"},
+		{"必填不使用自定义信息", true, "", "必填字段 编码 不能为空"},
+		{"类型不使用自定义信息", false, float64(1), "编码 必须是文本"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fieldErr := validateFieldValue(attr, tt.required, validation, map[string]interface{}{"code": tt.value}, nil, time.Now())
+			if fieldErr == nil || fieldErr.Message != tt.wantMessage {
+				t.Errorf("validateFieldValue() = %+v, want message %q", fieldErr, tt.wantMessage)
+			}
+		})
+	}
+}
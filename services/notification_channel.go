@@ -0,0 +1,216 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"gin-web-api/config"
+	"gin-web-api/models"
+)
+
+// NotificationChannel 通知渠道：站内信由通知记录本身承载，其他渠道通过 RegisterNotificationChannel 注册
+type NotificationChannel interface {
+	// DefaultTarget 用户未配置接收地址时使用的默认地址，返回空表示该用户不能通过此渠道接收
+	DefaultTarget(user *models.User) string
+	// ValidateTarget 检查用户在通知偏好中配置的接收地址
+	ValidateTarget(target string) error
+	// Send 将通知发送到 notification.Target
+	Send(notification *models.Notification) error
+}
+
+var (
+	notificationChannelsMu sync.RWMutex
+	notificationChannels   = make(map[string]NotificationChannel)
+)
+
+// RegisterNotificationChannel 注册通知渠道，同名渠道会被替换
+func RegisterNotificationChannel(name string, channel NotificationChannel) {
+	notificationChannelsMu.Lock()
+	defer notificationChannelsMu.Unlock()
+	notificationChannels[name] = channel
+}
+
+// getNotificationChannel 获取已注册的通知渠道
+func getNotificationChannel(name string) (NotificationChannel, bool) {
+	notificationChannelsMu.RLock()
+	defer notificationChannelsMu.RUnlock()
+	channel, ok := notificationChannels[name]
+	return channel, ok
+}
+
+// notificationChannelNames 获取可用的通知渠道：站内信在前，其余按名称排序
+func notificationChannelNames() []string {
+	notificationChannelsMu.RLock()
+	defer notificationChannelsMu.RUnlock()
+	names := make([]string, 0, len(notificationChannels))
+	for name := range notificationChannels {
+		if name != models.NotificationChannelInbox {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return append([]string{models.NotificationChannelInbox}, names...)
+}
+
+// SMTPChannel 邮件通知渠道，默认发送到用户邮箱
+type SMTPChannel struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+// NewSMTPChannel 创建邮件通知渠道，未配置用户名时不进行认证（如本地的测试邮件服务器）
+func NewSMTPChannel(cfg config.SMTPConfig) *SMTPChannel {
+	channel := &SMTPChannel{
+		addr: cfg.Host + ":" + cfg.Port,
+		from: cfg.From,
+	}
+	if cfg.Username != "" {
+		channel.auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+	return channel
+}
+
+// DefaultTarget 默认发送到用户邮箱
+func (c *SMTPChannel) DefaultTarget(user *models.User) string {
+	return user.Email
+}
+
+// ValidateTarget 接收地址必须是单个邮箱地址
+func (c *SMTPChannel) ValidateTarget(target string) error {
+	address, err := mail.ParseAddress(target)
+	if err != nil || address.Address != target {
+		return fmt.Errorf("无效的邮箱地址: %s", target)
+	}
+	return nil
+}
+
+// Send 发送邮件
+func (c *SMTPChannel) Send(notification *models.Notification) error {
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", c.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", notification.Target)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.BEncoding.Encode("UTF-8", notification.Subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(strings.ReplaceAll(notification.Body, "\n", "\r\n"))
+
+	if err := smtp.SendMail(c.addr, c.auth, c.from, []string{notification.Target}, msg.Bytes()); err != nil {
+		return fmt.Errorf("发送邮件失败: %w", err)
+	}
+	return nil
+}
+
+// WebhookChannel 推送通知渠道：将通知以JSON推送到用户在通知偏好中配置的地址。
+// 推送地址由普通用户配置，不允许访问内网、本机和链路本地地址
+type WebhookChannel struct {
+	client *http.Client
+}
+
+// NewWebhookChannel 创建推送通知渠道，连接时再次检查解析出的地址，防止域名解析到内网地址
+func NewWebhookChannel() *WebhookChannel {
+	dialer := &net.Dialer{
+		Timeout: webhookTimeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || isInternalIP(ip) {
+				return fmt.Errorf("不允许推送到内网地址: %s", host)
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &WebhookChannel{client: &http.Client{
+		Timeout:   webhookTimeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}}
+}
+
+// ValidateTarget 推送地址必须是 http(s) 地址，且不能指向内网地址
+func (c *WebhookChannel) ValidateTarget(target string) error {
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return fmt.Errorf("推送地址必须是 http 或 https 地址: %s", target)
+	}
+	if u.User != nil {
+		return errors.New("推送地址不能包含用户名和密码")
+	}
+	ips, err := net.LookupIP(u.Hostname())
+	if err != nil {
+		return fmt.Errorf("无法解析推送地址: %w", err)
+	}
+	for _, ip := range ips {
+		if isInternalIP(ip) {
+			return fmt.Errorf("不允许推送到内网地址: %s", u.Hostname())
+		}
+	}
+	return nil
+}
+
+// isInternalIP 判断是否为内网、本机、链路本地或未指定地址
+func isInternalIP(ip net.IP) bool {
+	return ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsUnspecified() || ip.IsMulticast()
+}
+
+// DefaultTarget 推送地址需由用户配置，没有默认地址
+func (c *WebhookChannel) DefaultTarget(user *models.User) string {
+	return ""
+}
+
+// Send 推送通知
+func (c *WebhookChannel) Send(notification *models.Notification) error {
+	body, _ := json.Marshal(map[string]interface{}{
+		"id":          notification.ID,
+		"event":       notification.Event,
+		"user_id":     notification.UserID,
+		"instance_id": notification.InstanceID,
+		"task_id":     notification.TaskID,
+		"subject":     notification.Subject,
+		"body":        notification.Body,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), webhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, notification.Target, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("创建请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, webhookResponseLimit))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("推送地址返回状态码 %d", resp.StatusCode)
+	}
+	return nil
+}
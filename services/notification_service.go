@@ -0,0 +1,669 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gin-web-api/database"
+	"gin-web-api/models"
+
+	"gorm.io/gorm"
+)
+
+// 通知发送的默认设置
+const (
+	notificationMaxAttempts  = 3
+	notificationRetryBackoff = time.Minute
+	notificationBatchSize    = 50
+)
+
+// defaultNotificationTemplates 未配置模板时使用的默认模板
+var defaultNotificationTemplates = map[string]struct{ Subject, Body string }{
+	models.EventTaskCreated: {
+		Subject: "待审批：${instance.title}",
+		Body:    "${recipient.name}，您好：\n「${instance.title}」已到达「${task.node_name}」节点，请及时处理。",
+	},
+	models.NotificationTaskReminder: {
+		Subject: "审批超时提醒：${instance.title}",
+		Body:    "${recipient.name}，您好：\n「${instance.title}」在「${task.node_name}」节点的任务已超过处理时限（${task.due_time}），请尽快处理。",
+	},
+	models.NotificationTaskEscalated: {
+		Subject: "超时任务转交：${instance.title}",
+		Body:    "${recipient.name}，您好：\n「${instance.title}」在「${task.node_name}」节点的任务处理超时，已转交给您处理。",
+	},
+	models.EventInstanceApproved: {
+		Subject: "审批通过：${instance.title}",
+		Body:    "${recipient.name}，您好：\n您发起的「${instance.title}」已审批通过。",
+	},
+	models.EventInstanceRejected: {
+		Subject: "审批未通过：${instance.title}",
+		Body:    "${recipient.name}，您好：\n您发起的「${instance.title}」未通过审批。",
+	},
+	models.EventInstanceCancelled: {
+		Subject: "流程已取消：${instance.title}",
+		Body:    "${recipient.name}，您好：\n您发起的「${instance.title}」已取消。",
+	},
+}
+
+// notifyEventInTx 按工作流事件通知相关用户：任务创建通知处理人（待认领任务通知候选人），实例结束通知发起人
+func (s *WorkflowService) notifyEventInTx(tx *gorm.DB, eventType string, instance *models.WorkflowInstance, task *models.WorkflowTask) error {
+	switch eventType {
+	case models.EventTaskCreated:
+		// 等待前序审批的加签任务暂不通知
+		if task.Status == models.TaskStatusWaiting {
+			return nil
+		}
+		recipients, err := s.taskRecipientsInTx(tx, task)
+		if err != nil {
+			return err
+		}
+		return s.notifyInTx(tx, eventType, instance, task, recipients)
+
+	case models.EventInstanceApproved, models.EventInstanceRejected, models.EventInstanceCancelled:
+		return s.notifyInTx(tx, eventType, instance, nil, []uint{instance.InitiatorID})
+	}
+	return nil
+}
+
+// taskRecipientsInTx 获取任务的通知对象：已有处理人时为处理人，待认领任务为全部候选人
+func (s *WorkflowService) taskRecipientsInTx(tx *gorm.DB, task *models.WorkflowTask) ([]uint, error) {
+	if task.AssigneeID != 0 {
+		return []uint{task.AssigneeID}, nil
+	}
+	var candidateIDs []uint
+	if err := tx.Model(&models.WorkflowTaskCandidate{}).Where("task_id = ?", task.ID).Pluck("user_id", &candidateIDs).Error; err != nil {
+		return nil, fmt.Errorf("获取任务候选人失败: %w", err)
+	}
+	return candidateIDs, nil
+}
+
+// notifyInTx 在业务事务中为接收人创建通知记录：按用户偏好选择渠道，按模板生成内容；
+// 站内信直接可见，其他渠道由后台任务发送
+func (s *WorkflowService) notifyInTx(tx *gorm.DB, event string, instance *models.WorkflowInstance, task *models.WorkflowTask, userIDs []uint) error {
+	seen := make(map[uint]bool)
+	var ids []uint
+	for _, id := range userIDs {
+		if id != 0 && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	var recipients []models.User
+	if err := tx.Where("id IN ? AND is_active = ?", ids, true).Find(&recipients).Error; err != nil {
+		return fmt.Errorf("获取通知接收人失败: %w", err)
+	}
+	var preferences []models.NotificationPreference
+	if err := tx.Where("user_id IN ?", ids).Find(&preferences).Error; err != nil {
+		return fmt.Errorf("获取通知偏好失败: %w", err)
+	}
+
+	lineageID := instance.WorkflowID
+	var workflow models.WorkflowDefinition
+	if err := tx.Select("id", "lineage_id").First(&workflow, instance.WorkflowID).Error; err == nil {
+		lineageID = workflow.GetLineageID()
+	}
+	var templates []models.NotificationTemplate
+	if err := tx.Where("event = ? AND (workflow_id IS NULL OR workflow_id = ?)", event, lineageID).
+		Order("id").Find(&templates).Error; err != nil {
+		return fmt.Errorf("获取通知模板失败: %w", err)
+	}
+
+	tmpl := &serviceTemplate{
+		ctx: s.buildConditionContext(tx, instance),
+		instance: map[string]interface{}{
+			"id":            instance.ID,
+			"title":         instance.Title,
+			"business_key":  instance.BusinessKey,
+			"business_type": instance.BusinessType,
+			"workflow_id":   instance.WorkflowID,
+			"initiator_id":  instance.InitiatorID,
+			"status":        string(instance.Status),
+		},
+		extra: map[string]map[string]interface{}{},
+	}
+	var taskID *uint
+	nodeKey := ""
+	if task != nil {
+		id := task.ID
+		taskID = &id
+		nodeKey = task.NodeKey
+		dueTime := ""
+		if task.DueTime != nil {
+			dueTime = task.DueTime.Format("2006-01-02 15:04")
+		}
+		tmpl.extra["task"] = map[string]interface{}{
+			"id":        task.ID,
+			"node_key":  task.NodeKey,
+			"node_name": task.NodeName,
+			"due_time":  dueTime,
+		}
+	}
+
+	now := time.Now()
+	for i := range recipients {
+		recipient := &recipients[i]
+		tmpl.extra["recipient"] = map[string]interface{}{
+			"id":       recipient.ID,
+			"username": recipient.Username,
+			"name":     userDisplayName(recipient),
+		}
+
+		for _, channel := range notificationChannelNames() {
+			target, ok := resolveNotificationTarget(preferences, recipient, channel, event)
+			if !ok {
+				continue
+			}
+			subject, body := selectNotificationTemplate(templates, event, nodeKey, channel)
+			notification := &models.Notification{
+				UserID:     recipient.ID,
+				Channel:    channel,
+				Event:      event,
+				InstanceID: instance.ID,
+				TaskID:     taskID,
+				Target:     target,
+				Subject:    tmpl.renderString(subject),
+				Body:       tmpl.renderString(body),
+				Status:     models.NotificationStatusPending,
+				NextRunAt:  now,
+			}
+			if channel == models.NotificationChannelInbox {
+				notification.Status = models.NotificationStatusSent
+				notification.SentAt = &now
+			}
+			if err := tx.Create(notification).Error; err != nil {
+				return fmt.Errorf("创建通知失败: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// resolveNotificationTarget 按用户偏好判断是否通过该渠道接收通知，并返回接收地址；
+// 指定事件的偏好优先于整个渠道的偏好，没有偏好时默认接收
+func resolveNotificationTarget(preferences []models.NotificationPreference, user *models.User, channel, event string) (string, bool) {
+	var matched *models.NotificationPreference
+	for i := range preferences {
+		pref := &preferences[i]
+		if pref.UserID != user.ID || pref.Channel != channel {
+			continue
+		}
+		if pref.Event == event {
+			matched = pref
+			break
+		}
+		if pref.Event == "" {
+			matched = pref
+		}
+	}
+	if matched != nil && !matched.Enabled {
+		return "", false
+	}
+	if channel == models.NotificationChannelInbox {
+		return "", true
+	}
+
+	target := ""
+	if matched != nil {
+		target = matched.Target
+	}
+	if target == "" {
+		if handler, ok := getNotificationChannel(channel); ok {
+			target = handler.DefaultTarget(user)
+		}
+	}
+	return target, target != ""
+}
+
+// selectNotificationTemplate 选择最具体的模板：工作流 > 节点 > 渠道，模板未填写的部分使用默认模板
+func selectNotificationTemplate(templates []models.NotificationTemplate, event, nodeKey, channel string) (string, string) {
+	defaults := defaultNotificationTemplates[event]
+	subject, body := defaults.Subject, defaults.Body
+
+	best, bestScore := -1, -1
+	for i, t := range templates {
+		if t.Event != event || (t.NodeKey != "" && t.NodeKey != nodeKey) || (t.Channel != "" && t.Channel != channel) {
+			continue
+		}
+		score := 0
+		if t.WorkflowID != nil {
+			score += 4
+		}
+		if t.NodeKey != "" {
+			score += 2
+		}
+		if t.Channel != "" {
+			score++
+		}
+		if score >= bestScore {
+			best, bestScore = i, score
+		}
+	}
+	if best >= 0 {
+		if templates[best].Subject != "" {
+			subject = templates[best].Subject
+		}
+		if templates[best].Body != "" {
+			body = templates[best].Body
+		}
+	}
+	return subject, body
+}
+
+// NotificationService 通知服务：站内信、通知偏好、通知模板和通知发送
+type NotificationService struct {
+	db *gorm.DB
+}
+
+// NewNotificationService 创建通知服务
+func NewNotificationService() *NotificationService {
+	return &NotificationService{
+		db: database.GetDB(),
+	}
+}
+
+// ListInbox 获取用户的站内信，unreadOnly 为 true 时只返回未读
+func (s *NotificationService) ListInbox(userID uint, unreadOnly bool, page, pageSize int) ([]models.Notification, int64, error) {
+	query := s.db.Model(&models.Notification{}).
+		Where("user_id = ? AND channel = ?", userID, models.NotificationChannelInbox)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("获取站内信失败: %w", err)
+	}
+
+	var notifications []models.Notification
+	if err := query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&notifications).Error; err != nil {
+		return nil, 0, fmt.Errorf("获取站内信失败: %w", err)
+	}
+	return notifications, total, nil
+}
+
+// CountUnread 获取用户的未读站内信数量
+func (s *NotificationService) CountUnread(userID uint) (int64, error) {
+	var count int64
+	if err := s.db.Model(&models.Notification{}).
+		Where("user_id = ? AND channel = ? AND read_at IS NULL", userID, models.NotificationChannelInbox).
+		Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("获取未读数量失败: %w", err)
+	}
+	return count, nil
+}
+
+// MarkRead 将站内信标记为已读或未读
+func (s *NotificationService) MarkRead(notificationID, userID uint, read bool) error {
+	var notification models.Notification
+	if err := s.db.Where("id = ? AND user_id = ? AND channel = ?", notificationID, userID, models.NotificationChannelInbox).
+		First(&notification).Error; err != nil {
+		return fmt.Errorf("站内信不存在: %w", err)
+	}
+
+	var readAt *time.Time
+	if read {
+		now := time.Now()
+		readAt = &now
+		if notification.ReadAt != nil {
+			return nil
+		}
+	}
+	return s.db.Model(&notification).Update("read_at", readAt).Error
+}
+
+// MarkAllRead 将用户全部未读站内信标记为已读，返回标记的数量
+func (s *NotificationService) MarkAllRead(userID uint) (int64, error) {
+	result := s.db.Model(&models.Notification{}).
+		Where("user_id = ? AND channel = ? AND read_at IS NULL", userID, models.NotificationChannelInbox).
+		Update("read_at", time.Now())
+	if result.Error != nil {
+		return 0, fmt.Errorf("标记已读失败: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// NotificationPreferenceItem 通知偏好项
+type NotificationPreferenceItem struct {
+	Channel string `json:"channel" binding:"required"`
+	Event   string `json:"event"` // 为空表示该渠道的全部事件
+	Enabled bool   `json:"enabled"`
+	Target  string `json:"target"`
+}
+
+// NotificationPreferences 用户的通知偏好及可用的渠道和事件
+type NotificationPreferences struct {
+	Channels    []string                        `json:"channels"`
+	Events      []string                        `json:"events"`
+	Preferences []models.NotificationPreference `json:"preferences"`
+}
+
+// GetPreferences 获取用户的通知偏好，未配置的渠道和事件默认接收
+func (s *NotificationService) GetPreferences(userID uint) (*NotificationPreferences, error) {
+	var preferences []models.NotificationPreference
+	if err := s.db.Where("user_id = ?", userID).Order("channel, event").Find(&preferences).Error; err != nil {
+		return nil, fmt.Errorf("获取通知偏好失败: %w", err)
+	}
+	return &NotificationPreferences{
+		Channels:    notificationChannelNames(),
+		Events:      models.NotificationEvents,
+		Preferences: preferences,
+	}, nil
+}
+
+// UpdatePreferences 保存用户的通知偏好，按渠道和事件覆盖已有设置
+func (s *NotificationService) UpdatePreferences(userID uint, items []NotificationPreferenceItem) (*NotificationPreferences, error) {
+	channels := notificationChannelNames()
+	for _, item := range items {
+		if !containsString(channels, item.Channel) {
+			return nil, fmt.Errorf("不支持的通知渠道: %s", item.Channel)
+		}
+		if item.Event != "" && !containsString(models.NotificationEvents, item.Event) {
+			return nil, fmt.Errorf("不支持的通知事件: %s", item.Event)
+		}
+		if item.Target == "" {
+			continue
+		}
+		channel, ok := getNotificationChannel(item.Channel)
+		if !ok {
+			return nil, fmt.Errorf("通知渠道 %s 不支持配置接收地址", item.Channel)
+		}
+		if err := channel.ValidateTarget(item.Target); err != nil {
+			return nil, err
+		}
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		for _, item := range items {
+			var pref models.NotificationPreference
+			err := tx.Where("user_id = ? AND channel = ? AND event = ?", userID, item.Channel, item.Event).First(&pref).Error
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("获取通知偏好失败: %w", err)
+			}
+			pref.UserID = userID
+			pref.Channel = item.Channel
+			pref.Event = item.Event
+			pref.Enabled = item.Enabled
+			pref.Target = item.Target
+			if err := tx.Save(&pref).Error; err != nil {
+				return fmt.Errorf("保存通知偏好失败: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetPreferences(userID)
+}
+
+// NotificationTemplateRequest 创建或更新通知模板请求
+type NotificationTemplateRequest struct {
+	Event      string `json:"event" binding:"required"`
+	WorkflowID *uint  `json:"workflow_id"`
+	NodeKey    string `json:"node_key"`
+	Channel    string `json:"channel"`
+	Subject    string `json:"subject"`
+	Body       string `json:"body"`
+}
+
+// ListTemplates 获取通知模板，workflowID 不为0时只返回该工作流的模板
+func (s *NotificationService) ListTemplates(workflowID uint) ([]models.NotificationTemplate, error) {
+	query := s.db.Order("id")
+	if workflowID != 0 {
+		query = query.Where("workflow_id = ?", workflowID)
+	}
+	var templates []models.NotificationTemplate
+	if err := query.Find(&templates).Error; err != nil {
+		return nil, fmt.Errorf("获取通知模板失败: %w", err)
+	}
+	return templates, nil
+}
+
+// CreateTemplate 创建通知模板
+func (s *NotificationService) CreateTemplate(req *NotificationTemplateRequest, userID uint) (*models.NotificationTemplate, error) {
+	template := &models.NotificationTemplate{CreatedBy: userID}
+	if err := s.applyTemplateRequest(template, req); err != nil {
+		return nil, err
+	}
+	if err := s.db.Create(template).Error; err != nil {
+		return nil, fmt.Errorf("创建通知模板失败: %w", err)
+	}
+	return template, nil
+}
+
+// UpdateTemplate 更新通知模板
+func (s *NotificationService) UpdateTemplate(templateID uint, req *NotificationTemplateRequest) (*models.NotificationTemplate, error) {
+	var template models.NotificationTemplate
+	if err := s.db.First(&template, templateID).Error; err != nil {
+		return nil, fmt.Errorf("通知模板不存在: %w", err)
+	}
+	if err := s.applyTemplateRequest(&template, req); err != nil {
+		return nil, err
+	}
+	if err := s.db.Save(&template).Error; err != nil {
+		return nil, fmt.Errorf("更新通知模板失败: %w", err)
+	}
+	return &template, nil
+}
+
+// DeleteTemplate 删除通知模板
+func (s *NotificationService) DeleteTemplate(templateID uint) error {
+	result := s.db.Delete(&models.NotificationTemplate{}, templateID)
+	if result.Error != nil {
+		return fmt.Errorf("删除通知模板失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("通知模板不存在")
+	}
+	return nil
+}
+
+// applyTemplateRequest 校验并填写模板内容，工作流统一记录为版本系列ID
+func (s *NotificationService) applyTemplateRequest(template *models.NotificationTemplate, req *NotificationTemplateRequest) error {
+	if !containsString(models.NotificationEvents, req.Event) {
+		return fmt.Errorf("不支持的通知事件: %s", req.Event)
+	}
+	if req.Channel != "" && !containsString(notificationChannelNames(), req.Channel) {
+		return fmt.Errorf("不支持的通知渠道: %s", req.Channel)
+	}
+	if req.Subject == "" && req.Body == "" {
+		return errors.New("模板标题和内容不能都为空")
+	}
+
+	template.WorkflowID = nil
+	if req.WorkflowID != nil && *req.WorkflowID != 0 {
+		var workflow models.WorkflowDefinition
+		if err := s.db.First(&workflow, *req.WorkflowID).Error; err != nil {
+			return fmt.Errorf("工作流不存在: %w", err)
+		}
+		lineageID := workflow.GetLineageID()
+		template.WorkflowID = &lineageID
+	} else if req.NodeKey != "" {
+		return errors.New("指定节点的模板必须指定工作流")
+	}
+
+	template.Event = req.Event
+	template.NodeKey = req.NodeKey
+	template.Channel = req.Channel
+	template.Subject = req.Subject
+	template.Body = req.Body
+	return nil
+}
+
+// ListNotifications 获取通知发送记录，过滤条件为空时不过滤
+func (s *NotificationService) ListNotifications(userID uint, channel, status string, page, pageSize int) ([]models.Notification, int64, error) {
+	query := s.db.Model(&models.Notification{})
+	if userID != 0 {
+		query = query.Where("user_id = ?", userID)
+	}
+	if channel != "" {
+		query = query.Where("channel = ?", channel)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("获取通知记录失败: %w", err)
+	}
+
+	var notifications []models.Notification
+	if err := query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&notifications).Error; err != nil {
+		return nil, 0, fmt.Errorf("获取通知记录失败: %w", err)
+	}
+	return notifications, total, nil
+}
+
+// SendPending 发送到期的通知（含发送中断超过锁定期限的通知），返回发送的数量
+func (s *NotificationService) SendPending(now time.Time) (int, error) {
+	var notificationIDs []uint
+	if err := s.db.Model(&models.Notification{}).
+		Where("(status = ? AND next_run_at <= ?) OR (status = ? AND locked_until <= ?)",
+			models.NotificationStatusPending, now, models.NotificationStatusRunning, now).
+		Order("next_run_at").
+		Limit(notificationBatchSize).
+		Pluck("id", &notificationIDs).Error; err != nil {
+		return 0, fmt.Errorf("查询待发送通知失败: %w", err)
+	}
+
+	sent := 0
+	for _, notificationID := range notificationIDs {
+		claimed, err := s.send(notificationID, now)
+		if err != nil {
+			log.Printf("通知 %d 发送失败: %v", notificationID, err)
+			continue
+		}
+		if claimed {
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+// send 认领并发送一条通知；通知已被其他副本认领时返回 false
+func (s *NotificationService) send(notificationID uint, now time.Time) (bool, error) {
+	result := s.db.Model(&models.Notification{}).
+		Where("id = ? AND ((status = ? AND next_run_at <= ?) OR (status = ? AND locked_until <= ?))",
+			notificationID, models.NotificationStatusPending, now, models.NotificationStatusRunning, now).
+		Updates(map[string]interface{}{
+			"status":       models.NotificationStatusRunning,
+			"locked_until": now.Add(2 * time.Minute),
+			"attempts":     gorm.Expr("attempts + 1"),
+		})
+	if result.Error != nil {
+		return false, fmt.Errorf("认领通知失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+
+	var notification models.Notification
+	if err := s.db.First(&notification, notificationID).Error; err != nil {
+		return true, fmt.Errorf("通知不存在: %w", err)
+	}
+
+	var sendErr error
+	if channel, ok := getNotificationChannel(notification.Channel); ok {
+		sendErr = channel.Send(&notification)
+	} else {
+		sendErr = fmt.Errorf("通知渠道 %s 未启用", notification.Channel)
+		notification.Attempts = notificationMaxAttempts
+	}
+
+	finished := time.Now()
+	notification.LockedUntil = nil
+	switch {
+	case sendErr == nil:
+		notification.Status = models.NotificationStatusSent
+		notification.LastError = ""
+		notification.SentAt = &finished
+	case notification.Attempts < notificationMaxAttempts:
+		notification.Status = models.NotificationStatusPending
+		notification.LastError = sendErr.Error()
+		notification.NextRunAt = finished.Add(notificationRetryBackoff << (notification.Attempts - 1))
+	default:
+		notification.Status = models.NotificationStatusFailed
+		notification.LastError = sendErr.Error()
+	}
+	if err := s.db.Save(&notification).Error; err != nil {
+		return true, fmt.Errorf("更新通知失败: %w", err)
+	}
+	return true, nil
+}
+
+// containsString 判断字符串是否在列表中
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// NotificationWorker 通知发送后台任务：发送邮件、推送等渠道的待发送通知，
+// 通知通过条件更新认领，多副本部署时不会重复发送
+type NotificationWorker struct {
+	notificationService *NotificationService
+	interval            time.Duration
+	stop                chan struct{}
+}
+
+// NewNotificationWorker 创建通知发送后台任务
+func NewNotificationWorker(interval time.Duration) *NotificationWorker {
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	return &NotificationWorker{
+		notificationService: NewNotificationService(),
+		interval:            interval,
+		stop:                make(chan struct{}),
+	}
+}
+
+// Start 在后台启动通知发送任务
+func (w *NotificationWorker) Start() {
+	go func() {
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				w.RunOnce()
+			case <-w.stop:
+				return
+			}
+		}
+	}()
+	log.Printf("通知发送任务已启动，间隔 %s", w.interval)
+}
+
+// Stop 停止通知发送任务
+func (w *NotificationWorker) Stop() {
+	close(w.stop)
+}
+
+// RunOnce 发送一轮到期的通知
+func (w *NotificationWorker) RunOnce() {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("通知发送任务异常: %v", r)
+		}
+	}()
+
+	sent, err := w.notificationService.SendPending(time.Now())
+	if err != nil {
+		log.Printf("发送通知失败: %v", err)
+		return
+	}
+	if sent > 0 {
+		log.Printf("已发送 %d 条通知", sent)
+	}
+}
@@ -249,9 +249,11 @@ func (s *PermissionService) GetAllPermissions() ([]models.Permission, error) {
 func (s *PermissionService) IsWorkflowApprover(userID, instanceID uint) (bool, error) {
 	var count int64
 	
+	// 包括待认领任务的候选人
+	candidateTasks := s.db.Model(&models.WorkflowTaskCandidate{}).Select("task_id").Where("user_id = ?", userID)
 	err := s.db.Model(&models.WorkflowTask{}).
-		Where("instance_id = ? AND assignee_id = ? AND status = ?", 
-			instanceID, userID, models.TaskStatusPending).
+		Where("instance_id = ? AND status IN ? AND (assignee_id = ? OR (assignee_id = 0 AND id IN (?)))", 
+			instanceID, models.OpenTaskStatuses, userID, candidateTasks).
 		Count(&count).Error
 	
 	if err != nil {
@@ -302,6 +304,10 @@ func (s *PermissionService) CheckWorkflowPermission(userID uint, action string,
 		
 		return s.CheckPermission(userID, models.PermissionInstanceCancel)
 		
+	case "recall_instance":
+		// 可以撤回和重新提交实例：仅发起人
+		return s.IsWorkflowInitiator(userID, resourceID)
+		
 	default:
 		return false, errors.New("未知的权限检查类型")
 	}
@@ -320,8 +326,12 @@ func (s *PermissionService) InitializeDefaultData() error {
 			{Name: "创建实例", Code: models.PermissionInstanceCreate, Resource: "instance", Action: "create", Category: "实例", IsSystem: true},
 			{Name: "查看实例", Code: models.PermissionInstanceRead, Resource: "instance", Action: "read", Category: "实例", IsSystem: true},
 			{Name: "取消实例", Code: models.PermissionInstanceCancel, Resource: "instance", Action: "cancel", Category: "实例", IsSystem: true},
+			{Name: "挂起实例", Code: models.PermissionInstanceSuspend, Resource: "instance", Action: "suspend", Category: "实例", IsSystem: true},
 			{Name: "审批任务", Code: models.PermissionTaskApprove, Resource: "task", Action: "approve", Category: "任务", IsSystem: true},
 			{Name: "拒绝任务", Code: models.PermissionTaskReject, Resource: "task", Action: "reject", Category: "任务", IsSystem: true},
+			{Name: "认领任务", Code: models.PermissionTaskClaim, Resource: "task", Action: "claim", Category: "任务", IsSystem: true},
+			{Name: "转办任务", Code: models.PermissionInstanceTransfer, Resource: "instance", Action: "transfer", Category: "任务", IsSystem: true},
+			{Name: "委托任务", Code: models.PermissionTaskDelegate, Resource: "task", Action: "delegate", Category: "任务", IsSystem: true},
 			{Name: "系统管理", Code: models.PermissionSystemAdmin, Resource: "system", Action: "admin", Category: "系统", IsSystem: true},
 		}
 		
@@ -0,0 +1,313 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"gin-web-api/database"
+	"gin-web-api/models"
+	redisClient "gin-web-api/redis"
+
+	"gorm.io/gorm"
+)
+
+// realtimeChannel 实时事件的Redis频道，各副本订阅后推送给本副本上连接的用户
+const realtimeChannel = "workflow:realtime"
+
+// 实时推送的默认设置
+const (
+	realtimeEventMaxAge     = time.Minute // 超过该时长仍未推送的事件不再实时推送（如服务停止期间产生的事件）
+	realtimeBatchSize       = 100
+	realtimeSubscriberQueue = 32
+)
+
+// 实时事件类型
+const (
+	RealtimeTaskAssigned          = "task.assigned"           // 有新的待办任务或任务处理人变化
+	RealtimeTaskCompleted         = "task.completed"          // 任务已处理
+	RealtimeInstanceStatusChanged = "instance.status_changed" // 实例状态变化
+)
+
+// RealtimeEvent 推送给前端的实时事件
+type RealtimeEvent struct {
+	Type       string          `json:"type"`               // 实时事件类型
+	Event      string          `json:"event"`              // 来源工作流事件类型
+	InstanceID uint            `json:"instance_id"`        // 实例ID
+	TaskID     *uint           `json:"task_id,omitempty"`  // 任务ID
+	Payload    json.RawMessage `json:"payload"`            // 工作流事件内容
+	UserIDs    []uint          `json:"user_ids,omitempty"` // 接收人，推送给前端时不包含
+}
+
+// RealtimeRelay 实时事件转发后台任务：将发件箱中的新事件转换为实时事件发布到Redis，
+// 事件通过条件更新认领，多副本部署时每个事件只发布一次
+type RealtimeRelay struct {
+	db              *gorm.DB
+	workflowService *WorkflowService
+	interval        time.Duration
+	stop            chan struct{}
+}
+
+// NewRealtimeRelay 创建实时事件转发任务
+func NewRealtimeRelay(interval time.Duration) *RealtimeRelay {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	return &RealtimeRelay{
+		db:              database.GetDB(),
+		workflowService: NewWorkflowService(),
+		interval:        interval,
+		stop:            make(chan struct{}),
+	}
+}
+
+// Start 在后台启动实时事件转发任务
+func (r *RealtimeRelay) Start() {
+	go func() {
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				r.RunOnce()
+			case <-r.stop:
+				return
+			}
+		}
+	}()
+	log.Printf("实时事件转发任务已启动，间隔 %s", r.interval)
+}
+
+// Stop 停止实时事件转发任务
+func (r *RealtimeRelay) Stop() {
+	close(r.stop)
+}
+
+// RunOnce 发布一轮新事件
+func (r *RealtimeRelay) RunOnce() {
+	defer func() {
+		if rec := recover(); rec != nil {
+			log.Printf("实时事件转发任务异常: %v", rec)
+		}
+	}()
+
+	if _, err := r.PublishEvents(time.Now()); err != nil {
+		log.Printf("发布实时事件失败: %v", err)
+	}
+}
+
+// PublishEvents 将尚未推送的工作流事件发布到Redis频道，返回发布的数量
+func (r *RealtimeRelay) PublishEvents(now time.Time) (int, error) {
+	var events []models.WorkflowEvent
+	if err := r.db.Where("streamed_at IS NULL AND created_at >= ?", now.Add(-realtimeEventMaxAge)).
+		Order("id").Limit(realtimeBatchSize).Find(&events).Error; err != nil {
+		return 0, fmt.Errorf("查询工作流事件失败: %w", err)
+	}
+
+	published := 0
+	for _, event := range events {
+		err := r.db.Transaction(func(tx *gorm.DB) error {
+			result := tx.Model(&models.WorkflowEvent{}).
+				Where("id = ? AND streamed_at IS NULL", event.ID).
+				Update("streamed_at", now)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return nil
+			}
+
+			message, err := r.buildRealtimeEventInTx(tx, event)
+			if err != nil || message == nil {
+				return err
+			}
+			data, _ := json.Marshal(message)
+			// 发布失败时回滚认领，下一轮重试
+			if err := redisClient.Publish(realtimeChannel, data); err != nil {
+				return fmt.Errorf("发布到Redis失败: %w", err)
+			}
+			published++
+			return nil
+		})
+		if err != nil {
+			return published, fmt.Errorf("发布工作流事件 %d 失败: %w", event.ID, err)
+		}
+	}
+	return published, nil
+}
+
+// buildRealtimeEventInTx 将工作流事件转换为实时事件并确定接收人，不需要推送时返回 nil
+func (r *RealtimeRelay) buildRealtimeEventInTx(tx *gorm.DB, event models.WorkflowEvent) (*RealtimeEvent, error) {
+	var payload WorkflowEventPayload
+	if err := json.Unmarshal([]byte(event.Payload), &payload); err != nil {
+		return nil, fmt.Errorf("解析工作流事件失败: %w", err)
+	}
+
+	message := &RealtimeEvent{
+		Event:      event.EventType,
+		InstanceID: event.InstanceID,
+		TaskID:     event.TaskID,
+		Payload:    json.RawMessage(event.Payload),
+	}
+	var userIDs []uint
+	switch event.EventType {
+	case models.EventTaskCreated, models.EventTaskAssigned:
+		if payload.Task == nil || payload.Task.Status == models.TaskStatusWaiting {
+			return nil, nil
+		}
+		// 处理人和候选人看到新任务，转办、委托、认领时操作人的待办随之变化
+		message.Type = RealtimeTaskAssigned
+		recipients, err := r.workflowService.taskRecipientsInTx(tx, &models.WorkflowTask{ID: payload.Task.ID})
+		if err != nil {
+			return nil, err
+		}
+		userIDs = append(recipients, payload.Task.AssigneeID, payload.OperatorID)
+
+	case models.EventTaskApproved, models.EventTaskRejected:
+		if payload.Task == nil {
+			return nil, nil
+		}
+		// 同一节点的其他处理人（如或签时被取消的任务）和发起人
+		message.Type = RealtimeTaskCompleted
+		var assigneeIDs []uint
+		if err := tx.Model(&models.WorkflowTask{}).
+			Where("instance_id = ? AND node_key = ?", event.InstanceID, payload.Task.NodeKey).
+			Distinct().Pluck("assignee_id", &assigneeIDs).Error; err != nil {
+			return nil, err
+		}
+		userIDs = append(assigneeIDs, payload.Task.AssigneeID, payload.Instance.InitiatorID)
+
+	default:
+		// 实例状态变化推送给发起人和全部参与人
+		message.Type = RealtimeInstanceStatusChanged
+		var assigneeIDs, candidateIDs []uint
+		if err := tx.Model(&models.WorkflowTask{}).Where("instance_id = ?", event.InstanceID).
+			Distinct().Pluck("assignee_id", &assigneeIDs).Error; err != nil {
+			return nil, err
+		}
+		if err := tx.Model(&models.WorkflowTaskCandidate{}).
+			Joins("JOIN workflow_tasks ON workflow_tasks.id = workflow_task_candidates.task_id").
+			Where("workflow_tasks.instance_id = ?", event.InstanceID).
+			Distinct().Pluck("workflow_task_candidates.user_id", &candidateIDs).Error; err != nil {
+			return nil, err
+		}
+		userIDs = append(append(assigneeIDs, candidateIDs...), payload.Instance.InitiatorID)
+	}
+
+	seen := make(map[uint]bool)
+	for _, id := range userIDs {
+		if id != 0 && !seen[id] {
+			seen[id] = true
+			message.UserIDs = append(message.UserIDs, id)
+		}
+	}
+	if len(message.UserIDs) == 0 {
+		return nil, nil
+	}
+	return message, nil
+}
+
+// RealtimeSubscriber 一个实时连接
+type RealtimeSubscriber struct {
+	UserID uint
+	Events chan RealtimeEvent
+}
+
+// RealtimeHub 本副本的实时连接管理：订阅Redis频道，将事件推送给本副本上连接的接收人
+type RealtimeHub struct {
+	mu          sync.RWMutex
+	subscribers map[uint]map[*RealtimeSubscriber]struct{}
+	stop        chan struct{}
+}
+
+var (
+	realtimeHub     *RealtimeHub
+	realtimeHubOnce sync.Once
+)
+
+// GetRealtimeHub 获取本副本的实时连接管理
+func GetRealtimeHub() *RealtimeHub {
+	realtimeHubOnce.Do(func() {
+		realtimeHub = &RealtimeHub{
+			subscribers: make(map[uint]map[*RealtimeSubscriber]struct{}),
+			stop:        make(chan struct{}),
+		}
+	})
+	return realtimeHub
+}
+
+// Start 订阅Redis频道，在后台分发收到的实时事件
+func (h *RealtimeHub) Start() {
+	pubsub := redisClient.Subscribe(realtimeChannel)
+	go func() {
+		defer pubsub.Close()
+		messages := pubsub.Channel()
+		for {
+			select {
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+				h.dispatch(msg.Payload)
+			case <-h.stop:
+				return
+			}
+		}
+	}()
+	log.Println("实时事件订阅已启动")
+}
+
+// Stop 停止订阅
+func (h *RealtimeHub) Stop() {
+	close(h.stop)
+}
+
+// Subscribe 为用户建立实时连接，连接关闭时需调用 Unsubscribe
+func (h *RealtimeHub) Subscribe(userID uint) *RealtimeSubscriber {
+	subscriber := &RealtimeSubscriber{
+		UserID: userID,
+		Events: make(chan RealtimeEvent, realtimeSubscriberQueue),
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subscribers[userID] == nil {
+		h.subscribers[userID] = make(map[*RealtimeSubscriber]struct{})
+	}
+	h.subscribers[userID][subscriber] = struct{}{}
+	return subscriber
+}
+
+// Unsubscribe 关闭实时连接
+func (h *RealtimeHub) Unsubscribe(subscriber *RealtimeSubscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.subscribers[subscriber.UserID], subscriber)
+	if len(h.subscribers[subscriber.UserID]) == 0 {
+		delete(h.subscribers, subscriber.UserID)
+	}
+}
+
+// dispatch 将实时事件推送给本副本上连接的接收人，连接处理不过来时丢弃该连接的事件
+func (h *RealtimeHub) dispatch(data string) {
+	var event RealtimeEvent
+	if err := json.Unmarshal([]byte(data), &event); err != nil {
+		log.Printf("解析实时事件失败: %v", err)
+		return
+	}
+	userIDs := event.UserIDs
+	event.UserIDs = nil
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for _, userID := range userIDs {
+		for subscriber := range h.subscribers[userID] {
+			select {
+			case subscriber.Events <- event:
+			default:
+				log.Printf("用户 %d 的实时连接处理过慢，丢弃事件 %s", userID, event.Type)
+			}
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"gin-web-api/database"
+	"gin-web-api/models"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testUserCount 测试数据库中预先创建的用户数量，ID 从1开始
+const testUserCount = 6
+
+// setupTestDB 为每个测试创建独立的SQLite数据库并替换全局连接，迁移全部模型并创建测试用户
+func setupTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := fmt.Sprintf("file:%s?_pragma=busy_timeout(3000)", filepath.Join(t.TempDir(), "test.db"))
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("打开测试数据库失败: %v", err)
+	}
+	previous := database.DB
+	database.DB = db
+	t.Cleanup(func() { database.DB = previous })
+
+	if err := db.AutoMigrate(
+		&models.User{},
+		&models.Role{},
+		&models.Permission{},
+		&models.UserRole{},
+		&models.RolePermission{},
+		&models.Department{},
+		&models.UserDepartment{},
+		&models.FormDefinition{},
+		&models.FormCard{},
+		&models.FormAttribute{},
+		&models.FieldAttribute{},
+		&models.FormButton{},
+		&models.FormData{},
+		&models.FormDataChange{},
+		&models.WorkflowDefinition{},
+		&models.WorkflowNode{},
+		&models.WorkflowBranch{},
+		&models.WorkflowInstance{},
+		&models.WorkflowTask{},
+		&models.WorkflowTaskCandidate{},
+		&models.WorkflowHistory{},
+		&models.WorkflowServiceJob{},
+		&models.WorkflowEvent{},
+		&models.WebhookSubscription{},
+		&models.WebhookDelivery{},
+		&models.Notification{},
+		&models.NotificationTemplate{},
+		&models.NotificationPreference{},
+		&models.FileObject{},
+		&models.FileUpload{},
+	); err != nil {
+		t.Fatalf("迁移测试数据库失败: %v", err)
+	}
+
+	for i := 1; i <= testUserCount; i++ {
+		user := models.User{Username: fmt.Sprintf("user%d", i), Email: fmt.Sprintf("user%d@example.com", i), Password: "x"}
+		if err := db.Create(&user).Error; err != nil {
+			t.Fatalf("创建测试用户失败: %v", err)
+		}
+	}
+	return db
+}
+
+// createTestWorkflow 按节点树JSON创建并发布工作流
+func createTestWorkflow(t *testing.T, s *WorkflowService, nodeTree string) *models.WorkflowDefinition {
+	t.Helper()
+
+	var tree models.NodeTreeData
+	if err := json.Unmarshal([]byte(nodeTree), &tree); err != nil {
+		t.Fatalf("节点树格式错误: %v", err)
+	}
+	workflow, err := s.CreateWorkflowWithNodeTree(&CreateWorkflowWithTreeRequest{Name: t.Name(), NodeTree: &tree}, 1)
+	if err != nil {
+		t.Fatalf("创建工作流失败: %v", err)
+	}
+	if _, err := s.PublishWorkflow(workflow.ID, 1, true); err != nil {
+		t.Fatalf("发布工作流失败: %v", err)
+	}
+	return workflow
+}
+
+// openTestTasks 获取实例的未处理任务，按ID排序
+func openTestTasks(t *testing.T, s *WorkflowService, instanceID uint) []models.WorkflowTask {
+	t.Helper()
+
+	var tasks []models.WorkflowTask
+	if err := s.db.Where("instance_id = ? AND status IN ?", instanceID, models.OpenTaskStatuses).Order("id").Find(&tasks).Error; err != nil {
+		t.Fatalf("查询任务失败: %v", err)
+	}
+	return tasks
+}
@@ -0,0 +1,579 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gin-web-api/database"
+	"gin-web-api/models"
+
+	"gorm.io/gorm"
+)
+
+// 事件推送的默认设置
+const (
+	webhookTimeout       = 10 * time.Second
+	webhookRetryBackoff  = time.Minute
+	webhookResponseLimit = 64 << 10 // 响应内容最多保存64KB
+	webhookBatchSize     = 50
+)
+
+// webhookHTTPClient 事件推送使用的HTTP客户端
+var webhookHTTPClient = &http.Client{Timeout: webhookTimeout}
+
+// WebhookService 事件订阅服务：管理订阅，将工作流事件推送给订阅方
+type WebhookService struct {
+	db *gorm.DB
+}
+
+// NewWebhookService 创建事件订阅服务
+func NewWebhookService() *WebhookService {
+	return &WebhookService{
+		db: database.GetDB(),
+	}
+}
+
+// CreateWebhookRequest 创建事件订阅请求
+type CreateWebhookRequest struct {
+	Name        string   `json:"name" binding:"required"`
+	URL         string   `json:"url" binding:"required"`
+	Secret      string   `json:"secret"`       // 为空时自动生成
+	Events      []string `json:"events"`       // 为空订阅全部事件
+	WorkflowID  *uint    `json:"workflow_id"`  // 为空推送全部工作流
+	MaxAttempts int      `json:"max_attempts"` // 默认6次
+}
+
+// UpdateWebhookRequest 更新事件订阅请求
+type UpdateWebhookRequest struct {
+	Name        string    `json:"name"`
+	URL         string    `json:"url"`
+	Secret      string    `json:"secret"`
+	Events      *[]string `json:"events"`
+	WorkflowID  *uint     `json:"workflow_id"`
+	MaxAttempts int       `json:"max_attempts"`
+	IsActive    *bool     `json:"is_active"`
+}
+
+// WebhookWithSecret 包含签名密钥的事件订阅，仅在创建订阅和重置密钥时返回
+type WebhookWithSecret struct {
+	models.WebhookSubscription
+	Secret string `json:"secret"`
+}
+
+// ListWebhooks 获取事件订阅列表
+func (s *WebhookService) ListWebhooks() ([]models.WebhookSubscription, error) {
+	var webhooks []models.WebhookSubscription
+	if err := s.db.Order("id").Find(&webhooks).Error; err != nil {
+		return nil, fmt.Errorf("获取事件订阅失败: %w", err)
+	}
+	return webhooks, nil
+}
+
+// GetWebhook 获取事件订阅
+func (s *WebhookService) GetWebhook(webhookID uint) (*models.WebhookSubscription, error) {
+	var webhook models.WebhookSubscription
+	if err := s.db.First(&webhook, webhookID).Error; err != nil {
+		return nil, fmt.Errorf("事件订阅不存在: %w", err)
+	}
+	return &webhook, nil
+}
+
+// CreateWebhook 创建事件订阅，返回结果中包含签名密钥，之后查询订阅不再返回密钥
+func (s *WebhookService) CreateWebhook(req *CreateWebhookRequest, userID uint) (*WebhookWithSecret, error) {
+	if err := validateWebhookURL(req.URL); err != nil {
+		return nil, err
+	}
+	events, err := encodeWebhookEvents(req.Events)
+	if err != nil {
+		return nil, err
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		secret = generateWebhookSecret()
+	}
+	webhook := &models.WebhookSubscription{
+		Name:        req.Name,
+		URL:         req.URL,
+		Secret:      secret,
+		Events:      events,
+		WorkflowID:  req.WorkflowID,
+		MaxAttempts: req.MaxAttempts,
+		IsActive:    true,
+		CreatedBy:   userID,
+	}
+	if err := s.db.Create(webhook).Error; err != nil {
+		return nil, fmt.Errorf("创建事件订阅失败: %w", err)
+	}
+	return &WebhookWithSecret{WebhookSubscription: *webhook, Secret: webhook.Secret}, nil
+}
+
+// RotateWebhookSecret 重新生成事件订阅的签名密钥，之后的推送使用新密钥签名
+func (s *WebhookService) RotateWebhookSecret(webhookID uint) (*WebhookWithSecret, error) {
+	webhook, err := s.GetWebhook(webhookID)
+	if err != nil {
+		return nil, err
+	}
+	webhook.Secret = generateWebhookSecret()
+	if err := s.db.Model(webhook).Update("secret", webhook.Secret).Error; err != nil {
+		return nil, fmt.Errorf("重置签名密钥失败: %w", err)
+	}
+	return &WebhookWithSecret{WebhookSubscription: *webhook, Secret: webhook.Secret}, nil
+}
+
+// UpdateWebhook 更新事件订阅
+func (s *WebhookService) UpdateWebhook(webhookID uint, req *UpdateWebhookRequest) (*models.WebhookSubscription, error) {
+	webhook, err := s.GetWebhook(webhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != "" {
+		webhook.Name = req.Name
+	}
+	if req.URL != "" {
+		if err := validateWebhookURL(req.URL); err != nil {
+			return nil, err
+		}
+		webhook.URL = req.URL
+	}
+	if req.Secret != "" {
+		webhook.Secret = req.Secret
+	}
+	if req.Events != nil {
+		events, err := encodeWebhookEvents(*req.Events)
+		if err != nil {
+			return nil, err
+		}
+		webhook.Events = events
+	}
+	if req.WorkflowID != nil {
+		// workflow_id 为0表示取消工作流过滤
+		if *req.WorkflowID == 0 {
+			webhook.WorkflowID = nil
+		} else {
+			webhook.WorkflowID = req.WorkflowID
+		}
+	}
+	if req.MaxAttempts > 0 {
+		webhook.MaxAttempts = req.MaxAttempts
+	}
+	if req.IsActive != nil {
+		webhook.IsActive = *req.IsActive
+	}
+
+	if err := s.db.Save(webhook).Error; err != nil {
+		return nil, fmt.Errorf("更新事件订阅失败: %w", err)
+	}
+	return webhook, nil
+}
+
+// DeleteWebhook 删除事件订阅，未完成的推送不再继续
+func (s *WebhookService) DeleteWebhook(webhookID uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&models.WebhookSubscription{}, webhookID)
+		if result.Error != nil {
+			return fmt.Errorf("删除事件订阅失败: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("事件订阅不存在")
+		}
+		return tx.Model(&models.WebhookDelivery{}).
+			Where("subscription_id = ? AND status = ?", webhookID, models.WebhookDeliveryPending).
+			Updates(map[string]interface{}{
+				"status":     models.WebhookDeliveryFailed,
+				"last_error": "事件订阅已删除",
+			}).Error
+	})
+}
+
+// ListDeliveries 获取事件订阅的推送记录，status 为空时返回全部状态
+func (s *WebhookService) ListDeliveries(webhookID uint, status string, page, pageSize int) ([]models.WebhookDelivery, int64, error) {
+	query := s.db.Model(&models.WebhookDelivery{}).Where("subscription_id = ?", webhookID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("获取推送记录失败: %w", err)
+	}
+
+	var deliveries []models.WebhookDelivery
+	if err := query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&deliveries).Error; err != nil {
+		return nil, 0, fmt.Errorf("获取推送记录失败: %w", err)
+	}
+	return deliveries, total, nil
+}
+
+// Redeliver 人工重新推送：按原推送内容创建一条新的推送记录，立即推送
+func (s *WebhookService) Redeliver(deliveryID uint) (*models.WebhookDelivery, error) {
+	var original models.WebhookDelivery
+	if err := s.db.First(&original, deliveryID).Error; err != nil {
+		return nil, fmt.Errorf("推送记录不存在: %w", err)
+	}
+	if original.Status == models.WebhookDeliveryPending || original.Status == models.WebhookDeliveryRunning {
+		return nil, errors.New("推送尚未结束，不能重新推送")
+	}
+	webhook, err := s.GetWebhook(original.SubscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery := newWebhookDelivery(webhook, original.EventID, original.EventType, original.Payload, time.Now())
+	delivery.RedeliveryOf = &original.ID
+	if err := s.db.Create(delivery).Error; err != nil {
+		return nil, fmt.Errorf("创建推送记录失败: %w", err)
+	}
+	return delivery, nil
+}
+
+// RedeliverFailed 重新推送事件订阅下全部失败的推送，返回重新推送的数量
+func (s *WebhookService) RedeliverFailed(webhookID uint) (int, error) {
+	webhook, err := s.GetWebhook(webhookID)
+	if err != nil {
+		return 0, err
+	}
+
+	var failed []models.WebhookDelivery
+	if err := s.db.Where("subscription_id = ? AND status = ?", webhookID, models.WebhookDeliveryFailed).
+		Order("id").Find(&failed).Error; err != nil {
+		return 0, fmt.Errorf("获取推送记录失败: %w", err)
+	}
+
+	count := 0
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		for _, original := range failed {
+			// 已重新推送过的记录跳过
+			var redelivered int64
+			if err := tx.Model(&models.WebhookDelivery{}).Where("redelivery_of = ?", original.ID).Count(&redelivered).Error; err != nil {
+				return err
+			}
+			if redelivered > 0 {
+				continue
+			}
+
+			delivery := newWebhookDelivery(webhook, original.EventID, original.EventType, original.Payload, now)
+			originalID := original.ID
+			delivery.RedeliveryOf = &originalID
+			if err := tx.Create(delivery).Error; err != nil {
+				return fmt.Errorf("创建推送记录失败: %w", err)
+			}
+			count++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// DispatchEvents 分发尚未分发的工作流事件：为每个匹配的订阅创建推送记录，返回分发的事件数量
+func (s *WebhookService) DispatchEvents(now time.Time) (int, error) {
+	var events []models.WorkflowEvent
+	if err := s.db.Where("dispatched_at IS NULL").Order("id").Limit(webhookBatchSize).Find(&events).Error; err != nil {
+		return 0, fmt.Errorf("查询工作流事件失败: %w", err)
+	}
+	if len(events) == 0 {
+		return 0, nil
+	}
+
+	var webhooks []models.WebhookSubscription
+	if err := s.db.Where("is_active = ?", true).Find(&webhooks).Error; err != nil {
+		return 0, fmt.Errorf("查询事件订阅失败: %w", err)
+	}
+
+	dispatched := 0
+	for _, event := range events {
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			// 条件更新认领事件，多副本部署时每个事件只分发一次
+			result := tx.Model(&models.WorkflowEvent{}).
+				Where("id = ? AND dispatched_at IS NULL", event.ID).
+				Update("dispatched_at", now)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return nil
+			}
+
+			for i := range webhooks {
+				if !webhooks[i].Matches(event.EventType, event.LineageID) {
+					continue
+				}
+				delivery := newWebhookDelivery(&webhooks[i], event.ID, event.EventType, event.Payload, now)
+				if err := tx.Create(delivery).Error; err != nil {
+					return fmt.Errorf("创建推送记录失败: %w", err)
+				}
+			}
+			dispatched++
+			return nil
+		})
+		if err != nil {
+			return dispatched, fmt.Errorf("分发工作流事件 %d 失败: %w", event.ID, err)
+		}
+	}
+	return dispatched, nil
+}
+
+// DeliverPending 推送到期的推送记录（含推送中断超过锁定期限的记录），返回推送的数量
+func (s *WebhookService) DeliverPending(now time.Time) (int, error) {
+	var deliveryIDs []uint
+	if err := s.db.Model(&models.WebhookDelivery{}).
+		Where("(status = ? AND next_run_at <= ?) OR (status = ? AND locked_until <= ?)",
+			models.WebhookDeliveryPending, now, models.WebhookDeliveryRunning, now).
+		Order("next_run_at").
+		Limit(webhookBatchSize).
+		Pluck("id", &deliveryIDs).Error; err != nil {
+		return 0, fmt.Errorf("查询推送记录失败: %w", err)
+	}
+
+	delivered := 0
+	for _, deliveryID := range deliveryIDs {
+		claimed, err := s.deliver(deliveryID, now)
+		if err != nil {
+			log.Printf("推送记录 %d 处理失败: %v", deliveryID, err)
+			continue
+		}
+		if claimed {
+			delivered++
+		}
+	}
+	return delivered, nil
+}
+
+// deliver 认领并推送一条记录；记录已被其他副本认领时返回 false
+func (s *WebhookService) deliver(deliveryID uint, now time.Time) (bool, error) {
+	result := s.db.Model(&models.WebhookDelivery{}).
+		Where("id = ? AND ((status = ? AND next_run_at <= ?) OR (status = ? AND locked_until <= ?))",
+			deliveryID, models.WebhookDeliveryPending, now, models.WebhookDeliveryRunning, now).
+		Updates(map[string]interface{}{
+			"status":       models.WebhookDeliveryRunning,
+			"locked_until": now.Add(webhookTimeout + time.Minute),
+			"attempts":     gorm.Expr("attempts + 1"),
+		})
+	if result.Error != nil {
+		return false, fmt.Errorf("认领推送记录失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+
+	var delivery models.WebhookDelivery
+	if err := s.db.First(&delivery, deliveryID).Error; err != nil {
+		return true, fmt.Errorf("推送记录不存在: %w", err)
+	}
+
+	var webhook models.WebhookSubscription
+	var statusCode int
+	var responseBody string
+	var callErr error
+	if err := s.db.First(&webhook, delivery.SubscriptionID).Error; err != nil {
+		callErr = errors.New("事件订阅已删除")
+		delivery.Attempts = delivery.MaxAttempts
+	} else {
+		statusCode, responseBody, callErr = postWebhook(&webhook, &delivery, time.Now())
+	}
+
+	delivery.ResponseStatus = statusCode
+	delivery.ResponseBody = responseBody
+	delivery.LockedUntil = nil
+	finished := time.Now()
+	switch {
+	case callErr == nil:
+		delivery.Status = models.WebhookDeliverySucceeded
+		delivery.LastError = ""
+		delivery.DeliveredAt = &finished
+	case delivery.Attempts < delivery.MaxAttempts:
+		delivery.Status = models.WebhookDeliveryPending
+		delivery.LastError = callErr.Error()
+		delivery.NextRunAt = finished.Add(webhookRetryDelay(delivery.Attempts))
+	default:
+		delivery.Status = models.WebhookDeliveryFailed
+		delivery.LastError = callErr.Error()
+	}
+	if err := s.db.Save(&delivery).Error; err != nil {
+		return true, fmt.Errorf("更新推送记录失败: %w", err)
+	}
+	return true, nil
+}
+
+// postWebhook 推送事件：请求体为事件内容，签名为 HMAC-SHA256(密钥, "时间戳.请求体") 的十六进制值
+func postWebhook(webhook *models.WebhookSubscription, delivery *models.WebhookDelivery, now time.Time) (int, string, error) {
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	body := []byte(delivery.Payload)
+
+	ctx, cancel := context.WithTimeout(context.Background(), webhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, "", fmt.Errorf("创建请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Event", delivery.EventType)
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", "sha256="+SignWebhookPayload(webhook.Secret, timestamp, body))
+
+	resp, err := webhookHTTPClient.Do(req)
+	if err != nil {
+		return 0, "", fmt.Errorf("请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, webhookResponseLimit))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, string(data), fmt.Errorf("订阅方返回状态码 %d", resp.StatusCode)
+	}
+	return resp.StatusCode, string(data), nil
+}
+
+// SignWebhookPayload 计算推送签名，订阅方按同样方式计算后与 X-Webhook-Signature 比较
+func SignWebhookPayload(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookRetryDelay 计算第N次失败后的重试间隔：首次1分钟，之后每次翻倍
+func webhookRetryDelay(attempts int) time.Duration {
+	delay := webhookRetryBackoff
+	for i := 1; i < attempts && delay < 12*time.Hour; i++ {
+		delay *= 2
+	}
+	return delay
+}
+
+// newWebhookDelivery 为订阅创建待推送记录
+func newWebhookDelivery(webhook *models.WebhookSubscription, eventID uint, eventType, payload string, now time.Time) *models.WebhookDelivery {
+	maxAttempts := webhook.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 1
+	}
+	return &models.WebhookDelivery{
+		SubscriptionID: webhook.ID,
+		EventID:        eventID,
+		EventType:      eventType,
+		Payload:        payload,
+		Status:         models.WebhookDeliveryPending,
+		MaxAttempts:    maxAttempts,
+		NextRunAt:      now,
+	}
+}
+
+// encodeWebhookEvents 校验订阅的事件类型并转换为JSON数组
+func encodeWebhookEvents(events []string) (string, error) {
+	if len(events) == 0 {
+		return "", nil
+	}
+	for _, event := range events {
+		known := false
+		for _, eventType := range models.WorkflowEvents {
+			if event == eventType {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return "", fmt.Errorf("不支持的事件类型: %s", event)
+		}
+	}
+	data, _ := json.Marshal(events)
+	return string(data), nil
+}
+
+// validateWebhookURL 校验推送地址
+func validateWebhookURL(url string) error {
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		return errors.New("推送地址必须以 http:// 或 https:// 开头")
+	}
+	return nil
+}
+
+// generateWebhookSecret 生成随机签名密钥
+func generateWebhookSecret() string {
+	buf := make([]byte, 32)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// WebhookDispatcher 事件推送后台任务：分发发件箱中的事件并推送到期的记录，
+// 事件和推送记录都通过条件更新认领，多副本部署时不会重复推送
+type WebhookDispatcher struct {
+	webhookService *WebhookService
+	interval       time.Duration
+	stop           chan struct{}
+}
+
+// NewWebhookDispatcher 创建事件推送后台任务
+func NewWebhookDispatcher(interval time.Duration) *WebhookDispatcher {
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	return &WebhookDispatcher{
+		webhookService: NewWebhookService(),
+		interval:       interval,
+		stop:           make(chan struct{}),
+	}
+}
+
+// Start 在后台启动事件推送任务
+func (w *WebhookDispatcher) Start() {
+	go func() {
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				w.RunOnce()
+			case <-w.stop:
+				return
+			}
+		}
+	}()
+	log.Printf("事件推送任务已启动，间隔 %s", w.interval)
+}
+
+// Stop 停止事件推送任务
+func (w *WebhookDispatcher) Stop() {
+	close(w.stop)
+}
+
+// RunOnce 分发新事件并推送到期的记录
+func (w *WebhookDispatcher) RunOnce() {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("事件推送任务异常: %v", r)
+		}
+	}()
+
+	now := time.Now()
+	if _, err := w.webhookService.DispatchEvents(now); err != nil {
+		log.Printf("分发工作流事件失败: %v", err)
+	}
+	delivered, err := w.webhookService.DeliverPending(now)
+	if err != nil {
+		log.Printf("推送事件失败: %v", err)
+		return
+	}
+	if delivered > 0 {
+		log.Printf("已推送 %d 条事件", delivered)
+	}
+}
@@ -0,0 +1,49 @@
+package services
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestWebhookSecretOnlyReturnedOnCreateAndRotate(t *testing.T) {
+	setupTestDB(t)
+	s := NewWebhookService()
+
+	created, err := s.CreateWebhook(&CreateWebhookRequest{Name: "ERP同步", URL: "https://erp.example.com/hooks"}, 1)
+	if err != nil {
+		t.Fatalf("CreateWebhook() error = %v", err)
+	}
+	data, _ := json.Marshal(created)
+	if created.Secret == "" || !strings.Contains(string(data), `"secret":"`+created.Secret+`"`) {
+		t.Fatalf("创建结果应包含签名密钥: %s", data)
+	}
+
+	webhook, err := s.GetWebhook(created.ID)
+	if err != nil {
+		t.Fatalf("GetWebhook() error = %v", err)
+	}
+	if webhook.Secret != created.Secret {
+		t.Errorf("保存的密钥 = %q, want %q", webhook.Secret, created.Secret)
+	}
+	list, _ := s.ListWebhooks()
+	for _, value := range []interface{}{webhook, list} {
+		if data, _ := json.Marshal(value); strings.Contains(string(data), "secret") || strings.Contains(string(data), created.Secret) {
+			t.Errorf("查询结果不应包含签名密钥: %s", data)
+		}
+	}
+
+	rotated, err := s.RotateWebhookSecret(created.ID)
+	if err != nil {
+		t.Fatalf("RotateWebhookSecret() error = %v", err)
+	}
+	if rotated.Secret == "" || rotated.Secret == created.Secret {
+		t.Fatalf("重置后的密钥 = %q", rotated.Secret)
+	}
+	if webhook, _ := s.GetWebhook(created.ID); webhook.Secret != rotated.Secret {
+		t.Errorf("保存的密钥 = %q, want %q", webhook.Secret, rotated.Secret)
+	}
+	if _, err := s.RotateWebhookSecret(999); err == nil {
+		t.Error("订阅不存在时应返回错误")
+	}
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"gin-web-api/models"
+
+	"gorm.io/gorm"
+)
+
+// WorkflowEventPayload 工作流事件内容，推送给订阅方
+type WorkflowEventPayload struct {
+	Event      string                `json:"event"`
+	OccurredAt time.Time             `json:"occurred_at"`
+	OperatorID uint                  `json:"operator_id"`
+	Instance   WorkflowEventInstance `json:"instance"`
+	Task       *WorkflowEventTask    `json:"task,omitempty"`
+}
+
+// WorkflowEventInstance 事件中的实例摘要
+type WorkflowEventInstance struct {
+	ID              uint                  `json:"id"`
+	Title           string                `json:"title"`
+	WorkflowID      uint                  `json:"workflow_id"`
+	LineageID       uint                  `json:"lineage_id"`
+	WorkflowVersion int                   `json:"workflow_version"`
+	BusinessKey     string                `json:"business_key"`
+	BusinessType    string                `json:"business_type"`
+	Status          models.InstanceStatus `json:"status"`
+	InitiatorID     uint                  `json:"initiator_id"`
+	CurrentNodes    []string              `json:"current_nodes"`
+}
+
+// WorkflowEventTask 事件中的任务摘要
+type WorkflowEventTask struct {
+	ID         uint              `json:"id"`
+	NodeKey    string            `json:"node_key"`
+	NodeName   string            `json:"node_name"`
+	AssigneeID uint              `json:"assignee_id"`
+	Status     models.TaskStatus `json:"status"`
+	Comment    string            `json:"comment"`
+	DueTime    *time.Time        `json:"due_time"`
+}
+
+// publishEventInTx 在业务事务中写入工作流事件（发件箱）并通知相关用户，事务提交后由后台任务推送给订阅方；
+// task 为空表示实例事件
+func (s *WorkflowService) publishEventInTx(tx *gorm.DB, eventType string, instance *models.WorkflowInstance, task *models.WorkflowTask, operatorID uint) error {
+	if instance == nil || instance.ID == 0 {
+		var loaded models.WorkflowInstance
+		if task == nil || tx.First(&loaded, task.InstanceID).Error != nil {
+			return errors.New("写入工作流事件失败: 实例不存在")
+		}
+		instance = &loaded
+	}
+
+	lineageID := instance.WorkflowID
+	var workflow models.WorkflowDefinition
+	if err := tx.Select("id", "lineage_id").First(&workflow, instance.WorkflowID).Error; err == nil {
+		lineageID = workflow.GetLineageID()
+	}
+
+	currentNodes := instance.GetCurrentNodes()
+	if currentNodes == nil {
+		currentNodes = []string{}
+	}
+	payload := WorkflowEventPayload{
+		Event:      eventType,
+		OccurredAt: time.Now(),
+		OperatorID: operatorID,
+		Instance: WorkflowEventInstance{
+			ID:              instance.ID,
+			Title:           instance.Title,
+			WorkflowID:      instance.WorkflowID,
+			LineageID:       lineageID,
+			WorkflowVersion: instance.WorkflowVersion,
+			BusinessKey:     instance.BusinessKey,
+			BusinessType:    instance.BusinessType,
+			Status:          instance.Status,
+			InitiatorID:     instance.InitiatorID,
+			CurrentNodes:    currentNodes,
+		},
+	}
+
+	event := &models.WorkflowEvent{
+		EventType:  eventType,
+		InstanceID: instance.ID,
+		LineageID:  lineageID,
+	}
+	if task != nil {
+		taskID := task.ID
+		event.TaskID = &taskID
+		payload.Task = &WorkflowEventTask{
+			ID:         task.ID,
+			NodeKey:    task.NodeKey,
+			NodeName:   task.NodeName,
+			AssigneeID: task.AssigneeID,
+			Status:     task.Status,
+			Comment:    task.Comment,
+			DueTime:    task.DueTime,
+		}
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("序列化工作流事件失败: %w", err)
+	}
+	event.Payload = string(data)
+	if err := tx.Create(event).Error; err != nil {
+		return fmt.Errorf("写入工作流事件失败: %w", err)
+	}
+	return s.notifyEventInTx(tx, eventType, instance, task)
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"gin-web-api/models"
+)
+
+// WorkflowExport 工作流导出数据，格式与导入接口一致
+type WorkflowExport struct {
+	Workflow CreateWorkflowWithTreeRequest `json:"workflow"`
+	Form     *CreateFormRequest            `json:"form,omitempty"`
+}
+
+// ExportWorkflow 导出工作流定义：节点树中每个节点带有完整配置（props），导出结果可直接用于导入
+func (s *WorkflowService) ExportWorkflow(workflowID uint) (*WorkflowExport, error) {
+	var workflow models.WorkflowDefinition
+	if err := s.db.Preload("Nodes.Branches").First(&workflow, workflowID).Error; err != nil {
+		return nil, fmt.Errorf("工作流不存在: %w", err)
+	}
+
+	nodeTree, err := workflow.ParseNodeTree()
+	if err != nil {
+		return nil, fmt.Errorf("解析节点树失败: %w", err)
+	}
+	if nodeTree == nil {
+		return nil, errors.New("工作流没有节点树，无法导出")
+	}
+	fillNodeTreeProps(nodeTree, workflow.Nodes)
+
+	export := &WorkflowExport{
+		Workflow: CreateWorkflowWithTreeRequest{
+			Name:        workflow.Name,
+			Description: workflow.Description,
+			Category:    workflow.Category,
+			NodeTree:    nodeTree,
+		},
+	}
+
+	if workflow.FormID != nil {
+		form, err := s.formService.ExportFormDefinition(*workflow.FormID)
+		if err != nil {
+			return nil, fmt.Errorf("导出表单失败: %w", err)
+		}
+		export.Form = form
+	}
+
+	return export, nil
+}
+
+// fillNodeTreeProps 按节点记录填充节点树中各节点和分支的配置
+func fillNodeTreeProps(nodeTree *models.NodeTreeData, nodes []models.WorkflowNode) {
+	nodeMap := make(map[string]models.WorkflowNode, len(nodes))
+	branchConditions := make(map[string]string)
+	for _, node := range nodes {
+		nodeMap[node.NodeKey] = node
+		for _, branch := range node.Branches {
+			branchConditions[branch.BranchKey] = branch.Conditions
+		}
+	}
+
+	var fill func(treeNode *models.NodeTreeData)
+	fill = func(treeNode *models.NodeTreeData) {
+		if treeNode.IsEmpty() {
+			return
+		}
+		if node, ok := nodeMap[treeNode.Key]; ok {
+			props := &models.NodeProps{
+				Assignees:        rawJSON(node.Assignees),
+				Conditions:       rawJSON(node.Conditions),
+				Settings:         rawJSON(node.Settings),
+				FieldPermissions: rawJSON(node.FieldPermissions),
+			}
+			if node.Type == models.NodeTypeApproval {
+				props.ApprovalMode = node.ApprovalMode
+			}
+			if props.ApprovalMode != "" || props.Assignees != nil || props.Conditions != nil ||
+				props.Settings != nil || props.FieldPermissions != nil {
+				treeNode.Props = props
+			}
+		}
+		fill(treeNode.Child)
+		for i := range treeNode.Branches {
+			branch := &treeNode.Branches[i]
+			if conditions := branchConditions[branch.Key]; conditions != "" {
+				branch.Props = &models.NodeProps{Conditions: rawJSON(conditions)}
+			}
+			fill(branch.Child)
+		}
+	}
+	fill(nodeTree)
+}
+
+// propsJSON 将节点配置中的JSON值转换为节点记录保存的JSON字符串，兼容以字符串形式传入的JSON
+func propsJSON(raw json.RawMessage) string {
+	if len(raw) == 0 || string(raw) == "null" {
+		return ""
+	}
+	var encoded string
+	if err := json.Unmarshal(raw, &encoded); err == nil {
+		return encoded
+	}
+	return string(raw)
+}
+
+// rawJSON 将节点记录保存的JSON字符串转换为导出的JSON值，不是合法JSON时按字符串导出
+func rawJSON(value string) json.RawMessage {
+	if value == "" {
+		return nil
+	}
+	if json.Valid([]byte(value)) {
+		return json.RawMessage(value)
+	}
+	encoded, _ := json.Marshal(value)
+	return encoded
+}
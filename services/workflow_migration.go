@@ -0,0 +1,362 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"gin-web-api/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 迁移时未处理任务的处理方式
+const (
+	MigrationTaskRemap    = "remap"    // 保留原任务，改为目标版本的节点
+	MigrationTaskRecreate = "recreate" // 取消原任务，按目标版本的节点配置重新创建
+)
+
+var (
+	errMigrationDryRun  = errors.New("试运行，不保存迁移结果")
+	errMigrationInvalid = errors.New("实例无法迁移")
+)
+
+// MigrateInstancesRequest 实例版本迁移请求
+type MigrateInstancesRequest struct {
+	FromWorkflowID uint              `json:"from_workflow_id" binding:"required"`
+	ToWorkflowID   uint              `json:"to_workflow_id" binding:"required"`
+	InstanceIDs    []uint            `json:"instance_ids"`  // 为空时迁移来源版本全部运行中的实例
+	NodeMapping    map[string]string `json:"node_mapping"`  // 旧节点标识 -> 新节点标识，未配置的节点按相同标识对应
+	TaskStrategy   string            `json:"task_strategy"` // 未处理任务的处理方式：remap（默认）或 recreate
+	DryRun         *bool             `json:"dry_run"`       // 试运行，只检查不保存；不传时默认试运行，正式迁移需传 false
+	Comment        string            `json:"comment"`
+}
+
+// isDryRun 是否试运行，未明确传 dry_run=false 时都只试运行
+func (r *MigrateInstancesRequest) isDryRun() bool {
+	return r.DryRun == nil || *r.DryRun
+}
+
+// InstanceMigrationResult 单个实例的迁移结果
+type InstanceMigrationResult struct {
+	InstanceID   uint     `json:"instance_id"`
+	Title        string   `json:"title"`
+	Migratable   bool     `json:"migratable"`
+	Migrated     bool     `json:"migrated"`
+	CurrentNodes []string `json:"current_nodes,omitempty"` // 迁移后的活动节点
+	Issues       []string `json:"issues,omitempty"`        // 无法迁移的原因
+}
+
+// InstanceMigrationReport 实例版本迁移报告
+type InstanceMigrationReport struct {
+	DryRun      bool                      `json:"dry_run"`
+	FromVersion int                       `json:"from_version"`
+	ToVersion   int                       `json:"to_version"`
+	Total       int                       `json:"total"`
+	Migratable  int                       `json:"migratable"`
+	Migrated    int                       `json:"migrated"`
+	Results     []InstanceMigrationResult `json:"results"`
+}
+
+// migrationPlan 迁移使用的来源和目标版本
+type migrationPlan struct {
+	req      *MigrateInstancesRequest
+	from     models.WorkflowDefinition
+	to       models.WorkflowDefinition
+	fromTree *models.NodeTreeData
+	toTree   *models.NodeTreeData
+}
+
+// targetKey 获取旧节点标识在目标版本中对应的节点标识
+func (p *migrationPlan) targetKey(key string) string {
+	if mapped, ok := p.req.NodeMapping[key]; ok {
+		return mapped
+	}
+	return key
+}
+
+// MigrateInstances 将运行中的实例从一个版本迁移到同一工作流的另一个版本：
+// 按节点映射改写活动节点和未处理任务，各实例独立迁移，无法迁移的实例在报告中说明原因
+func (s *WorkflowService) MigrateInstances(req *MigrateInstancesRequest, operatorID uint) (*InstanceMigrationReport, error) {
+	if req.TaskStrategy == "" {
+		req.TaskStrategy = MigrationTaskRemap
+	}
+	if req.TaskStrategy != MigrationTaskRemap && req.TaskStrategy != MigrationTaskRecreate {
+		return nil, fmt.Errorf("不支持的任务处理方式: %s", req.TaskStrategy)
+	}
+	if req.FromWorkflowID == req.ToWorkflowID {
+		return nil, errors.New("来源版本和目标版本相同")
+	}
+
+	plan := &migrationPlan{req: req}
+	if err := s.db.First(&plan.from, req.FromWorkflowID).Error; err != nil {
+		return nil, fmt.Errorf("来源版本不存在: %w", err)
+	}
+	if err := s.db.First(&plan.to, req.ToWorkflowID).Error; err != nil {
+		return nil, fmt.Errorf("目标版本不存在: %w", err)
+	}
+	if plan.from.GetLineageID() != plan.to.GetLineageID() {
+		return nil, errors.New("只能在同一工作流的版本之间迁移")
+	}
+	if !plan.to.IsPublished() || plan.to.Status != models.WorkflowStatusActive {
+		return nil, errors.New("目标版本未发布或未启用")
+	}
+
+	var err error
+	if plan.fromTree, err = plan.from.ParseNodeTree(); err != nil || plan.fromTree == nil {
+		return nil, errors.New("来源版本没有节点树，无法迁移")
+	}
+	if plan.toTree, err = plan.to.ParseNodeTree(); err != nil || plan.toTree == nil {
+		return nil, errors.New("目标版本没有节点树，无法迁移")
+	}
+	for oldKey, newKey := range req.NodeMapping {
+		if plan.toTree.FindPath(newKey) == nil {
+			return nil, fmt.Errorf("节点映射 %s -> %s 的目标节点在目标版本中不存在", oldKey, newKey)
+		}
+	}
+
+	instanceIDs := req.InstanceIDs
+	if len(instanceIDs) == 0 {
+		if err := s.db.Model(&models.WorkflowInstance{}).
+			Where("workflow_id = ? AND status = ?", plan.from.ID, models.InstanceStatusRunning).
+			Order("id").
+			Pluck("id", &instanceIDs).Error; err != nil {
+			return nil, fmt.Errorf("查询待迁移实例失败: %w", err)
+		}
+	}
+
+	report := &InstanceMigrationReport{
+		DryRun:      req.isDryRun(),
+		FromVersion: plan.from.Version,
+		ToVersion:   plan.to.Version,
+		Total:       len(instanceIDs),
+		Results:     []InstanceMigrationResult{},
+	}
+	for _, instanceID := range instanceIDs {
+		result := s.migrateInstance(plan, instanceID, operatorID)
+		if result.Migratable {
+			report.Migratable++
+		}
+		if result.Migrated {
+			report.Migrated++
+		}
+		report.Results = append(report.Results, result)
+	}
+	return report, nil
+}
+
+// migrateInstance 在独立事务中迁移单个实例，试运行时回滚事务
+func (s *WorkflowService) migrateInstance(plan *migrationPlan, instanceID, operatorID uint) InstanceMigrationResult {
+	result := InstanceMigrationResult{InstanceID: instanceID}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var instance models.WorkflowInstance
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&instance, instanceID).Error; err != nil {
+			return fmt.Errorf("实例不存在: %w", err)
+		}
+		result.Title = instance.Title
+
+		if instance.WorkflowID != plan.from.ID {
+			result.Issues = append(result.Issues, "实例不属于来源版本")
+		}
+		if instance.Status != models.InstanceStatusRunning {
+			result.Issues = append(result.Issues, "实例不在运行中")
+		}
+		if len(result.Issues) > 0 {
+			return errMigrationInvalid
+		}
+
+		var tasks []models.WorkflowTask
+		if err := tx.Where("instance_id = ? AND status IN ?", instance.ID, models.OpenTaskStatuses).Find(&tasks).Error; err != nil {
+			return err
+		}
+
+		result.Issues = s.checkInstanceMapping(plan, &instance, tasks)
+		if len(result.Issues) > 0 {
+			return errMigrationInvalid
+		}
+
+		if err := s.applyInstanceMigrationInTx(tx, plan, &instance, tasks); err != nil {
+			return err
+		}
+		result.CurrentNodes = instance.GetCurrentNodes()
+
+		if plan.req.isDryRun() {
+			return errMigrationDryRun
+		}
+		return s.recordHistoryInTx(tx, instance.ID, "", "迁移版本", operatorID,
+			fmt.Sprintf("从版本%d迁移到版本%d: %s", plan.from.Version, plan.to.Version, plan.req.Comment), "", "")
+	})
+
+	switch {
+	case err == nil:
+		result.Migratable = true
+		result.Migrated = true
+	case errors.Is(err, errMigrationDryRun):
+		result.Migratable = true
+	case errors.Is(err, errMigrationInvalid):
+	default:
+		result.Issues = append(result.Issues, err.Error())
+	}
+	return result
+}
+
+// checkInstanceMapping 检查实例的活动节点和未处理任务能否对应到目标版本的节点
+func (s *WorkflowService) checkInstanceMapping(plan *migrationPlan, instance *models.WorkflowInstance, tasks []models.WorkflowTask) []string {
+	var issues []string
+	checked := make(map[string]bool)
+	check := func(oldKey string) {
+		if checked[oldKey] {
+			return
+		}
+		checked[oldKey] = true
+
+		newKey := plan.targetKey(oldKey)
+		newPath := plan.toTree.FindPath(newKey)
+		if newPath == nil {
+			issues = append(issues, fmt.Sprintf("节点 %s 在目标版本中没有对应节点", oldKey))
+			return
+		}
+		if oldPath := plan.fromTree.FindPath(oldKey); oldPath != nil {
+			oldNode, newNode := oldPath[len(oldPath)-1], newPath[len(newPath)-1]
+			if oldNode.Type != newNode.Type {
+				issues = append(issues, fmt.Sprintf("节点 %s（%s）与目标节点 %s（%s）类型不一致", oldKey, oldNode.Type, newKey, newNode.Type))
+			}
+		}
+	}
+
+	targets := make(map[string]string)
+	for _, oldKey := range instance.GetCurrentNodes() {
+		check(oldKey)
+		newKey := plan.targetKey(oldKey)
+		if other, ok := targets[newKey]; ok {
+			issues = append(issues, fmt.Sprintf("活动节点 %s 和 %s 对应到同一目标节点 %s", other, oldKey, newKey))
+		}
+		targets[newKey] = oldKey
+	}
+	for _, task := range tasks {
+		check(task.NodeKey)
+	}
+	return issues
+}
+
+// applyInstanceMigrationInTx 将实例改到目标版本：改写活动节点、执行路径和依次审批顺序，并处理未处理任务
+func (s *WorkflowService) applyInstanceMigrationInTx(tx *gorm.DB, plan *migrationPlan, instance *models.WorkflowInstance, tasks []models.WorkflowTask) error {
+	oldNodes := instance.GetCurrentNodes()
+
+	instance.WorkflowID = plan.to.ID
+	instance.WorkflowVersion = plan.to.Version
+	remapExecutionPath(instance, plan.req.NodeMapping)
+	remapApprovalChains(instance, plan.req.NodeMapping)
+
+	var recreate []*models.NodeTreeData
+	newNodes := make([]string, 0, len(oldNodes))
+	for _, oldKey := range oldNodes {
+		newKey := plan.targetKey(oldKey)
+		path := plan.toTree.FindPath(newKey)
+		node := path[len(path)-1]
+		if plan.req.TaskStrategy == MigrationTaskRecreate && node.Type == models.NodeTypeApproval {
+			recreate = append(recreate, node)
+			continue
+		}
+		newNodes = append(newNodes, newKey)
+	}
+	instance.SetCurrentNodes(newNodes)
+
+	// 已处理的任务保留原节点标识作为历史记录。依次审批按节点统计本轮已通过的人数，
+	// 节点标识改变时去掉审批顺序中已通过的审批人，新节点从当前审批人继续
+	if plan.req.TaskStrategy == MigrationTaskRemap {
+		for _, oldKey := range oldNodes {
+			newKey := plan.targetKey(oldKey)
+			chain := instance.GetApprovalChain(newKey)
+			if newKey == oldKey || len(chain) == 0 {
+				continue
+			}
+			query := tx.Model(&models.WorkflowTask{}).
+				Where("instance_id = ? AND node_key = ? AND status = ? AND parent_task_id IS NULL", instance.ID, oldKey, models.TaskStatusApproved)
+			if enteredAt := s.nodeEnteredAt(instance, newKey); !enteredAt.IsZero() {
+				query = query.Where("created_at >= ?", enteredAt.Truncate(time.Microsecond))
+			}
+			var approved int64
+			if err := query.Count(&approved).Error; err != nil {
+				return fmt.Errorf("统计已通过的审批人失败: %w", err)
+			}
+			if int(approved) < len(chain) {
+				instance.SetApprovalChain(newKey, chain[approved:])
+			}
+		}
+	}
+
+	for _, task := range tasks {
+		newKey := plan.targetKey(task.NodeKey)
+		path := plan.toTree.FindPath(newKey)
+		node := path[len(path)-1]
+
+		updates := map[string]interface{}{"node_key": newKey, "node_name": node.Name}
+		if plan.req.TaskStrategy == MigrationTaskRecreate && node.Type == models.NodeTypeApproval {
+			updates = map[string]interface{}{"status": models.TaskStatusCancelled}
+		}
+		if err := tx.Model(&models.WorkflowTask{}).Where("id = ?", task.ID).Updates(updates).Error; err != nil {
+			return fmt.Errorf("迁移任务失败: %w", err)
+		}
+	}
+
+	// 停留在服务节点的调用任务改为新节点标识，调用完成后按目标版本继续流转
+	for _, oldKey := range oldNodes {
+		if newKey := plan.targetKey(oldKey); newKey != oldKey {
+			if err := tx.Model(&models.WorkflowServiceJob{}).
+				Where("instance_id = ? AND node_key = ?", instance.ID, oldKey).
+				Update("node_key", newKey).Error; err != nil {
+				return fmt.Errorf("迁移服务调用任务失败: %w", err)
+			}
+		}
+	}
+
+	if err := tx.Save(instance).Error; err != nil {
+		return fmt.Errorf("更新实例失败: %w", err)
+	}
+
+	// 按目标版本的节点配置重新进入节点，重新解析审批人并创建任务
+	for _, node := range recreate {
+		if err := s.executeNodeTree(tx, instance, plan.toTree, node); err != nil {
+			return fmt.Errorf("重新创建节点 %s 的任务失败: %w", node.Key, err)
+		}
+	}
+	return nil
+}
+
+// remapExecutionPath 按节点映射改写执行路径中的节点标识，使退回、汇聚等逻辑能在目标版本中找到对应节点
+func remapExecutionPath(instance *models.WorkflowInstance, mapping map[string]string) {
+	if len(mapping) == 0 || instance.ExecutionPath == "" {
+		return
+	}
+	steps := instance.GetExecutionPath()
+	for i := range steps {
+		if newKey, ok := mapping[steps[i].NodeKey]; ok {
+			steps[i].NodeKey = newKey
+		}
+		if newKey, ok := mapping[steps[i].BranchKey]; ok {
+			steps[i].BranchKey = newKey
+		}
+	}
+	data, _ := json.Marshal(steps)
+	instance.ExecutionPath = string(data)
+}
+
+// remapApprovalChains 按节点映射改写依次审批顺序的节点标识
+func remapApprovalChains(instance *models.WorkflowInstance, mapping map[string]string) {
+	if len(mapping) == 0 || instance.ApprovalChains == "" {
+		return
+	}
+	chains := make(map[string][]uint)
+	for key, assignees := range instance.GetApprovalChains() {
+		if newKey, ok := mapping[key]; ok {
+			key = newKey
+		}
+		chains[key] = assignees
+	}
+	data, _ := json.Marshal(chains)
+	instance.ApprovalChains = string(data)
+}
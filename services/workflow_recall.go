@@ -0,0 +1,174 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gin-web-api/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RecallInstance 发起人撤回运行中的实例：取消未处理任务，表单数据回到草稿，实例回到草稿状态等待重新提交
+func (s *WorkflowService) RecallInstance(instanceID, userID uint, reason string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var instance models.WorkflowInstance
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&instance, instanceID).Error; err != nil {
+			return fmt.Errorf("实例不存在: %w", err)
+		}
+		if instance.InitiatorID != userID {
+			return errors.New("只有发起人可以撤回")
+		}
+		if instance.Status != models.InstanceStatusRunning {
+			return errors.New("只能撤回运行中的实例")
+		}
+
+		if err := s.checkRecallAllowedInTx(tx, &instance); err != nil {
+			return err
+		}
+
+		if err := s.cancelPendingTasksInTx(tx, instance.ID); err != nil {
+			return err
+		}
+		if err := s.updateInstanceFormStatusInTx(tx, &instance, models.FormStatusDraft); err != nil {
+			return err
+		}
+
+		instance.Status = models.InstanceStatusDraft
+		instance.SetCurrentNodes(nil)
+		if err := tx.Save(&instance).Error; err != nil {
+			return fmt.Errorf("撤回实例失败: %w", err)
+		}
+
+		if err := s.recordHistoryInTx(tx, instance.ID, "", "撤回", userID, reason, "", ""); err != nil {
+			return err
+		}
+		return s.publishEventInTx(tx, models.EventInstanceRecalled, &instance, nil, userID)
+	})
+}
+
+// CancelInstance 取消运行中的实例：取消未处理的任务和服务调用，实例结束
+func (s *WorkflowService) CancelInstance(instanceID, operatorID uint, reason string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var instance models.WorkflowInstance
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&instance, instanceID).Error; err != nil {
+			return fmt.Errorf("实例不存在: %w", err)
+		}
+		if instance.Status != models.InstanceStatusRunning {
+			return errors.New("只能取消运行中的实例")
+		}
+
+		if err := s.cancelPendingTasksInTx(tx, instance.ID); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		instance.Status = models.InstanceStatusCancelled
+		instance.SetCurrentNodes(nil)
+		instance.EndTime = &now
+		if err := tx.Save(&instance).Error; err != nil {
+			return fmt.Errorf("取消实例失败: %w", err)
+		}
+
+		if err := s.recordHistoryInTx(tx, instance.ID, "", "取消", operatorID, reason, "", ""); err != nil {
+			return err
+		}
+		return s.publishEventInTx(tx, models.EventInstanceCancelled, &instance, nil, operatorID)
+	})
+}
+
+// ResubmitInstance 发起人修改表单后重新提交已撤回的实例，从开始节点重新执行，保留原有历史记录；
+// 提交 formValues 时 formVersion 为读取表单时的版本，缺少时返回 ErrFormVersionRequired，表单数据已被修改时返回 ErrFormDataConflict
+func (s *WorkflowService) ResubmitInstance(instanceID, userID uint, formValues string, formVersion int, comment string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var instance models.WorkflowInstance
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&instance, instanceID).Error; err != nil {
+			return fmt.Errorf("实例不存在: %w", err)
+		}
+		if instance.InitiatorID != userID {
+			return errors.New("只有发起人可以重新提交")
+		}
+		if instance.Status != models.InstanceStatusDraft {
+			return errors.New("只能重新提交已撤回的实例")
+		}
+
+		if formValues != "" {
+			if err := s.updateInstanceFormValuesInTx(tx, &instance, formValues, formVersion, formChangeSource{UserID: userID}); err != nil {
+				return err
+			}
+		}
+		if err := s.updateInstanceFormStatusInTx(tx, &instance, models.FormStatusSubmitted); err != nil {
+			return err
+		}
+
+		var workflow models.WorkflowDefinition
+		if err := tx.Preload("Nodes").First(&workflow, instance.WorkflowID).Error; err != nil {
+			return fmt.Errorf("工作流定义不存在: %w", err)
+		}
+
+		instance.Status = models.InstanceStatusRunning
+		instance.SetCurrentNodes(nil)
+		if err := s.publishEventInTx(tx, models.EventInstanceStarted, &instance, nil, userID); err != nil {
+			return err
+		}
+		if err := s.executeWorkflowWithTree(tx, &instance, workflow); err != nil {
+			return fmt.Errorf("重新提交失败: %w", err)
+		}
+		if err := tx.Save(&instance).Error; err != nil {
+			return fmt.Errorf("更新实例失败: %w", err)
+		}
+
+		return s.recordHistoryInTx(tx, instance.ID, "", "重新提交", userID, comment, formValues, "")
+	})
+}
+
+// checkRecallAllowedInTx 按当前活动节点的撤回规则判断是否允许撤回
+func (s *WorkflowService) checkRecallAllowedInTx(tx *gorm.DB, instance *models.WorkflowInstance) error {
+	for _, nodeKey := range instance.GetCurrentNodes() {
+		settings, err := s.loadNodeSettings(tx, instance.WorkflowID, nodeKey)
+		if err != nil {
+			return err
+		}
+
+		switch settings.RecallRule {
+		case models.RecallRuleAlways:
+			continue
+		case models.RecallRuleNever:
+			return errors.New("当前节点不允许撤回")
+		}
+
+		// 默认规则：当前节点本轮没有审批人处理过（审批、拒绝或加签）才可撤回
+		query := tx.Model(&models.WorkflowTask{}).
+			Where("instance_id = ? AND node_key = ? AND status NOT IN ?", instance.ID, nodeKey,
+				[]models.TaskStatus{models.TaskStatusPending, models.TaskStatusClaimed, models.TaskStatusCancelled})
+		if enteredAt := s.nodeEnteredAt(instance, nodeKey); !enteredAt.IsZero() {
+			query = query.Where("created_at >= ?", enteredAt.Truncate(time.Microsecond))
+		}
+		var acted int64
+		if err := query.Count(&acted).Error; err != nil {
+			return err
+		}
+		if acted > 0 {
+			return errors.New("当前节点已有审批人处理，无法撤回")
+		}
+	}
+	return nil
+}
+
+// updateInstanceFormStatusInTx 更新实例关联表单数据的状态
+func (s *WorkflowService) updateInstanceFormStatusInTx(tx *gorm.DB, instance *models.WorkflowInstance, status string) error {
+	if instance.FormDataID == nil {
+		return nil
+	}
+
+	updates := map[string]interface{}{"status": status}
+	if status == models.FormStatusSubmitted {
+		updates["submitted_at"] = time.Now()
+	}
+	if err := tx.Model(&models.FormData{}).Where("id = ?", *instance.FormDataID).Updates(updates).Error; err != nil {
+		return fmt.Errorf("更新表单数据状态失败: %w", err)
+	}
+	return nil
+}
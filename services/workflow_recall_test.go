@@ -0,0 +1,98 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"gin-web-api/models"
+)
+
+// recallTestTree 开始 -> 审批节点 a1 -> 结束
+const recallTestTree = `{"key":"root","name":"发起","type":"ROOT",
+	"child":{"key":"a1","name":"审批","type":"approval","props":{"assignees":{"type":"users","user_ids":[2]}},
+		"child":{"key":"end","name":"结束","type":"END"}}}`
+
+// startRecalledTestInstance 发起带表单的流程后由发起人撤回，返回实例
+func startRecalledTestInstance(t *testing.T, s *WorkflowService) *models.WorkflowInstance {
+	t.Helper()
+
+	form, err := NewFormService().CreateFormDefinition(&CreateFormRequest{Object: "t", Name: "报销单", Key: "expense", Cards: []CreateCardRequest{{
+		Name: "基本信息",
+		Attributes: []CreateAttributeRequest{{
+			Attribute: CreateFieldAttrRequest{Object: "t", Name: "金额", Key: "amount", Type: "NUMERIC", Element: "number"},
+			Element:   "number", Name: "金额", Show: true, Required: true,
+		}},
+	}}}, 1)
+	if err != nil {
+		t.Fatalf("创建表单失败: %v", err)
+	}
+	workflow := createTestWorkflow(t, s, recallTestTree)
+	if err := s.db.Model(workflow).Update("form_id", form.ID).Error; err != nil {
+		t.Fatalf("关联表单失败: %v", err)
+	}
+
+	instance, err := s.StartWorkflowWithForm(&StartWorkflowWithFormRequest{
+		WorkflowID: workflow.ID, Title: "报销申请", FormValues: `{"amount":1000}`,
+	}, 1)
+	if err != nil {
+		t.Fatalf("发起流程失败: %v", err)
+	}
+	if err := s.RecallInstance(instance.ID, 1, "金额填写错误"); err != nil {
+		t.Fatalf("撤回失败: %v", err)
+	}
+	return instance
+}
+
+func TestResubmitInstanceWithFormValues(t *testing.T) {
+	tests := []struct {
+		name        string
+		formVersion int
+		wantErr     error
+		wantAmount  float64
+	}{
+		{name: "携带当前版本", formVersion: 1, wantAmount: 1200},
+		{name: "缺少版本", formVersion: 0, wantErr: ErrFormVersionRequired, wantAmount: 1000},
+		{name: "版本已过期", formVersion: 2, wantErr: ErrFormDataConflict, wantAmount: 1000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTestDB(t)
+			s := NewWorkflowService()
+			instance := startRecalledTestInstance(t, s)
+
+			err := s.ResubmitInstance(instance.ID, 1, `{"amount":1200}`, tt.formVersion, "已更正金额")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ResubmitInstance() error = %v, want %v", err, tt.wantErr)
+			}
+
+			var formData models.FormData
+			if err := s.db.First(&formData, *instance.FormDataID).Error; err != nil {
+				t.Fatalf("查询表单数据失败: %v", err)
+			}
+			var values map[string]interface{}
+			if err := json.Unmarshal([]byte(formData.FormValues), &values); err != nil {
+				t.Fatalf("表单数据格式错误: %v", err)
+			}
+			if values["amount"] != tt.wantAmount {
+				t.Errorf("amount = %v, want %v", values["amount"], tt.wantAmount)
+			}
+
+			var reloaded models.WorkflowInstance
+			if err := s.db.First(&reloaded, instance.ID).Error; err != nil {
+				t.Fatalf("查询实例失败: %v", err)
+			}
+			wantStatus := models.InstanceStatusDraft
+			if tt.wantErr == nil {
+				wantStatus = models.InstanceStatusRunning
+			}
+			if reloaded.Status != wantStatus {
+				t.Errorf("实例状态 = %v, want %v", reloaded.Status, wantStatus)
+			}
+			if tasks := openTestTasks(t, s, instance.ID); (len(tasks) == 1) != (tt.wantErr == nil) {
+				t.Errorf("未处理任务数 = %d", len(tasks))
+			}
+		})
+	}
+}
@@ -0,0 +1,333 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gin-web-api/models"
+
+	"gorm.io/gorm"
+)
+
+// handleRejectInTx 根据节点的拒绝设置处理拒绝：终止流程、退回上一审批节点、退回指定节点或退回发起人
+func (s *WorkflowService) handleRejectInTx(tx *gorm.DB, task *models.WorkflowTask, operatorID uint, comment, targetNodeKey string) error {
+	instance := &task.Instance
+
+	var workflow models.WorkflowDefinition
+	if err := tx.First(&workflow, instance.WorkflowID).Error; err != nil {
+		return err
+	}
+	nodeTree, err := workflow.ParseNodeTree()
+	if err != nil || nodeTree == nil {
+		// 传统节点不支持退回，直接拒绝整个工作流实例
+		return s.completeWorkflowInTx(tx, instance, models.InstanceStatusRejected)
+	}
+
+	settings, err := s.loadNodeSettings(tx, instance.WorkflowID, task.NodeKey)
+	if err != nil {
+		return err
+	}
+
+	switch settings.RejectAction {
+	case models.RejectActionPrevious:
+		previousKey := s.findPreviousApprovalNode(instance, nodeTree, task.NodeKey)
+		if previousKey == "" {
+			// 没有上一审批节点，退回发起人
+			return s.returnToInitiatorInTx(tx, instance, nodeTree, task.NodeKey, operatorID, comment)
+		}
+		return s.rewindToNodeInTx(tx, instance, nodeTree, task.NodeKey, previousKey, operatorID, comment)
+
+	case models.RejectActionNode:
+		if targetNodeKey == "" {
+			targetNodeKey = settings.RejectTarget
+		}
+		if targetNodeKey == "" {
+			return errors.New("请选择退回的节点")
+		}
+		if targetNodeKey == nodeTree.Key {
+			return s.returnToInitiatorInTx(tx, instance, nodeTree, task.NodeKey, operatorID, comment)
+		}
+		return s.rewindToNodeInTx(tx, instance, nodeTree, task.NodeKey, targetNodeKey, operatorID, comment)
+
+	case models.RejectActionInitiator:
+		return s.returnToInitiatorInTx(tx, instance, nodeTree, task.NodeKey, operatorID, comment)
+	}
+
+	// 默认终止流程
+	return s.completeWorkflowInTx(tx, instance, models.InstanceStatusRejected)
+}
+
+// rewindToNodeInTx 退回到执行路径上已经过的审批节点：取消退回节点与目标节点之间的未处理任务并在目标节点重新创建任务，
+// 并行节点中其他分支的任务不受影响
+func (s *WorkflowService) rewindToNodeInTx(tx *gorm.DB, instance *models.WorkflowInstance, root *models.NodeTreeData, rejectNodeKey, targetNodeKey string, operatorID uint, comment string) error {
+	if !s.isOnExecutionPath(instance, targetNodeKey) {
+		return fmt.Errorf("节点 %s 不在已执行的路径上，无法退回", targetNodeKey)
+	}
+
+	target := root.FindNode(targetNodeKey)
+	if target == nil {
+		return fmt.Errorf("节点树中找不到节点: %s", targetNodeKey)
+	}
+	if target.Type != models.NodeTypeApproval {
+		return errors.New("只能退回到审批节点")
+	}
+	if inParallelSiblingBranches(root, rejectNodeKey, targetNodeKey) {
+		return errors.New("不能退回到其他并行分支中的节点")
+	}
+
+	if err := s.closeRewoundNodesInTx(tx, instance, root, rejectNodeKey, targetNodeKey); err != nil {
+		return err
+	}
+	reopenParallelBranches(instance, root, rejectNodeKey, targetNodeKey)
+	instance.AppendExecutionStep(models.ExecutionStep{
+		NodeKey:  target.Key,
+		NodeName: target.Name,
+		NodeType: target.Type,
+		Action:   models.ExecutionActionJump,
+	})
+	s.recordHistoryInTx(tx, instance.ID, target.Key, "退回", operatorID, fmt.Sprintf("退回至节点[%s]: %s", target.Name, comment), "", "")
+
+	if err := s.executeNodeTree(tx, instance, root, target); err != nil {
+		return err
+	}
+	return tx.Save(instance).Error
+}
+
+// returnToInitiatorInTx 退回发起人：关闭退回节点所在的全部并行节点，在开始节点为发起人创建重新提交的任务
+func (s *WorkflowService) returnToInitiatorInTx(tx *gorm.DB, instance *models.WorkflowInstance, root *models.NodeTreeData, rejectNodeKey string, operatorID uint, comment string) error {
+	if err := s.closeRewoundNodesInTx(tx, instance, root, rejectNodeKey, root.Key); err != nil {
+		return err
+	}
+
+	var startNode models.WorkflowNode
+	if err := tx.Where("workflow_id = ? AND node_key = ?", instance.WorkflowID, root.Key).First(&startNode).Error; err != nil {
+		return fmt.Errorf("找不到开始节点配置: %w", err)
+	}
+
+	instance.AppendExecutionStep(models.ExecutionStep{
+		NodeKey:  root.Key,
+		NodeName: root.Name,
+		NodeType: root.Type,
+		Action:   models.ExecutionActionJump,
+	})
+	s.recordHistoryInTx(tx, instance.ID, root.Key, "退回发起人", operatorID, comment, "", "")
+
+	if err := s.createSingleTaskInTx(tx, instance, startNode, instance.InitiatorID); err != nil {
+		return fmt.Errorf("创建重新提交任务失败: %w", err)
+	}
+	instance.AddCurrentNode(root.Key)
+	return tx.Save(instance).Error
+}
+
+// GetRejectTargets 获取任务可退回的节点（执行路径上已经过的审批节点，不含其他并行分支中的节点）
+func (s *WorkflowService) GetRejectTargets(taskID uint) ([]models.ExecutionStep, error) {
+	var task models.WorkflowTask
+	if err := s.db.Preload("Instance").First(&task, taskID).Error; err != nil {
+		return nil, fmt.Errorf("任务不存在: %w", err)
+	}
+
+	var workflow models.WorkflowDefinition
+	if err := s.db.First(&workflow, task.Instance.WorkflowID).Error; err != nil {
+		return nil, fmt.Errorf("工作流不存在: %w", err)
+	}
+	nodeTree, _ := workflow.ParseNodeTree()
+
+	targets := make([]models.ExecutionStep, 0)
+	seen := map[string]bool{task.NodeKey: true}
+	for _, step := range task.Instance.GetExecutionPath() {
+		if step.Action != models.ExecutionActionEnter || seen[step.NodeKey] {
+			continue
+		}
+		if nodeTree != nil && inParallelSiblingBranches(nodeTree, task.NodeKey, step.NodeKey) {
+			continue
+		}
+		if step.NodeType == models.NodeTypeApproval || step.NodeType == models.NodeTypeRoot || step.NodeType == models.NodeTypeStart {
+			seen[step.NodeKey] = true
+			targets = append(targets, step)
+		}
+	}
+	return targets, nil
+}
+
+// findPreviousApprovalNode 在执行路径上查找当前节点之前的上一个审批节点（跳过其他并行分支中的节点）
+func (s *WorkflowService) findPreviousApprovalNode(instance *models.WorkflowInstance, root *models.NodeTreeData, nodeKey string) string {
+	steps := instance.GetExecutionPath()
+	found := false
+	for i := len(steps) - 1; i >= 0; i-- {
+		step := steps[i]
+		if step.Action != models.ExecutionActionEnter {
+			continue
+		}
+		if !found {
+			found = step.NodeKey == nodeKey
+			continue
+		}
+		if step.NodeType == models.NodeTypeApproval && step.NodeKey != nodeKey && !inParallelSiblingBranches(root, nodeKey, step.NodeKey) {
+			return step.NodeKey
+		}
+	}
+	return ""
+}
+
+// isOnExecutionPath 判断节点是否已在执行路径上经过
+func (s *WorkflowService) isOnExecutionPath(instance *models.WorkflowInstance, nodeKey string) bool {
+	for _, step := range instance.GetExecutionPath() {
+		if step.NodeKey == nodeKey && step.Action == models.ExecutionActionEnter {
+			return true
+		}
+	}
+	return false
+}
+
+// nodeEnteredAt 获取节点最近一次进入的时间
+func (s *WorkflowService) nodeEnteredAt(instance *models.WorkflowInstance, nodeKey string) time.Time {
+	steps := instance.GetExecutionPath()
+	for i := len(steps) - 1; i >= 0; i-- {
+		step := steps[i]
+		if step.NodeKey == nodeKey && (step.Action == models.ExecutionActionEnter || step.Action == models.ExecutionActionJump) {
+			return step.Time
+		}
+	}
+	return time.Time{}
+}
+
+// isStartNodeTask 判断是否为退回发起人后的重新提交任务
+func (s *WorkflowService) isStartNodeTask(tx *gorm.DB, task *models.WorkflowTask) bool {
+	var node models.WorkflowNode
+	if err := tx.Where("workflow_id = ? AND node_key = ?", task.Instance.WorkflowID, task.NodeKey).First(&node).Error; err != nil {
+		return false
+	}
+	return node.Type == models.NodeTypeRoot || node.Type == models.NodeTypeStart
+}
+
+// closeRewoundNodesInTx 取消退回影响范围内的未处理任务并移出当前节点
+func (s *WorkflowService) closeRewoundNodesInTx(tx *gorm.DB, instance *models.WorkflowInstance, root *models.NodeTreeData, rejectNodeKey, targetNodeKey string) error {
+	nodeKeys := rewoundNodeKeys(root, rejectNodeKey, targetNodeKey)
+	if err := tx.Model(&models.WorkflowTask{}).
+		Where("instance_id = ? AND node_key IN ? AND status IN ?", instance.ID, nodeKeys, models.OpenTaskStatuses).
+		Update("status", models.TaskStatusCancelled).Error; err != nil {
+		return fmt.Errorf("取消未处理任务失败: %w", err)
+	}
+	if err := tx.Model(&models.WorkflowServiceJob{}).
+		Where("instance_id = ? AND node_key IN ? AND status = ?", instance.ID, nodeKeys, models.ServiceJobStatusPending).
+		Update("status", models.ServiceJobStatusCancelled).Error; err != nil {
+		return fmt.Errorf("取消服务调用失败: %w", err)
+	}
+	instance.RemoveCurrentNodes(nodeKeys...)
+	return nil
+}
+
+// rewoundNodeKeys 退回影响的节点：退回节点本身，以及包含退回节点但不包含目标节点的并行节点
+// （目标节点在其分支之外，整个并行节点随之关闭）中的全部节点
+func rewoundNodeKeys(root *models.NodeTreeData, rejectNodeKey, targetNodeKey string) []string {
+	nodeKeys := []string{rejectNodeKey}
+	path := root.FindPath(rejectNodeKey)
+	for i := len(path) - 2; i >= 0; i-- {
+		gateway := path[i]
+		if gateway.Type != models.NodeTypeParallel || gateway.Child == path[i+1] {
+			continue
+		}
+		if path[i+1].FindPath(targetNodeKey) != nil {
+			// 目标节点在同一分支中，外层的并行节点不受影响
+			break
+		}
+		nodeKeys = parallelBlockKeys(gateway)
+	}
+	return nodeKeys
+}
+
+// parallelBlockKeys 获取并行节点、其各分支以及合并节点的标识
+func parallelBlockKeys(gateway *models.NodeTreeData) []string {
+	nodeKeys := []string{gateway.Key}
+	for i := range gateway.Branches {
+		nodeKeys = append(nodeKeys, gateway.Branches[i].Keys()...)
+	}
+	if merge := gateway.Child; !merge.IsEmpty() && merge.Type == models.NodeTypeMerge {
+		nodeKeys = append(nodeKeys, merge.Key)
+	}
+	return nodeKeys
+}
+
+// reopenParallelBranches 目标节点位于已汇聚的并行分支中时重新分叉，
+// 并视其余分支为已到达，使重新执行的分支到达后即可汇聚
+func reopenParallelBranches(instance *models.WorkflowInstance, root *models.NodeTreeData, rejectNodeKey, targetNodeKey string) {
+	path := root.FindPath(targetNodeKey)
+	for i := 0; i < len(path)-1; i++ {
+		gateway := path[i]
+		if gateway.Type != models.NodeTypeParallel || gateway.Child == path[i+1] || path[i+1].FindPath(rejectNodeKey) != nil {
+			// 退回节点与目标节点在同一分支中，分叉仍未汇聚
+			continue
+		}
+		instance.AppendExecutionStep(models.ExecutionStep{
+			NodeKey:  gateway.Key,
+			NodeName: gateway.Name,
+			NodeType: gateway.Type,
+			Action:   models.ExecutionActionFork,
+		})
+		for j := range gateway.Branches {
+			if &gateway.Branches[j] == path[i+1] {
+				continue
+			}
+			instance.AppendExecutionStep(models.ExecutionStep{
+				NodeKey:   gateway.Key,
+				NodeName:  gateway.Name,
+				NodeType:  gateway.Type,
+				Action:    models.ExecutionActionArrive,
+				BranchKey: gateway.Branches[j].Key,
+			})
+		}
+	}
+}
+
+// inParallelSiblingBranches 判断两个节点是否位于同一并行节点的不同分支中
+func inParallelSiblingBranches(root *models.NodeTreeData, nodeKey, otherKey string) bool {
+	path, other := root.FindPath(nodeKey), root.FindPath(otherKey)
+	for i := 0; i < len(path)-1 && i < len(other)-1 && path[i] == other[i]; i++ {
+		if path[i].Type == models.NodeTypeParallel && path[i+1] != other[i+1] &&
+			path[i+1] != path[i].Child && other[i+1] != other[i].Child {
+			return true
+		}
+	}
+	return false
+}
+
+// updateInstanceFormValuesInTx 验证并替换实例关联的表单数据，记录变化的字段；
+// formVersion 为读取表单时的版本，必须提供，表单数据已被他人修改时返回 ErrFormDataConflict
+func (s *WorkflowService) updateInstanceFormValuesInTx(tx *gorm.DB, instance *models.WorkflowInstance, formValues string, formVersion int, source formChangeSource) error {
+	if instance.FormDataID == nil {
+		return nil
+	}
+
+	var formData models.FormData
+	if err := tx.First(&formData, *instance.FormDataID).Error; err != nil {
+		return fmt.Errorf("表单数据不存在: %w", err)
+	}
+	if formVersion == 0 {
+		return ErrFormVersionRequired
+	}
+	if formVersion != formData.Version {
+		return ErrFormDataConflict
+	}
+	formValues, err := s.formService.validateFormData(newFieldOptionChecker(tx, false), formData.FormID, formValues)
+	if err != nil {
+		return fmt.Errorf("表单数据验证失败: %w", err)
+	}
+
+	return saveFormValuesInTx(tx, &formData, formValues, source)
+}
+
+// cancelPendingTasksInTx 取消实例的所有未处理任务（含等待加签的任务和等待执行的服务调用）
+func (s *WorkflowService) cancelPendingTasksInTx(tx *gorm.DB, instanceID uint) error {
+	if err := tx.Model(&models.WorkflowTask{}).
+		Where("instance_id = ? AND status IN ?", instanceID, models.OpenTaskStatuses).
+		Update("status", models.TaskStatusCancelled).Error; err != nil {
+		return fmt.Errorf("取消未处理任务失败: %w", err)
+	}
+	if err := tx.Model(&models.WorkflowServiceJob{}).
+		Where("instance_id = ? AND status = ?", instanceID, models.ServiceJobStatusPending).
+		Update("status", models.ServiceJobStatusCancelled).Error; err != nil {
+		return fmt.Errorf("取消服务调用失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	redisClient "gin-web-api/redis"
+)
+
+// schedulerLockKey 定时任务的分布式锁，保证多副本部署时每轮只有一个副本执行
+const schedulerLockKey = "workflow:scheduler:lock"
+
+// WorkflowScheduler 工作流后台定时任务：处理超时提醒、升级和自动审批
+type WorkflowScheduler struct {
+	workflowService *WorkflowService
+	interval        time.Duration
+	instanceID      string
+	stop            chan struct{}
+}
+
+// NewWorkflowScheduler 创建工作流定时任务
+func NewWorkflowScheduler(interval time.Duration) *WorkflowScheduler {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	hostname, _ := os.Hostname()
+	return &WorkflowScheduler{
+		workflowService: NewWorkflowService(),
+		interval:        interval,
+		instanceID:      fmt.Sprintf("%s-%d-%d", hostname, os.Getpid(), time.Now().UnixNano()),
+		stop:            make(chan struct{}),
+	}
+}
+
+// Start 在后台启动定时任务
+func (s *WorkflowScheduler) Start() {
+	go func() {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.RunOnce()
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+	log.Printf("工作流定时任务已启动，间隔 %s", s.interval)
+}
+
+// Stop 停止定时任务
+func (s *WorkflowScheduler) Stop() {
+	close(s.stop)
+}
+
+// RunOnce 执行一轮定时任务，未获取到分布式锁时跳过
+func (s *WorkflowScheduler) RunOnce() {
+	if redisClient.GetClient() != nil {
+		acquired, err := redisClient.SetNX(schedulerLockKey, s.instanceID, s.interval)
+		if err != nil {
+			log.Printf("获取定时任务锁失败: %v", err)
+			return
+		}
+		if !acquired {
+			return
+		}
+		defer redisClient.ReleaseLock(schedulerLockKey, s.instanceID)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("工作流定时任务异常: %v", r)
+		}
+	}()
+
+	processed, err := s.workflowService.ProcessOverdueTasks(time.Now())
+	if err != nil {
+		log.Printf("处理超时任务失败: %v", err)
+		return
+	}
+	if processed > 0 {
+		log.Printf("已处理 %d 个超时任务", processed)
+	}
+}
@@ -10,11 +10,13 @@ import (
 	"gin-web-api/models"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type WorkflowService struct {
 	db          *gorm.DB
 	formService *FormService
+	simulation  *workflowSimulation // 流程模拟时记录分支决策，正常运行时为nil
 }
 
 func NewWorkflowService() *WorkflowService {
@@ -62,6 +64,9 @@ func (s *WorkflowService) CreateWorkflowWithNodeTree(req *CreateWorkflowWithTree
 			workflow.RootNodeID = &rootNode.ID
 		}
 
+		// 首个版本作为版本系列的标识
+		workflow.LineageID = workflow.ID
+
 		// 保存工作流
 		if err := tx.Save(workflow).Error; err != nil {
 			return nil, fmt.Errorf("保存工作流失败: %w", err)
@@ -85,12 +90,23 @@ func (s *WorkflowService) createNodesFromTree(tx *gorm.DB, workflowID uint, node
 		node.ParentNodeID = &parentNode.ID
 	}
 
+	// 节点配置
+	if props := nodeTree.Props; props != nil {
+		if props.ApprovalMode != "" {
+			node.ApprovalMode = props.ApprovalMode
+		}
+		node.Assignees = propsJSON(props.Assignees)
+		node.Conditions = propsJSON(props.Conditions)
+		node.Settings = propsJSON(props.Settings)
+		node.FieldPermissions = propsJSON(props.FieldPermissions)
+	}
+
 	if err := tx.Create(node).Error; err != nil {
 		return nil, err
 	}
 
 	// 创建子节点
-	if nodeTree.Child != nil {
+	if !nodeTree.Child.IsEmpty() {
 		childNode, err := s.createNodesFromTree(tx, workflowID, nodeTree.Child, node)
 		if err != nil {
 			return nil, err
@@ -108,13 +124,16 @@ func (s *WorkflowService) createNodesFromTree(tx *gorm.DB, workflowID uint, node
 			Type:      branch.Type,
 			SortOrder: i,
 		}
+		if branch.Props != nil {
+			branchRecord.Conditions = propsJSON(branch.Props.Conditions)
+		}
 
 		if err := tx.Create(branchRecord).Error; err != nil {
 			return nil, err
 		}
 
 		// 递归创建分支子节点
-		if branch.Child != nil {
+		if !branch.Child.IsEmpty() {
 			childNode, err := s.createNodesFromTree(tx, workflowID, branch.Child, node)
 			if err != nil {
 				return nil, err
@@ -142,21 +161,28 @@ func (s *WorkflowService) CreateWorkflow(req *CreateWorkflowRequest, creatorID u
 		return nil, fmt.Errorf("创建工作流失败: %w", err)
 	}
 
+	// 首个版本作为版本系列的标识
+	workflow.LineageID = workflow.ID
+	if err := s.db.Model(workflow).Update("lineage_id", workflow.ID).Error; err != nil {
+		return nil, fmt.Errorf("创建工作流失败: %w", err)
+	}
+
 	// 创建节点
 	for _, nodeReq := range req.Nodes {
 		node := &models.WorkflowNode{
-			WorkflowID:     workflow.ID,
-			NodeKey:        nodeReq.NodeKey,
-			Name:           nodeReq.Name,
-			Type:           nodeReq.Type,
-			ApprovalMode:   nodeReq.ApprovalMode,
-			Position:       nodeReq.Position,
-			Assignees:      nodeReq.Assignees,
-			Conditions:     nodeReq.Conditions,
-			Settings:       nodeReq.Settings,
-			NextNodes:      nodeReq.NextNodes,
-			FormConditions: nodeReq.FormConditions,
-			SortOrder:      nodeReq.SortOrder,
+			WorkflowID:       workflow.ID,
+			NodeKey:          nodeReq.NodeKey,
+			Name:             nodeReq.Name,
+			Type:             nodeReq.Type,
+			ApprovalMode:     nodeReq.ApprovalMode,
+			Position:         nodeReq.Position,
+			Assignees:        nodeReq.Assignees,
+			Conditions:       nodeReq.Conditions,
+			Settings:         nodeReq.Settings,
+			NextNodes:        nodeReq.NextNodes,
+			FormConditions:   nodeReq.FormConditions,
+			FieldPermissions: nodeReq.FieldPermissions,
+			SortOrder:        nodeReq.SortOrder,
 		}
 		if err := s.db.Create(node).Error; err != nil {
 			return nil, fmt.Errorf("创建工作流节点失败: %w", err)
@@ -169,16 +195,16 @@ func (s *WorkflowService) CreateWorkflow(req *CreateWorkflowRequest, creatorID u
 // StartWorkflowWithForm 启动带表单的工作流实例
 func (s *WorkflowService) StartWorkflowWithForm(req *StartWorkflowWithFormRequest, initiatorID uint) (*models.WorkflowInstance, error) {
 	return s.db.Transaction(func(tx *gorm.DB) (*models.WorkflowInstance, error) {
-		// 获取工作流定义
+		// 获取工作流定义，新实例使用版本系列的默认版本
+		versionID, err := s.resolveStartVersion(tx, req.WorkflowID)
+		if err != nil {
+			return nil, err
+		}
 		var workflow models.WorkflowDefinition
-		if err := tx.Preload("Nodes").Preload("Form").First(&workflow, req.WorkflowID).Error; err != nil {
+		if err := tx.Preload("Nodes").Preload("Form").First(&workflow, versionID).Error; err != nil {
 			return nil, fmt.Errorf("工作流定义不存在: %w", err)
 		}
 
-		if workflow.Status != models.WorkflowStatusActive {
-			return nil, errors.New("工作流未激活，无法启动")
-		}
-
 		// 如果有表单数据，先创建表单数据
 		var formData *models.FormData
 		if req.FormValues != "" && workflow.FormID != nil {
@@ -189,22 +215,23 @@ func (s *WorkflowService) StartWorkflowWithForm(req *StartWorkflowWithFormReques
 			}
 			
 			var err error
-			formData, err = s.formService.CreateFormData(formDataReq, initiatorID)
+			formData, err = s.formService.createFormDataInTx(tx, formDataReq, initiatorID)
 			if err != nil {
 				return nil, fmt.Errorf("创建表单数据失败: %w", err)
 			}
 		}
 
-		// 创建工作流实例
+		// 创建工作流实例，固定在启动时的版本上
 		instance := &models.WorkflowInstance{
-			WorkflowID:   req.WorkflowID,
-			Title:        req.Title,
-			BusinessKey:  req.BusinessKey,
-			BusinessType: req.BusinessType,
-			BusinessData: req.BusinessData,
-			Status:       models.InstanceStatusRunning,
-			StartTime:    time.Now(),
-			InitiatorID:  initiatorID,
+			WorkflowID:      workflow.ID,
+			WorkflowVersion: workflow.Version,
+			Title:           req.Title,
+			BusinessKey:     req.BusinessKey,
+			BusinessType:    req.BusinessType,
+			BusinessData:    req.BusinessData,
+			Status:          models.InstanceStatusRunning,
+			StartTime:       time.Now(),
+			InitiatorID:     initiatorID,
 		}
 
 		if formData != nil {
@@ -223,6 +250,19 @@ func (s *WorkflowService) StartWorkflowWithForm(req *StartWorkflowWithFormReques
 		if err := tx.Create(instance).Error; err != nil {
 			return nil, fmt.Errorf("创建工作流实例失败: %w", err)
 		}
+		if formData != nil {
+			// 关联实例并记录各字段的初始值
+			formData.InstanceID = instance.ID
+			if err := tx.Model(formData).Update("instance_id", instance.ID).Error; err != nil {
+				return nil, fmt.Errorf("关联表单数据失败: %w", err)
+			}
+			if err := recordFormDataChangesInTx(tx, formData, "", formData.FormValues, formChangeSource{UserID: initiatorID}); err != nil {
+				return nil, err
+			}
+		}
+		if err := s.publishEventInTx(tx, models.EventInstanceStarted, instance, nil, initiatorID); err != nil {
+			return nil, err
+		}
 
 		// 执行流程引擎，开始第一个节点
 		if err := s.executeWorkflowWithTree(tx, instance, workflow); err != nil {
@@ -242,21 +282,31 @@ func (s *WorkflowService) executeWorkflowWithTree(tx *gorm.DB, instance *models.
 	nodeTree, err := workflow.ParseNodeTree()
 	if err != nil || nodeTree == nil {
 		// 回退到传统节点执行
-		return s.executeWorkflow(instance, workflow.Nodes, "")
+		return s.executeWorkflow(tx, instance, workflow.Nodes, "")
 	}
 
 	// 从根节点开始执行
-	return s.executeNodeTree(tx, instance, nodeTree, nil)
+	return s.executeNodeTree(tx, instance, nodeTree, nodeTree)
 }
 
 // executeNodeTree 执行节点树
-func (s *WorkflowService) executeNodeTree(tx *gorm.DB, instance *models.WorkflowInstance, nodeTree *models.NodeTreeData, variables map[string]interface{}) error {
+func (s *WorkflowService) executeNodeTree(tx *gorm.DB, instance *models.WorkflowInstance, root, nodeTree *models.NodeTreeData) error {
+	if nodeTree.IsEmpty() {
+		return nil
+	}
+
+	// 记录执行路径
+	instance.AppendExecutionStep(models.ExecutionStep{
+		NodeKey:  nodeTree.Key,
+		NodeName: nodeTree.Name,
+		NodeType: nodeTree.Type,
+		Action:   models.ExecutionActionEnter,
+	})
+
 	switch nodeTree.Type {
 	case models.NodeTypeRoot, models.NodeTypeStart:
-		// 根节点或开始节点，执行子节点
-		if nodeTree.Child != nil {
-			return s.executeNodeTree(tx, instance, nodeTree.Child, variables)
-		}
+		// 根节点或开始节点，执行后续节点
+		return s.continueAfterNode(tx, instance, root, nodeTree.Key)
 		
 	case models.NodeTypeEnd:
 		// 结束节点，完成工作流
@@ -268,51 +318,241 @@ func (s *WorkflowService) executeNodeTree(tx *gorm.DB, instance *models.Workflow
 		
 	case models.NodeTypeCondition:
 		// 条件节点，评估分支
-		return s.evaluateConditionBranches(tx, instance, nodeTree, variables)
+		return s.evaluateConditionBranches(tx, instance, root, nodeTree)
+
+	case models.NodeTypeParallel:
+		// 并行节点，同时激活所有分支
+		return s.executeParallelBranches(tx, instance, root, nodeTree)
+
+	case models.NodeTypeMerge:
+		// 合并节点，汇聚已由并行节点完成，继续执行后续节点
+		return s.continueAfterNode(tx, instance, root, nodeTree.Key)
+
+	case models.NodeTypeService:
+		// 服务节点，创建调用任务，由后台任务调用接口后继续
+		return s.executeServiceNode(tx, instance, root, nodeTree)
 	}
 
 	return nil
 }
 
-// evaluateConditionBranches 评估条件分支
-func (s *WorkflowService) evaluateConditionBranches(tx *gorm.DB, instance *models.WorkflowInstance, nodeTree *models.NodeTreeData, variables map[string]interface{}) error {
-	// 获取表单数据进行条件评估
-	var formValues map[string]interface{}
-	if instance.FormDataID != nil {
-		var formData models.FormData
-		if err := tx.First(&formData, *instance.FormDataID).Error; err == nil {
-			json.Unmarshal([]byte(formData.FormValues), &formValues)
+// continueAfterNode 节点完成后继续执行：有子节点则执行子节点，
+// 分支末端回到所属网关继续执行网关的子节点，整棵树执行完毕则完成工作流
+func (s *WorkflowService) continueAfterNode(tx *gorm.DB, instance *models.WorkflowInstance, root *models.NodeTreeData, completedNodeKey string) error {
+	path := root.FindPath(completedNodeKey)
+	if path == nil {
+		return fmt.Errorf("节点树中找不到节点: %s", completedNodeKey)
+	}
+
+	for i := len(path) - 1; i >= 0; i-- {
+		node := path[i]
+		if i < len(path)-1 && node.Child == path[i+1] {
+			// 子节点链已执行完毕，当前节点随之完成，继续向上
+			continue
 		}
+		if i < len(path)-1 && node.Type == models.NodeTypeParallel {
+			// 并行分支执行完毕，到达汇聚点
+			return s.arriveAtJoin(tx, instance, root, node, path[i+1].Key)
+		}
+		if !node.Child.IsEmpty() {
+			return s.executeNodeTree(tx, instance, root, node.Child)
+		}
+	}
+
+	// 节点树执行完毕
+	return s.completeWorkflowInTx(tx, instance, models.InstanceStatusApproved)
+}
+
+// evaluateConditionBranches 评估条件分支，按顺序执行第一个满足条件的分支，
+// 都不满足时执行未配置条件的默认分支
+func (s *WorkflowService) evaluateConditionBranches(tx *gorm.DB, instance *models.WorkflowInstance, root, nodeTree *models.NodeTreeData) error {
+	ctx := s.buildConditionContext(tx, instance)
+	branchConditions, err := s.loadBranchConditions(tx, instance.WorkflowID, nodeTree.Key)
+	if err != nil {
+		return err
 	}
 
-	// 评估每个分支条件
-	for _, branch := range nodeTree.Branches {
-		if s.evaluateBranchCondition(&branch, formValues, variables) {
-			// 条件满足，执行该分支
-			if branch.Child != nil {
-				return s.executeNodeTree(tx, instance, branch.Child, variables)
+	var selected, defaultBranch *models.NodeTreeData
+	for i := range nodeTree.Branches {
+		branch := &nodeTree.Branches[i]
+		matched, isDefault, err := s.evaluateBranchCondition(branchConditions[branch.Key], ctx)
+		if err != nil {
+			return fmt.Errorf("分支 %s 的条件配置错误: %w", branch.Key, err)
+		}
+		if isDefault {
+			if defaultBranch == nil {
+				defaultBranch = branch
 			}
+			continue
 		}
+		if matched {
+			selected = branch
+			break
+		}
+	}
+	if selected == nil {
+		selected = defaultBranch
 	}
 
-	// 如果没有分支满足条件，执行默认子节点
-	if nodeTree.Child != nil {
-		return s.executeNodeTree(tx, instance, nodeTree.Child, variables)
+	if s.simulation != nil {
+		s.simulation.recordDecision(nodeTree, branchConditions, ctx, selected)
 	}
 
-	return nil
+	if selected != nil {
+		return s.executeBranch(tx, instance, root, selected)
+	}
+
+	// 没有满足条件的分支，继续执行网关的后续节点
+	return s.continueAfterNode(tx, instance, root, nodeTree.Key)
 }
 
-// evaluateBranchCondition 评估分支条件
-func (s *WorkflowService) evaluateBranchCondition(branch *models.NodeTreeData, formValues, variables map[string]interface{}) bool {
-	// 解析条件配置
-	if branch.Type != models.NodeTypeCondition {
-		return true // 非条件分支默认为true
+// executeParallelBranches 执行并行网关，同时激活所有分支
+func (s *WorkflowService) executeParallelBranches(tx *gorm.DB, instance *models.WorkflowInstance, root, nodeTree *models.NodeTreeData) error {
+	if len(nodeTree.Branches) == 0 {
+		return s.continueAfterNode(tx, instance, root, nodeTree.Key)
 	}
 
-	// 这里可以实现复杂的条件评估逻辑
-	// 暂时返回true，表示所有条件都满足
-	return true
+	instance.AppendExecutionStep(models.ExecutionStep{
+		NodeKey:  nodeTree.Key,
+		NodeName: nodeTree.Name,
+		NodeType: nodeTree.Type,
+		Action:   models.ExecutionActionFork,
+	})
+
+	for i := range nodeTree.Branches {
+		if instance.Status != models.InstanceStatusRunning {
+			// 某个分支已结束整个流程
+			break
+		}
+		if err := s.executeBranch(tx, instance, root, &nodeTree.Branches[i]); err != nil {
+			return err
+		}
+	}
+
+	return tx.Save(instance).Error
+}
+
+// arriveAtJoin 并行分支到达汇聚点，全部（或合并节点配置的N个）分支完成后继续执行
+func (s *WorkflowService) arriveAtJoin(tx *gorm.DB, instance *models.WorkflowInstance, root, gateway *models.NodeTreeData, branchKey string) error {
+	instance.AppendExecutionStep(models.ExecutionStep{
+		NodeKey:   gateway.Key,
+		NodeName:  gateway.Name,
+		NodeType:  gateway.Type,
+		Action:    models.ExecutionActionArrive,
+		BranchKey: branchKey,
+	})
+
+	// 统计本次分叉以来到达的分支数
+	arrived, joined := 0, false
+	steps := instance.GetExecutionPath()
+	for i := len(steps) - 1; i >= 0; i-- {
+		step := steps[i]
+		if step.NodeKey != gateway.Key {
+			continue
+		}
+		if step.Action == models.ExecutionActionFork {
+			break
+		}
+		switch step.Action {
+		case models.ExecutionActionArrive:
+			arrived++
+		case models.ExecutionActionJoin:
+			joined = true
+		}
+	}
+	if joined {
+		// 已汇聚（N选M），后到的分支不再继续
+		return tx.Save(instance).Error
+	}
+
+	required := len(gateway.Branches)
+	waitKey := gateway.Key
+	if merge := gateway.Child; !merge.IsEmpty() && merge.Type == models.NodeTypeMerge {
+		waitKey = merge.Key
+		settings, err := s.loadNodeSettings(tx, instance.WorkflowID, merge.Key)
+		if err != nil {
+			return err
+		}
+		if settings.CompleteCount > 0 && settings.CompleteCount < required {
+			required = settings.CompleteCount
+		}
+	}
+
+	if arrived < required {
+		// 等待其它分支
+		instance.AddCurrentNode(waitKey)
+		return tx.Save(instance).Error
+	}
+
+	// 汇聚：取消其余分支中未完成的任务
+	var branchKeys []string
+	for i := range gateway.Branches {
+		branchKeys = append(branchKeys, gateway.Branches[i].Keys()...)
+	}
+	if err := tx.Model(&models.WorkflowTask{}).
+		Where("instance_id = ? AND node_key IN ? AND status IN ?", instance.ID, branchKeys, models.OpenTaskStatuses).
+		Update("status", models.TaskStatusCancelled).Error; err != nil {
+		return fmt.Errorf("取消未完成分支任务失败: %w", err)
+	}
+	instance.RemoveCurrentNodes(append(branchKeys, waitKey)...)
+	instance.AppendExecutionStep(models.ExecutionStep{
+		NodeKey:  gateway.Key,
+		NodeName: gateway.Name,
+		NodeType: gateway.Type,
+		Action:   models.ExecutionActionJoin,
+	})
+
+	return s.continueAfterNode(tx, instance, root, gateway.Key)
+}
+
+// loadNodeSettings 加载节点设置
+func (s *WorkflowService) loadNodeSettings(tx *gorm.DB, workflowID uint, nodeKey string) (*models.NodeSettings, error) {
+	var node models.WorkflowNode
+	if err := tx.Where("workflow_id = ? AND node_key = ?", workflowID, nodeKey).First(&node).Error; err != nil {
+		return nil, fmt.Errorf("找不到节点配置: %w", err)
+	}
+	settings, err := node.ParseSettings()
+	if err != nil {
+		return nil, fmt.Errorf("解析节点设置失败: %w", err)
+	}
+	return settings, nil
+}
+
+// executeBranch 执行分支，空分支直接回到网关继续
+func (s *WorkflowService) executeBranch(tx *gorm.DB, instance *models.WorkflowInstance, root, branch *models.NodeTreeData) error {
+	if !branch.Child.IsEmpty() {
+		return s.executeNodeTree(tx, instance, root, branch.Child)
+	}
+	return s.continueAfterNode(tx, instance, root, branch.Key)
+}
+
+// loadBranchConditions 加载条件节点下各分支的条件配置
+func (s *WorkflowService) loadBranchConditions(tx *gorm.DB, workflowID uint, nodeKey string) (map[string]string, error) {
+	var branches []models.WorkflowBranch
+	if err := tx.Model(&models.WorkflowBranch{}).
+		Joins("JOIN workflow_nodes ON workflow_nodes.id = workflow_branches.node_id").
+		Where("workflow_nodes.workflow_id = ? AND workflow_nodes.node_key = ? AND workflow_nodes.deleted_at IS NULL", workflowID, nodeKey).
+		Find(&branches).Error; err != nil {
+		return nil, fmt.Errorf("获取分支条件失败: %w", err)
+	}
+
+	conditions := make(map[string]string, len(branches))
+	for _, branch := range branches {
+		conditions[branch.BranchKey] = branch.Conditions
+	}
+	return conditions, nil
+}
+
+// evaluateBranchCondition 评估分支条件，未配置条件的分支为默认分支；条件配置无法解析时返回错误
+func (s *WorkflowService) evaluateBranchCondition(conditions string, ctx *ConditionContext) (matched bool, isDefault bool, err error) {
+	condition, err := ParseShowCondition(conditions)
+	if err != nil {
+		return false, false, err
+	}
+	if IsEmptyCondition(condition) {
+		return false, true, nil
+	}
+	return EvaluateCondition(condition, ctx), false, nil
 }
 
 // createApprovalTasksFromNode 从节点创建审批任务
@@ -341,11 +581,23 @@ func (s *WorkflowService) createApprovalTasksFromNode(tx *gorm.DB, instance *mod
 		return errors.New("未找到有效的审批人")
 	}
 
+	// 认领模式，只创建一个待认领任务，由候选人认领后处理
+	if assigneeConfig.Mode == AssigneeModeQueue {
+		if err := s.createQueueTaskInTx(tx, instance, node, assignees); err != nil {
+			return err
+		}
+		instance.AddCurrentNode(node.NodeKey)
+		return tx.Save(instance).Error
+	}
+
 	// 根据审批模式创建任务
 	switch node.ApprovalMode {
 	case models.ApprovalModeSequence:
-		// 依次审批，只创建第一个人的任务
-		return s.createSingleTaskInTx(tx, instance, node, assignees[0])
+		// 依次审批，记录审批顺序，只创建第一个人的任务
+		instance.SetApprovalChain(node.NodeKey, assignees)
+		if err := s.createSingleTaskInTx(tx, instance, node, assignees[0]); err != nil {
+			return err
+		}
 		
 	case models.ApprovalModeParallel, models.ApprovalModeAny, models.ApprovalModeAll:
 		// 并行审批，为所有人创建任务
@@ -356,44 +608,46 @@ func (s *WorkflowService) createApprovalTasksFromNode(tx *gorm.DB, instance *mod
 		}
 	}
 
-	// 更新实例当前节点
-	currentNodes := []string{node.NodeKey}
-	currentNodesJson, _ := json.Marshal(currentNodes)
-	instance.CurrentNodes = string(currentNodesJson)
+	// 更新实例当前节点（并行分支时保留其它活动节点）
+	instance.AddCurrentNode(node.NodeKey)
 	
 	return tx.Save(instance).Error
 }
 
 // StartWorkflow 启动工作流实例
 func (s *WorkflowService) StartWorkflow(req *StartWorkflowRequest, initiatorID uint) (*models.WorkflowInstance, error) {
-	// 获取工作流定义
+	// 获取工作流定义，新实例使用版本系列的默认版本
+	versionID, err := s.resolveStartVersion(s.db, req.WorkflowID)
+	if err != nil {
+		return nil, err
+	}
 	var workflow models.WorkflowDefinition
-	if err := s.db.Preload("Nodes").First(&workflow, req.WorkflowID).Error; err != nil {
+	if err := s.db.Preload("Nodes").First(&workflow, versionID).Error; err != nil {
 		return nil, fmt.Errorf("工作流定义不存在: %w", err)
 	}
 
-	if workflow.Status != models.WorkflowStatusActive {
-		return nil, errors.New("工作流未激活，无法启动")
-	}
-
-	// 创建工作流实例
+	// 创建工作流实例，固定在启动时的版本上
 	instance := &models.WorkflowInstance{
-		WorkflowID:   req.WorkflowID,
-		Title:        req.Title,
-		BusinessKey:  req.BusinessKey,
-		BusinessType: req.BusinessType,
-		BusinessData: req.BusinessData,
-		Status:       models.InstanceStatusRunning,
-		StartTime:    time.Now(),
-		InitiatorID:  initiatorID,
+		WorkflowID:      workflow.ID,
+		WorkflowVersion: workflow.Version,
+		Title:           req.Title,
+		BusinessKey:     req.BusinessKey,
+		BusinessType:    req.BusinessType,
+		BusinessData:    req.BusinessData,
+		Status:          models.InstanceStatusRunning,
+		StartTime:       time.Now(),
+		InitiatorID:     initiatorID,
 	}
 
 	if err := s.db.Create(instance).Error; err != nil {
 		return nil, fmt.Errorf("创建工作流实例失败: %w", err)
 	}
+	if err := s.publishEventInTx(s.db, models.EventInstanceStarted, instance, nil, initiatorID); err != nil {
+		return nil, err
+	}
 
 	// 执行流程引擎，开始第一个节点
-	if err := s.executeWorkflow(instance, workflow.Nodes, ""); err != nil {
+	if err := s.executeWorkflow(s.db, instance, workflow.Nodes, ""); err != nil {
 		return nil, fmt.Errorf("启动工作流失败: %w", err)
 	}
 
@@ -404,7 +658,7 @@ func (s *WorkflowService) StartWorkflow(req *StartWorkflowRequest, initiatorID u
 }
 
 // executeWorkflow 执行工作流引擎
-func (s *WorkflowService) executeWorkflow(instance *models.WorkflowInstance, nodes []models.WorkflowNode, fromNodeKey string) error {
+func (s *WorkflowService) executeWorkflow(tx *gorm.DB, instance *models.WorkflowInstance, nodes []models.WorkflowNode, fromNodeKey string) error {
 	var nextNodes []models.WorkflowNode
 	
 	if fromNodeKey == "" {
@@ -428,7 +682,11 @@ func (s *WorkflowService) executeWorkflow(instance *models.WorkflowInstance, nod
 					for _, nextNode := range nodes {
 						if nextNode.NodeKey == nextKey {
 							// 检查条件是否满足
-							if s.checkNodeCondition(nextNode, instance) {
+							matched, err := s.checkNodeCondition(tx, nextNode, instance)
+							if err != nil {
+								return err
+							}
+							if matched {
 								nextNodes = append(nextNodes, nextNode)
 							}
 						}
@@ -441,7 +699,7 @@ func (s *WorkflowService) executeWorkflow(instance *models.WorkflowInstance, nod
 
 	// 处理下一个节点
 	for _, nextNode := range nextNodes {
-		if err := s.processNode(instance, nextNode); err != nil {
+		if err := s.processNode(tx, instance, nextNode); err != nil {
 			return err
 		}
 	}
@@ -450,23 +708,23 @@ func (s *WorkflowService) executeWorkflow(instance *models.WorkflowInstance, nod
 }
 
 // processNode 处理单个节点
-func (s *WorkflowService) processNode(instance *models.WorkflowInstance, node models.WorkflowNode) error {
+func (s *WorkflowService) processNode(tx *gorm.DB, instance *models.WorkflowInstance, node models.WorkflowNode) error {
 	switch node.Type {
 	case models.NodeTypeStart:
 		// 开始节点，直接执行下一个节点
-		return s.executeWorkflow(instance, []models.WorkflowNode{node}, node.NodeKey)
+		return s.executeWorkflow(tx, instance, []models.WorkflowNode{node}, node.NodeKey)
 		
 	case models.NodeTypeEnd:
 		// 结束节点，完成工作流
-		return s.completeWorkflow(instance, models.InstanceStatusApproved)
+		return s.completeWorkflowInTx(tx, instance, models.InstanceStatusApproved)
 		
 	case models.NodeTypeApproval:
 		// 审批节点，创建任务
-		return s.createApprovalTasks(instance, node)
+		return s.createApprovalTasks(tx, instance, node)
 		
 	case models.NodeTypeCondition:
 		// 条件节点，直接执行下一个节点
-		return s.executeWorkflow(instance, []models.WorkflowNode{node}, node.NodeKey)
+		return s.executeWorkflow(tx, instance, []models.WorkflowNode{node}, node.NodeKey)
 		
 	default:
 		return fmt.Errorf("不支持的节点类型: %s", node.Type)
@@ -474,7 +732,7 @@ func (s *WorkflowService) processNode(instance *models.WorkflowInstance, node mo
 }
 
 // createApprovalTasks 创建审批任务
-func (s *WorkflowService) createApprovalTasks(instance *models.WorkflowInstance, node models.WorkflowNode) error {
+func (s *WorkflowService) createApprovalTasks(tx *gorm.DB, instance *models.WorkflowInstance, node models.WorkflowNode) error {
 	// 解析审批人配置
 	var assigneeConfig AssigneeConfig
 	if node.Assignees != "" {
@@ -493,16 +751,28 @@ func (s *WorkflowService) createApprovalTasks(instance *models.WorkflowInstance,
 		return errors.New("未找到有效的审批人")
 	}
 
+	// 认领模式，只创建一个待认领任务，由候选人认领后处理
+	if assigneeConfig.Mode == AssigneeModeQueue {
+		if err := s.createQueueTaskInTx(tx, instance, node, assignees); err != nil {
+			return err
+		}
+		instance.AddCurrentNode(node.NodeKey)
+		return tx.Save(instance).Error
+	}
+
 	// 根据审批模式创建任务
 	switch node.ApprovalMode {
 	case models.ApprovalModeSequence:
-		// 依次审批，只创建第一个人的任务
-		return s.createSingleTask(instance, node, assignees[0])
+		// 依次审批，记录审批顺序，只创建第一个人的任务
+		instance.SetApprovalChain(node.NodeKey, assignees)
+		if err := s.createSingleTaskInTx(tx, instance, node, assignees[0]); err != nil {
+			return err
+		}
 		
 	case models.ApprovalModeParallel, models.ApprovalModeAny, models.ApprovalModeAll:
 		// 并行审批，为所有人创建任务
 		for _, assigneeID := range assignees {
-			if err := s.createSingleTask(instance, node, assigneeID); err != nil {
+			if err := s.createSingleTaskInTx(tx, instance, node, assigneeID); err != nil {
 				return err
 			}
 		}
@@ -512,24 +782,9 @@ func (s *WorkflowService) createApprovalTasks(instance *models.WorkflowInstance,
 	}
 
 	// 更新实例当前节点
-	currentNodes := []string{node.NodeKey}
-	currentNodesJson, _ := json.Marshal(currentNodes)
-	instance.CurrentNodes = string(currentNodesJson)
+	instance.AddCurrentNode(node.NodeKey)
 	
-	return s.db.Save(instance).Error
-}
-
-// createSingleTask 创建单个任务
-func (s *WorkflowService) createSingleTask(instance *models.WorkflowInstance, node models.WorkflowNode, assigneeID uint) error {
-	task := &models.WorkflowTask{
-		InstanceID: instance.ID,
-		NodeKey:    node.NodeKey,
-		NodeName:   node.Name,
-		AssigneeID: assigneeID,
-		Status:     models.TaskStatusPending,
-	}
-
-	return s.db.Create(task).Error
+	return tx.Save(instance).Error
 }
 
 // createSingleTaskInTx 在事务中创建单个任务
@@ -541,8 +796,12 @@ func (s *WorkflowService) createSingleTaskInTx(tx *gorm.DB, instance *models.Wor
 		AssigneeID: assigneeID,
 		Status:     models.TaskStatusPending,
 	}
+	s.applyTaskSLA(task, node)
 
-	return tx.Create(task).Error
+	if err := tx.Create(task).Error; err != nil {
+		return err
+	}
+	return s.publishEventInTx(tx, models.EventTaskCreated, instance, task, models.SystemOperatorID)
 }
 
 // ApproveTask 审批任务
@@ -550,8 +809,14 @@ func (s *WorkflowService) ApproveTask(taskID uint, userID uint, comment string)
 	return s.ApproveTaskWithForm(taskID, userID, comment, "")
 }
 
-// ApproveTaskWithForm 带表单数据的审批任务
+// ApproveTaskWithForm 带表单数据的审批任务；修改表单数据需提供版本，请使用 ApproveTaskWithFormVersion
 func (s *WorkflowService) ApproveTaskWithForm(taskID uint, userID uint, comment, formValues string) error {
+	return s.ApproveTaskWithFormVersion(taskID, userID, comment, formValues, 0)
+}
+
+// ApproveTaskWithFormVersion 带表单数据的审批任务，formVersion 为审批人修改时看到的表单数据版本，
+// 提交了表单数据时必须提供（否则返回 ErrFormVersionRequired），表单数据已被他人修改时返回 ErrFormDataConflict
+func (s *WorkflowService) ApproveTaskWithFormVersion(taskID uint, userID uint, comment, formValues string, formVersion int) error {
 	return s.db.Transaction(func(tx *gorm.DB) error {
 		// 获取任务信息
 		var task models.WorkflowTask
@@ -560,35 +825,83 @@ func (s *WorkflowService) ApproveTaskWithForm(taskID uint, userID uint, comment,
 		}
 
 		// 检查权限
+		if task.AssigneeID == 0 {
+			return errors.New("任务尚未认领，请先认领")
+		}
 		if task.AssigneeID != userID {
 			return errors.New("无权限处理此任务")
 		}
 
-		if task.Status != models.TaskStatusPending {
+		if !task.Status.IsActionable() {
 			return errors.New("任务已处理")
 		}
 
-		// 更新任务状态
-		task.Status = models.TaskStatusApproved
-		task.Comment = comment
-		task.FormValues = formValues
-		now := time.Now()
-		task.ProcessTime = &now
+		// 锁定实例，避免并行分支同时审批时覆盖活动节点
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&task.Instance, task.InstanceID).Error; err != nil {
+			return fmt.Errorf("实例不存在: %w", err)
+		}
+		if err := checkInstanceRunning(&task.Instance); err != nil {
+			return err
+		}
 
-		if err := tx.Save(&task).Error; err != nil {
-			return fmt.Errorf("更新任务失败: %w", err)
+		// 被委托人处理后，任务交还委托人
+		if task.DelegatorID != nil {
+			return s.resolveDelegatedTaskInTx(tx, &task, userID, "委托处理通过", comment)
 		}
 
-		// 记录历史
-		s.recordHistoryInTx(tx, task.InstanceID, task.NodeKey, "审批通过", userID, comment, formValues, "")
+		// 退回发起人后重新提交时更新表单数据，其他节点按字段权限合并审批人修改的字段
+		if formValues != "" {
+			source := formChangeSource{TaskID: &task.ID, NodeKey: task.NodeKey, UserID: userID}
+			if s.isStartNodeTask(tx, &task) {
+				if err := s.updateInstanceFormValuesInTx(tx, &task.Instance, formValues, formVersion, source); err != nil {
+					return err
+				}
+			} else if err := s.mergeApproverFormValuesInTx(tx, &task, formValues, formVersion); err != nil {
+				return err
+			}
+		}
 
-		// 检查节点是否完成
-		return s.checkNodeCompletionInTx(tx, &task)
+		return s.approveTaskInTx(tx, &task, userID, "审批通过", comment, formValues)
 	})
 }
 
+// approveTaskInTx 在事务中审批通过任务并推进流程，调用方需已校验任务状态并锁定实例
+func (s *WorkflowService) approveTaskInTx(tx *gorm.DB, task *models.WorkflowTask, operatorID uint, action, comment, formValues string) error {
+	// 更新任务状态
+	task.Status = models.TaskStatusApproved
+	task.Comment = comment
+	task.FormValues = formValues
+	task.DelegatorID = nil
+	now := time.Now()
+	task.ProcessTime = &now
+
+	if err := tx.Save(task).Error; err != nil {
+		return fmt.Errorf("更新任务失败: %w", err)
+	}
+
+	// 激活加签任务：后加签人开始审批，前加签全部通过后交还原审批人
+	if err := s.activateSignTasksInTx(tx, task); err != nil {
+		return err
+	}
+
+	// 记录历史
+	s.recordHistoryInTx(tx, task.InstanceID, task.NodeKey, action, operatorID, comment, formValues, "")
+	if err := s.publishEventInTx(tx, models.EventTaskApproved, &task.Instance, task, operatorID); err != nil {
+		return err
+	}
+
+	// 检查节点是否完成
+	return s.checkNodeCompletionInTx(tx, task)
+}
+
 // RejectTask 拒绝任务
 func (s *WorkflowService) RejectTask(taskID uint, userID uint, comment string) error {
+	return s.RejectTaskWithTarget(taskID, userID, comment, "")
+}
+
+// RejectTaskWithTarget 拒绝任务，按节点的拒绝设置终止流程或退回，
+// targetNodeKey 为退回指定节点时审批人选择的目标节点
+func (s *WorkflowService) RejectTaskWithTarget(taskID uint, userID uint, comment, targetNodeKey string) error {
 	return s.db.Transaction(func(tx *gorm.DB) error {
 		// 获取任务信息
 		var task models.WorkflowTask
@@ -597,32 +910,56 @@ func (s *WorkflowService) RejectTask(taskID uint, userID uint, comment string) e
 		}
 
 		// 检查权限
+		if task.AssigneeID == 0 {
+			return errors.New("任务尚未认领，请先认领")
+		}
 		if task.AssigneeID != userID {
 			return errors.New("无权限处理此任务")
 		}
 
-		if task.Status != models.TaskStatusPending {
+		if !task.Status.IsActionable() {
 			return errors.New("任务已处理")
 		}
 
-		// 更新任务状态
-		task.Status = models.TaskStatusRejected
-		task.Comment = comment
-		now := time.Now()
-		task.ProcessTime = &now
-
-		if err := tx.Save(&task).Error; err != nil {
-			return fmt.Errorf("更新任务失败: %w", err)
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&task.Instance, task.InstanceID).Error; err != nil {
+			return fmt.Errorf("实例不存在: %w", err)
+		}
+		if err := checkInstanceRunning(&task.Instance); err != nil {
+			return err
 		}
 
-		// 记录历史
-		s.recordHistoryInTx(tx, task.InstanceID, task.NodeKey, "拒绝", userID, comment, "", "")
+		// 被委托人处理后，任务交还委托人
+		if task.DelegatorID != nil {
+			return s.resolveDelegatedTaskInTx(tx, &task, userID, "委托处理拒绝", comment)
+		}
 
-		// 拒绝整个工作流实例
-		return s.completeWorkflowInTx(tx, &task.Instance, models.InstanceStatusRejected)
+		return s.rejectTaskInTx(tx, &task, userID, "拒绝", comment, targetNodeKey)
 	})
 }
 
+// rejectTaskInTx 在事务中拒绝任务并按节点设置处理，调用方需已校验任务状态并锁定实例
+func (s *WorkflowService) rejectTaskInTx(tx *gorm.DB, task *models.WorkflowTask, operatorID uint, action, comment, targetNodeKey string) error {
+	// 更新任务状态
+	task.Status = models.TaskStatusRejected
+	task.Comment = comment
+	task.DelegatorID = nil
+	now := time.Now()
+	task.ProcessTime = &now
+
+	if err := tx.Save(task).Error; err != nil {
+		return fmt.Errorf("更新任务失败: %w", err)
+	}
+
+	// 记录历史
+	s.recordHistoryInTx(tx, task.InstanceID, task.NodeKey, action, operatorID, comment, "", "")
+	if err := s.publishEventInTx(tx, models.EventTaskRejected, &task.Instance, task, operatorID); err != nil {
+		return err
+	}
+
+	// 根据节点设置处理拒绝
+	return s.handleRejectInTx(tx, task, operatorID, comment, targetNodeKey)
+}
+
 // checkNodeCompletion 检查节点是否完成
 func (s *WorkflowService) checkNodeCompletion(task *models.WorkflowTask) error {
 	return s.db.Transaction(func(tx *gorm.DB) error {
@@ -632,9 +969,13 @@ func (s *WorkflowService) checkNodeCompletion(task *models.WorkflowTask) error {
 
 // checkNodeCompletionInTx 在事务中检查节点是否完成
 func (s *WorkflowService) checkNodeCompletionInTx(tx *gorm.DB, task *models.WorkflowTask) error {
-	// 获取当前节点的所有任务
+	// 获取当前节点本轮的所有任务（退回后重新进入节点时忽略之前的任务）
 	var allTasks []models.WorkflowTask
-	if err := tx.Where("instance_id = ? AND node_key = ?", task.InstanceID, task.NodeKey).Find(&allTasks).Error; err != nil {
+	query := tx.Where("instance_id = ? AND node_key = ?", task.InstanceID, task.NodeKey)
+	if enteredAt := s.nodeEnteredAt(&task.Instance, task.NodeKey); !enteredAt.IsZero() {
+		query = query.Where("created_at >= ?", enteredAt.Truncate(time.Microsecond))
+	}
+	if err := query.Find(&allTasks).Error; err != nil {
 		return err
 	}
 
@@ -648,7 +989,10 @@ func (s *WorkflowService) checkNodeCompletionInTx(tx *gorm.DB, task *models.Work
 	var completed bool
 	switch node.ApprovalMode {
 	case models.ApprovalModeSequence:
-		completed = s.checkSequenceCompletion(allTasks)
+		var err error
+		if completed, err = s.advanceSequenceInTx(tx, &task.Instance, node, allTasks); err != nil {
+			return err
+		}
 	case models.ApprovalModeAny:
 		completed = s.checkAnyCompletion(allTasks)
 	case models.ApprovalModeAll:
@@ -658,22 +1002,35 @@ func (s *WorkflowService) checkNodeCompletionInTx(tx *gorm.DB, task *models.Work
 	}
 
 	if completed {
+		// 节点完成，取消本节点其余未处理的任务（如任意审批中的其他人、加签任务）
+		if err := tx.Model(&models.WorkflowTask{}).
+			Where("instance_id = ? AND node_key = ? AND status IN ?", task.InstanceID, task.NodeKey, models.OpenTaskStatuses).
+			Update("status", models.TaskStatusCancelled).Error; err != nil {
+			return fmt.Errorf("取消节点未处理任务失败: %w", err)
+		}
+
 		// 节点完成，检查是否使用节点树
 		var workflow models.WorkflowDefinition
 		if err := tx.First(&workflow, task.Instance.WorkflowID).Error; err != nil {
 			return err
 		}
 
+		// 节点完成，移出活动节点
+		task.Instance.RemoveCurrentNodes(task.NodeKey)
+
 		if workflow.NodeData != "" {
 			// 使用节点树执行
-			return s.continueWorkflowWithTree(tx, &task.Instance, &workflow, task.NodeKey)
+			if err := s.continueWorkflowWithTree(tx, &task.Instance, &workflow, task.NodeKey); err != nil {
+				return err
+			}
+			return tx.Save(&task.Instance).Error
 		} else {
 			// 使用传统节点执行
 			var nodes []models.WorkflowNode
 			if err := tx.Where("workflow_id = ?", task.Instance.WorkflowID).Find(&nodes).Error; err != nil {
 				return err
 			}
-			return s.executeWorkflow(&task.Instance, nodes, task.NodeKey)
+			return s.executeWorkflow(tx, &task.Instance, nodes, task.NodeKey)
 		}
 	}
 
@@ -689,35 +1046,35 @@ func (s *WorkflowService) continueWorkflowWithTree(tx *gorm.DB, instance *models
 	}
 
 	// 找到已完成的节点，继续执行下一个节点
-	nextNode := s.findNextNodeInTree(nodeTree, completedNodeKey)
-	if nextNode != nil {
-		return s.executeNodeTree(tx, instance, nextNode, nil)
-	}
-
-	return nil
+	return s.continueAfterNode(tx, instance, nodeTree, completedNodeKey)
 }
 
-// findNextNodeInTree 在节点树中查找下一个节点
-func (s *WorkflowService) findNextNodeInTree(nodeTree *models.NodeTreeData, completedNodeKey string) *models.NodeTreeData {
-	if nodeTree.Key == completedNodeKey {
-		return nodeTree.Child
+// advanceSequenceInTx 依次审批：上一人通过后为顺序中的下一人创建任务，最后一人通过后节点完成
+func (s *WorkflowService) advanceSequenceInTx(tx *gorm.DB, instance *models.WorkflowInstance, node models.WorkflowNode, tasks []models.WorkflowTask) (bool, error) {
+	chain := instance.GetApprovalChain(node.NodeKey)
+	if len(chain) == 0 {
+		// 没有记录审批顺序（如退回发起人的重新提交任务），任一通过即完成
+		return s.checkSequenceCompletion(tasks), nil
 	}
 
-	// 递归查找子节点
-	if nodeTree.Child != nil {
-		if result := s.findNextNodeInTree(nodeTree.Child, completedNodeKey); result != nil {
-			return result
+	// 加签任务不占用审批顺序中的位置
+	approved := 0
+	for _, task := range tasks {
+		if task.Status.IsOpen() {
+			return false, nil
 		}
-	}
-
-	// 递归查找分支节点
-	for _, branch := range nodeTree.Branches {
-		if result := s.findNextNodeInTree(&branch, completedNodeKey); result != nil {
-			return result
+		if task.Status == models.TaskStatusApproved && task.ParentTaskID == nil {
+			approved++
 		}
 	}
+	if approved >= len(chain) {
+		return true, nil
+	}
 
-	return nil
+	if err := s.createSingleTaskInTx(tx, instance, node, chain[approved]); err != nil {
+		return false, fmt.Errorf("创建下一审批人任务失败: %w", err)
+	}
+	return false, nil
 }
 
 // 辅助方法
@@ -735,9 +1092,25 @@ func (s *WorkflowService) checkSequenceCompletion(tasks []models.WorkflowTask) b
 }
 
 func (s *WorkflowService) checkAnyCompletion(tasks []models.WorkflowTask) bool {
-	// 任意审批：任何一个人审批通过即可
+	// 任意审批：任何一个人审批通过即可，其加签人也须全部处理完成
+	children := make(map[uint][]models.WorkflowTask)
 	for _, task := range tasks {
-		if task.Status == models.TaskStatusApproved {
+		if task.ParentTaskID != nil {
+			children[*task.ParentTaskID] = append(children[*task.ParentTaskID], task)
+		}
+	}
+	for _, task := range tasks {
+		if task.ParentTaskID == nil && task.Status == models.TaskStatusApproved && !hasOpenSignTask(task.ID, children) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasOpenSignTask 判断任务的加签任务（含多级加签）中是否还有未处理的
+func hasOpenSignTask(taskID uint, children map[uint][]models.WorkflowTask) bool {
+	for _, child := range children[taskID] {
+		if child.Status.IsOpen() || hasOpenSignTask(child.ID, children) {
 			return true
 		}
 	}
@@ -745,9 +1118,9 @@ func (s *WorkflowService) checkAnyCompletion(tasks []models.WorkflowTask) bool {
 }
 
 func (s *WorkflowService) checkAllCompletion(tasks []models.WorkflowTask) bool {
-	// 全员审批：所有人都必须审批通过
+	// 全员审批：所有人（含加签人）都必须审批通过
 	for _, task := range tasks {
-		if task.Status == models.TaskStatusPending {
+		if task.Status.IsOpen() {
 			return false
 		}
 		if task.Status == models.TaskStatusRejected {
@@ -757,23 +1130,37 @@ func (s *WorkflowService) checkAllCompletion(tasks []models.WorkflowTask) bool {
 	return true
 }
 
-func (s *WorkflowService) completeWorkflow(instance *models.WorkflowInstance, status models.InstanceStatus) error {
-	return s.db.Transaction(func(tx *gorm.DB) error {
-		return s.completeWorkflowInTx(tx, instance, status)
-	})
-}
-
 func (s *WorkflowService) completeWorkflowInTx(tx *gorm.DB, instance *models.WorkflowInstance, status models.InstanceStatus) error {
+	// 流程结束，取消其余未处理的任务（如并行分支、会签中的其他人）
+	if err := s.cancelPendingTasksInTx(tx, instance.ID); err != nil {
+		return err
+	}
+
 	instance.Status = status
+	instance.SetCurrentNodes(nil)
 	now := time.Now()
 	instance.EndTime = &now
-	return tx.Save(instance).Error
+	if err := tx.Save(instance).Error; err != nil {
+		return err
+	}
+
+	eventType := models.EventInstanceApproved
+	if status == models.InstanceStatusRejected {
+		eventType = models.EventInstanceRejected
+	}
+	return s.publishEventInTx(tx, eventType, instance, nil, models.SystemOperatorID)
 }
 
-func (s *WorkflowService) checkNodeCondition(node models.WorkflowNode, instance *models.WorkflowInstance) bool {
-	// 这里可以实现复杂的条件判断逻辑
-	// 可以根据表单数据、流程变量等进行判断
-	return true
+// checkNodeCondition 根据表单数据、流程变量、发起人属性判断节点条件；条件配置无法解析时返回错误
+func (s *WorkflowService) checkNodeCondition(tx *gorm.DB, node models.WorkflowNode, instance *models.WorkflowInstance) (bool, error) {
+	condition, err := ParseShowCondition(node.Conditions)
+	if err != nil {
+		return false, fmt.Errorf("节点 %s 的条件配置错误: %w", node.NodeKey, err)
+	}
+	if IsEmptyCondition(condition) {
+		return true, nil
+	}
+	return EvaluateCondition(condition, s.buildConditionContext(tx, instance)), nil
 }
 
 func (s *WorkflowService) resolveAssignees(config AssigneeConfig, instance *models.WorkflowInstance) ([]uint, error) {
@@ -836,6 +1223,55 @@ func (s *WorkflowService) GetDB() *gorm.DB {
 	return s.db
 }
 
+// ListInstances 获取实例列表：readAll 为 true 时返回全部实例，否则只返回用户发起的和需要用户审批的实例；
+// 表单数据按用户的字段权限去掉不可见的字段
+func (s *WorkflowService) ListInstances(userID uint, readAll bool) ([]models.WorkflowInstance, error) {
+	query := s.db.Preload("Workflow").
+		Preload("Initiator").
+		Preload("FormData.Form")
+	if !readAll {
+		query = query.Where("initiator_id = ? OR id IN (SELECT DISTINCT instance_id FROM workflow_tasks WHERE assignee_id = ?)",
+			userID, userID)
+	}
+
+	var instances []models.WorkflowInstance
+	if err := query.Find(&instances).Error; err != nil {
+		return nil, fmt.Errorf("获取实例列表失败: %w", err)
+	}
+	for i := range instances {
+		if err := s.FilterInstanceForViewer(&instances[i], userID); err != nil {
+			return nil, err
+		}
+	}
+	return instances, nil
+}
+
+// ListMyTasks 获取用户的待办任务（包括已认领和可认领的任务），includeSuspended 为 false 时不返回已挂起实例的任务；
+// 实例的表单数据按用户的字段权限去掉不可见的字段
+func (s *WorkflowService) ListMyTasks(userID uint, includeSuspended bool) ([]models.WorkflowTask, error) {
+	candidateTasks := s.db.Model(&models.WorkflowTaskCandidate{}).Select("task_id").Where("user_id = ?", userID)
+	query := s.db.Preload("Instance.Workflow").
+		Preload("Instance.Initiator").
+		Preload("Instance.FormData.Form").
+		Where("(assignee_id = ? AND status IN ?) OR (assignee_id = 0 AND status = ? AND id IN (?))",
+			userID, models.ActionableTaskStatuses, models.TaskStatusPending, candidateTasks)
+	if !includeSuspended {
+		runningInstances := s.db.Model(&models.WorkflowInstance{}).Select("id").Where("status = ?", models.InstanceStatusRunning)
+		query = query.Where("instance_id IN (?)", runningInstances)
+	}
+
+	var tasks []models.WorkflowTask
+	if err := query.Find(&tasks).Error; err != nil {
+		return nil, fmt.Errorf("获取待办任务失败: %w", err)
+	}
+	for i := range tasks {
+		if err := s.FilterInstanceForViewer(&tasks[i].Instance, userID); err != nil {
+			return nil, err
+		}
+	}
+	return tasks, nil
+}
+
 // 请求结构体
 type CreateWorkflowRequest struct {
 	Name        string                 `json:"name" binding:"required"`
@@ -845,17 +1281,18 @@ type CreateWorkflowRequest struct {
 }
 
 type CreateWorkflowNodeRequest struct {
-	NodeKey        string                `json:"node_key" binding:"required"`
-	Name           string                `json:"name" binding:"required"`
-	Type           models.NodeType       `json:"type" binding:"required"`
-	ApprovalMode   models.ApprovalMode   `json:"approval_mode"`
-	Position       string                `json:"position"`
-	Assignees      string                `json:"assignees"`
-	Conditions     string                `json:"conditions"`
-	Settings       string                `json:"settings"`
-	NextNodes      string                `json:"next_nodes"`
-	FormConditions string                `json:"form_conditions"`
-	SortOrder      int                   `json:"sort_order"`
+	NodeKey          string              `json:"node_key" binding:"required"`
+	Name             string              `json:"name" binding:"required"`
+	Type             models.NodeType     `json:"type" binding:"required"`
+	ApprovalMode     models.ApprovalMode `json:"approval_mode"`
+	Position         string              `json:"position"`
+	Assignees        string              `json:"assignees"`
+	Conditions       string              `json:"conditions"`
+	Settings         string              `json:"settings"`
+	NextNodes        string              `json:"next_nodes"`
+	FormConditions   string              `json:"form_conditions"`
+	FieldPermissions string              `json:"field_permissions"`
+	SortOrder        int                 `json:"sort_order"`
 }
 
 type CreateWorkflowWithTreeRequest struct {
@@ -886,7 +1323,11 @@ type StartWorkflowWithFormRequest struct {
 
 type AssigneeConfig struct {
 	Type          string `json:"type"` // users, roles, departments
+	Mode          string `json:"mode"` // 任务分配方式：空为每人一个任务，queue 为候选人认领
 	UserIDs       []uint `json:"user_ids"`
 	RoleIDs       []uint `json:"role_ids"`
 	DepartmentIDs []uint `json:"department_ids"`
-} 
\ No newline at end of file
+}
+
+// AssigneeModeQueue 认领模式：角色或部门成员共享一个待认领任务
+const AssigneeModeQueue = "queue" 
\ No newline at end of file
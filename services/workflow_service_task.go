@@ -0,0 +1,655 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"gin-web-api/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 服务节点调用的默认设置
+const (
+	defaultServiceTimeout      = 30 * time.Second
+	defaultServiceRetryBackoff = 30 * time.Second
+	defaultServiceMaxRetries   = 3
+	serviceResponseLimit       = 1 << 20 // 响应内容最多读取1MB
+	serviceJobBatchSize        = 20
+)
+
+// serviceTemplatePattern 模板占位符，如 ${form.amount}
+var serviceTemplatePattern = regexp.MustCompile(`\$\{([^}]+)\}`)
+
+// serviceHTTPClient 服务节点调用使用的HTTP客户端，超时由每次请求的上下文控制
+var serviceHTTPClient = &http.Client{}
+
+// executeServiceNode 服务节点：创建调用任务，由后台任务在事务外调用接口后继续流转
+func (s *WorkflowService) executeServiceNode(tx *gorm.DB, instance *models.WorkflowInstance, root, nodeTree *models.NodeTreeData) error {
+	if s.simulation != nil {
+		// 流程模拟不调用外部接口，按调用成功继续
+		return s.continueAfterNode(tx, instance, root, nodeTree.Key)
+	}
+
+	settings, err := s.loadNodeSettings(tx, instance.WorkflowID, nodeTree.Key)
+	if err != nil {
+		return err
+	}
+	service := settings.Service
+	if service == nil || service.URL == "" {
+		return fmt.Errorf("服务节点 %s 未配置请求地址", nodeTree.Name)
+	}
+
+	job, err := s.buildServiceJob(tx, instance, nodeTree, service)
+	if err != nil {
+		return err
+	}
+	if err := tx.Create(job).Error; err != nil {
+		return fmt.Errorf("创建服务调用任务失败: %w", err)
+	}
+
+	instance.AddCurrentNode(nodeTree.Key)
+	return tx.Save(instance).Error
+}
+
+// buildServiceJob 按节点设置和实例数据生成调用任务，请求内容在进入节点时确定
+func (s *WorkflowService) buildServiceJob(tx *gorm.DB, instance *models.WorkflowInstance, nodeTree *models.NodeTreeData, service *models.ServiceSettings) (*models.WorkflowServiceJob, error) {
+	tmpl := &serviceTemplate{
+		ctx: s.buildConditionContext(tx, instance),
+		instance: map[string]interface{}{
+			"id":            instance.ID,
+			"title":         instance.Title,
+			"business_key":  instance.BusinessKey,
+			"business_type": instance.BusinessType,
+			"workflow_id":   instance.WorkflowID,
+			"initiator_id":  instance.InitiatorID,
+			"node_key":      nodeTree.Key,
+		},
+	}
+
+	var body interface{}
+	if raw := propsJSON(service.Body); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &body); err != nil {
+			return nil, fmt.Errorf("解析服务节点 %s 的请求体模板失败: %w", nodeTree.Name, err)
+		}
+		body = tmpl.renderValue(body)
+	} else {
+		// 未配置请求体时发送实例信息、表单值和流程变量
+		body = map[string]interface{}{
+			"instance":  tmpl.instance,
+			"form":      tmpl.ctx.FormValues,
+			"variables": tmpl.ctx.Variables,
+		}
+	}
+	requestBody, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("生成请求体失败: %w", err)
+	}
+
+	headers := make(map[string]string, len(service.Headers))
+	for key, value := range service.Headers {
+		headers[key] = tmpl.renderString(value)
+	}
+	headersJSON, _ := json.Marshal(headers)
+
+	method := strings.ToUpper(service.Method)
+	if method == "" {
+		method = http.MethodPost
+	}
+	maxRetries := service.MaxRetries
+	if maxRetries <= 0 {
+		maxRetries = defaultServiceMaxRetries
+	}
+
+	return &models.WorkflowServiceJob{
+		InstanceID:  instance.ID,
+		NodeKey:     nodeTree.Key,
+		NodeName:    nodeTree.Name,
+		Status:      models.ServiceJobStatusPending,
+		Method:      method,
+		URL:         tmpl.renderString(service.URL),
+		Headers:     string(headersJSON),
+		RequestBody: string(requestBody),
+		MaxAttempts: maxRetries + 1,
+		NextRunAt:   time.Now(),
+	}, nil
+}
+
+// serviceTemplate 服务节点模板渲染，占位符取值规则与条件关键字一致，另支持 instance. 前缀
+type serviceTemplate struct {
+	ctx      *ConditionContext
+	instance map[string]interface{}
+	extra    map[string]map[string]interface{} // 其他前缀的取值，如通知模板的 task. 和 recipient.
+}
+
+// resolve 根据占位符取值
+func (t *serviceTemplate) resolve(keyword string) (interface{}, bool) {
+	keyword = strings.TrimSpace(keyword)
+	scope, path := splitConditionKeyword(keyword)
+	if scope == "instance" {
+		return lookupPath(t.instance, path)
+	}
+	if values, ok := t.extra[scope]; ok {
+		return lookupPath(values, path)
+	}
+	return t.ctx.Resolve(keyword)
+}
+
+// renderString 替换字符串中的占位符，非字符串的值按JSON格式替换，取不到值时替换为空
+func (t *serviceTemplate) renderString(text string) string {
+	return serviceTemplatePattern.ReplaceAllStringFunc(text, func(match string) string {
+		value, ok := t.resolve(match[2 : len(match)-1])
+		if !ok || value == nil {
+			return ""
+		}
+		if str, isString := value.(string); isString {
+			return str
+		}
+		encoded, _ := json.Marshal(value)
+		return string(encoded)
+	})
+}
+
+// renderValue 渲染JSON模板：整个字符串是一个占位符时保留值的原始类型，否则按字符串替换
+func (t *serviceTemplate) renderValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		if loc := serviceTemplatePattern.FindStringSubmatchIndex(v); loc != nil && loc[0] == 0 && loc[1] == len(v) {
+			resolved, _ := t.resolve(v[loc[2]:loc[3]])
+			return resolved
+		}
+		return t.renderString(v)
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = t.renderValue(item)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = t.renderValue(item)
+		}
+		return v
+	}
+	return value
+}
+
+// ProcessServiceJobs 执行到期的服务节点调用任务（含执行中断超过锁定期限的任务），返回执行的任务数量
+func (s *WorkflowService) ProcessServiceJobs(now time.Time) (int, error) {
+	var jobIDs []uint
+	if err := s.db.Model(&models.WorkflowServiceJob{}).
+		Joins("JOIN workflow_instances ON workflow_instances.id = workflow_service_jobs.instance_id").
+		Where("workflow_instances.status = ?", models.InstanceStatusRunning).
+		Where("((workflow_service_jobs.status = ? AND workflow_service_jobs.next_run_at <= ?) OR (workflow_service_jobs.status = ? AND workflow_service_jobs.locked_until <= ?))",
+			models.ServiceJobStatusPending, now, models.ServiceJobStatusRunning, now).
+		Order("workflow_service_jobs.next_run_at").
+		Limit(serviceJobBatchSize).
+		Pluck("workflow_service_jobs.id", &jobIDs).Error; err != nil {
+		return 0, fmt.Errorf("查询服务调用任务失败: %w", err)
+	}
+
+	processed := 0
+	for _, jobID := range jobIDs {
+		claimed, err := s.runServiceJob(jobID, now)
+		if err != nil {
+			log.Printf("执行服务调用任务 %d 失败: %v", jobID, err)
+			continue
+		}
+		if claimed {
+			processed++
+		}
+	}
+	return processed, nil
+}
+
+// runServiceJob 认领并执行调用任务：HTTP调用在事务外执行，完成后在事务中处理结果；
+// 任务已被其他副本认领时返回 false
+func (s *WorkflowService) runServiceJob(jobID uint, now time.Time) (bool, error) {
+	var job models.WorkflowServiceJob
+	if err := s.db.First(&job, jobID).Error; err != nil {
+		return false, fmt.Errorf("服务调用任务不存在: %w", err)
+	}
+
+	timeout := s.serviceTimeout(job)
+	lockedUntil := now.Add(timeout + time.Minute)
+	result := s.db.Model(&models.WorkflowServiceJob{}).
+		Where("id = ? AND ((status = ? AND next_run_at <= ?) OR (status = ? AND locked_until <= ?))",
+			jobID, models.ServiceJobStatusPending, now, models.ServiceJobStatusRunning, now).
+		Updates(map[string]interface{}{
+			"status":       models.ServiceJobStatusRunning,
+			"locked_until": lockedUntil,
+			"attempts":     gorm.Expr("attempts + 1"),
+		})
+	if result.Error != nil {
+		return false, fmt.Errorf("认领服务调用任务失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+
+	statusCode, responseBody, callErr := callServiceEndpoint(job, timeout)
+	return true, s.finishServiceJob(jobID, statusCode, responseBody, callErr)
+}
+
+// serviceTimeout 获取调用任务所在节点配置的超时时间
+func (s *WorkflowService) serviceTimeout(job models.WorkflowServiceJob) time.Duration {
+	service, err := s.loadServiceSettings(s.db, job)
+	if err != nil || service.Timeout == "" {
+		return defaultServiceTimeout
+	}
+	timeout, err := parseSLADuration(service.Timeout)
+	if err != nil || timeout <= 0 {
+		return defaultServiceTimeout
+	}
+	return timeout
+}
+
+// loadServiceSettings 加载调用任务所在节点的服务设置
+func (s *WorkflowService) loadServiceSettings(tx *gorm.DB, job models.WorkflowServiceJob) (*models.ServiceSettings, error) {
+	var instance models.WorkflowInstance
+	if err := tx.Select("id", "workflow_id").First(&instance, job.InstanceID).Error; err != nil {
+		return nil, fmt.Errorf("实例不存在: %w", err)
+	}
+	settings, err := s.loadNodeSettings(tx, instance.WorkflowID, job.NodeKey)
+	if err != nil {
+		return nil, err
+	}
+	if settings.Service == nil {
+		return nil, fmt.Errorf("节点 %s 没有服务设置", job.NodeKey)
+	}
+	return settings.Service, nil
+}
+
+// callServiceEndpoint 调用服务节点配置的接口，非2xx响应视为失败
+func callServiceEndpoint(job models.WorkflowServiceJob, timeout time.Duration) (int, string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var body io.Reader
+	if job.Method != http.MethodGet && job.Method != http.MethodDelete {
+		body = strings.NewReader(job.RequestBody)
+	}
+	req, err := http.NewRequestWithContext(ctx, job.Method, job.URL, body)
+	if err != nil {
+		return 0, "", fmt.Errorf("创建请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	var headers map[string]string
+	if job.Headers != "" {
+		if err := json.Unmarshal([]byte(job.Headers), &headers); err != nil {
+			return 0, "", fmt.Errorf("解析请求头失败: %w", err)
+		}
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := serviceHTTPClient.Do(req)
+	if err != nil {
+		return 0, "", fmt.Errorf("请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, serviceResponseLimit))
+	if err != nil {
+		return resp.StatusCode, "", fmt.Errorf("读取响应失败: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, string(data), fmt.Errorf("接口返回状态码 %d", resp.StatusCode)
+	}
+	return resp.StatusCode, string(data), nil
+}
+
+// finishServiceJob 处理调用结果：成功时写回流程变量并继续流转，失败时按退避间隔重试，
+// 重试用尽后执行错误分支，没有错误分支时停留在服务节点等待人工重试
+func (s *WorkflowService) finishServiceJob(jobID uint, statusCode int, responseBody string, callErr error) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var job models.WorkflowServiceJob
+		if err := tx.First(&job, jobID).Error; err != nil {
+			return fmt.Errorf("服务调用任务不存在: %w", err)
+		}
+		var instance models.WorkflowInstance
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Workflow").First(&instance, job.InstanceID).Error; err != nil {
+			return fmt.Errorf("实例不存在: %w", err)
+		}
+		if err := tx.First(&job, jobID).Error; err != nil {
+			return err
+		}
+		if job.Status != models.ServiceJobStatusRunning {
+			return nil
+		}
+
+		now := time.Now()
+		job.ResponseStatus = statusCode
+		job.ResponseBody = responseBody
+		job.LockedUntil = nil
+
+		// 实例挂起期间完成的调用在恢复后重新执行
+		if instance.Status == models.InstanceStatusSuspended {
+			job.Status = models.ServiceJobStatusPending
+			job.NextRunAt = now
+			return tx.Save(&job).Error
+		}
+
+		// 实例已结束、已离开服务节点或节点已重新进入时，结果不再使用
+		active, err := s.isServiceJobActiveInTx(tx, &instance, &job)
+		if err != nil {
+			return err
+		}
+		if !active {
+			job.Status = models.ServiceJobStatusCancelled
+			job.CompletedAt = &now
+			return tx.Save(&job).Error
+		}
+
+		service, err := s.loadServiceSettings(tx, job)
+		if err != nil {
+			return err
+		}
+
+		var outputs map[string]interface{}
+		if callErr == nil {
+			outputs, callErr = mapServiceResponse(responseBody, service.ResponseMapping)
+		}
+		var variables string
+		if callErr == nil {
+			variables, callErr = mergeServiceOutputs(instance.Variables, outputs)
+		}
+
+		if callErr == nil {
+			instance.Variables = variables
+			return s.completeServiceJobInTx(tx, &instance, &job, outputs, now)
+		}
+
+		job.LastError = callErr.Error()
+		if job.Attempts < job.MaxAttempts {
+			job.Status = models.ServiceJobStatusPending
+			job.NextRunAt = now.Add(serviceRetryDelay(service, job.Attempts))
+			return tx.Save(&job).Error
+		}
+		return s.failServiceJobInTx(tx, &instance, &job, service, now)
+	})
+}
+
+// isServiceJobActiveInTx 判断调用任务是否仍对应实例当前所在的服务节点
+func (s *WorkflowService) isServiceJobActiveInTx(tx *gorm.DB, instance *models.WorkflowInstance, job *models.WorkflowServiceJob) (bool, error) {
+	if instance.Status != models.InstanceStatusRunning {
+		return false, nil
+	}
+	current := false
+	for _, key := range instance.GetCurrentNodes() {
+		if key == job.NodeKey {
+			current = true
+			break
+		}
+	}
+	if !current {
+		return false, nil
+	}
+
+	var newer int64
+	if err := tx.Model(&models.WorkflowServiceJob{}).
+		Where("instance_id = ? AND node_key = ? AND id > ?", job.InstanceID, job.NodeKey, job.ID).
+		Count(&newer).Error; err != nil {
+		return false, err
+	}
+	return newer == 0, nil
+}
+
+// mapServiceResponse 按响应字段映射从响应JSON中取出流程变量
+func mapServiceResponse(responseBody string, mapping map[string]string) (map[string]interface{}, error) {
+	if len(mapping) == 0 {
+		return nil, nil
+	}
+
+	var response map[string]interface{}
+	if err := json.Unmarshal([]byte(responseBody), &response); err != nil {
+		return nil, fmt.Errorf("解析响应失败: %w", err)
+	}
+
+	outputs := make(map[string]interface{}, len(mapping))
+	for variable, path := range mapping {
+		value, ok := lookupPath(response, path)
+		if !ok {
+			return nil, fmt.Errorf("响应中缺少字段 %s", path)
+		}
+		outputs[variable] = value
+	}
+	return outputs, nil
+}
+
+// mergeServiceOutputs 把响应映射出的变量合并到流程变量JSON中；原有流程变量无法解析时返回错误，不覆盖原有变量
+func mergeServiceOutputs(variablesJSON string, outputs map[string]interface{}) (string, error) {
+	if len(outputs) == 0 {
+		return variablesJSON, nil
+	}
+
+	variables := make(map[string]interface{})
+	if variablesJSON != "" {
+		if err := json.Unmarshal([]byte(variablesJSON), &variables); err != nil {
+			return "", fmt.Errorf("解析流程变量失败: %w", err)
+		}
+	}
+	for key, value := range outputs {
+		variables[key] = value
+	}
+	data, err := json.Marshal(variables)
+	if err != nil {
+		return "", fmt.Errorf("序列化流程变量失败: %w", err)
+	}
+	return string(data), nil
+}
+
+// serviceRetryDelay 计算第N次失败后的重试间隔：首次为配置的间隔，之后每次翻倍
+func serviceRetryDelay(service *models.ServiceSettings, attempts int) time.Duration {
+	backoff := defaultServiceRetryBackoff
+	if service.RetryBackoff != "" {
+		if d, err := parseSLADuration(service.RetryBackoff); err == nil && d > 0 {
+			backoff = d
+		}
+	}
+	for i := 1; i < attempts && backoff < 24*time.Hour; i++ {
+		backoff *= 2
+	}
+	return backoff
+}
+
+// completeServiceJobInTx 调用成功：记录写回的流程变量（调用方已合并到 instance.Variables），离开服务节点继续流转
+func (s *WorkflowService) completeServiceJobInTx(tx *gorm.DB, instance *models.WorkflowInstance, job *models.WorkflowServiceJob, outputs map[string]interface{}, now time.Time) error {
+	job.Status = models.ServiceJobStatusSucceeded
+	job.LastError = ""
+	job.CompletedAt = &now
+	if err := tx.Save(job).Error; err != nil {
+		return fmt.Errorf("更新服务调用任务失败: %w", err)
+	}
+
+	var outputsJSON string
+	if len(outputs) > 0 {
+		encoded, _ := json.Marshal(outputs)
+		outputsJSON = string(encoded)
+	}
+
+	if err := s.recordHistoryInTx(tx, instance.ID, job.NodeKey, "服务调用成功", models.SystemOperatorID,
+		fmt.Sprintf("接口返回状态码 %d", job.ResponseStatus), "", outputsJSON); err != nil {
+		return err
+	}
+
+	root, err := instance.Workflow.ParseNodeTree()
+	if err != nil || root == nil {
+		return errors.New("解析节点树失败")
+	}
+	instance.RemoveCurrentNodes(job.NodeKey)
+	if err := s.continueAfterNode(tx, instance, root, job.NodeKey); err != nil {
+		return err
+	}
+	return tx.Save(instance).Error
+}
+
+// failServiceJobInTx 重试用尽：有错误分支时执行错误分支，否则停留在服务节点等待人工重试
+func (s *WorkflowService) failServiceJobInTx(tx *gorm.DB, instance *models.WorkflowInstance, job *models.WorkflowServiceJob, service *models.ServiceSettings, now time.Time) error {
+	job.Status = models.ServiceJobStatusFailed
+	job.CompletedAt = &now
+	if err := tx.Save(job).Error; err != nil {
+		return fmt.Errorf("更新服务调用任务失败: %w", err)
+	}
+
+	root, err := instance.Workflow.ParseNodeTree()
+	if err != nil || root == nil {
+		return errors.New("解析节点树失败")
+	}
+	node := root.FindNode(job.NodeKey)
+	if node == nil {
+		return fmt.Errorf("节点树中找不到节点: %s", job.NodeKey)
+	}
+	errorBranch := findServiceErrorBranch(node, service)
+
+	comment := fmt.Sprintf("重试 %d 次后仍失败: %s", job.Attempts-1, job.LastError)
+	if errorBranch == nil {
+		comment += "，等待人工重试"
+	}
+	if err := s.recordHistoryInTx(tx, instance.ID, job.NodeKey, "服务调用失败", models.SystemOperatorID, comment, "", ""); err != nil {
+		return err
+	}
+	if errorBranch == nil {
+		return nil
+	}
+
+	instance.RemoveCurrentNodes(job.NodeKey)
+	if err := s.executeBranch(tx, instance, root, errorBranch); err != nil {
+		return err
+	}
+	return tx.Save(instance).Error
+}
+
+// findServiceErrorBranch 查找服务节点的错误分支：按设置的分支标识查找，未设置时使用唯一的分支
+func findServiceErrorBranch(node *models.NodeTreeData, service *models.ServiceSettings) *models.NodeTreeData {
+	if service.ErrorBranch == "" {
+		if len(node.Branches) == 1 {
+			return &node.Branches[0]
+		}
+		return nil
+	}
+	for i := range node.Branches {
+		if node.Branches[i].Key == service.ErrorBranch {
+			return &node.Branches[i]
+		}
+	}
+	return nil
+}
+
+// RetryServiceJob 人工重试失败的服务调用（实例仍停留在该服务节点时）
+func (s *WorkflowService) RetryServiceJob(jobID, operatorID uint) (*models.WorkflowServiceJob, error) {
+	var job models.WorkflowServiceJob
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&job, jobID).Error; err != nil {
+			return fmt.Errorf("服务调用任务不存在: %w", err)
+		}
+		var instance models.WorkflowInstance
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&instance, job.InstanceID).Error; err != nil {
+			return fmt.Errorf("实例不存在: %w", err)
+		}
+		if err := tx.First(&job, jobID).Error; err != nil {
+			return err
+		}
+		if job.Status != models.ServiceJobStatusFailed {
+			return errors.New("只能重试失败的服务调用")
+		}
+		active, err := s.isServiceJobActiveInTx(tx, &instance, &job)
+		if err != nil {
+			return err
+		}
+		if !active {
+			return errors.New("实例已不在该服务节点，无法重试")
+		}
+
+		job.Status = models.ServiceJobStatusPending
+		job.Attempts = 0
+		job.NextRunAt = time.Now()
+		job.CompletedAt = nil
+		if err := tx.Save(&job).Error; err != nil {
+			return fmt.Errorf("更新服务调用任务失败: %w", err)
+		}
+		return s.recordHistoryInTx(tx, instance.ID, job.NodeKey, "重试服务调用", operatorID, "", "", "")
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// GetInstanceServiceJobs 获取实例的服务调用记录
+func (s *WorkflowService) GetInstanceServiceJobs(instanceID uint) ([]models.WorkflowServiceJob, error) {
+	var jobs []models.WorkflowServiceJob
+	if err := s.db.Where("instance_id = ?", instanceID).Order("id").Find(&jobs).Error; err != nil {
+		return nil, fmt.Errorf("获取服务调用记录失败: %w", err)
+	}
+	return jobs, nil
+}
+
+// ServiceTaskWorker 服务节点后台任务：轮询并执行到期的HTTP调用，
+// 任务通过条件更新认领，多副本部署时不会重复执行
+type ServiceTaskWorker struct {
+	workflowService *WorkflowService
+	interval        time.Duration
+	stop            chan struct{}
+}
+
+// NewServiceTaskWorker 创建服务节点后台任务
+func NewServiceTaskWorker(interval time.Duration) *ServiceTaskWorker {
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	return &ServiceTaskWorker{
+		workflowService: NewWorkflowService(),
+		interval:        interval,
+		stop:            make(chan struct{}),
+	}
+}
+
+// Start 在后台启动服务节点任务
+func (w *ServiceTaskWorker) Start() {
+	go func() {
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				w.RunOnce()
+			case <-w.stop:
+				return
+			}
+		}
+	}()
+	log.Printf("服务节点任务已启动，间隔 %s", w.interval)
+}
+
+// Stop 停止服务节点任务
+func (w *ServiceTaskWorker) Stop() {
+	close(w.stop)
+}
+
+// RunOnce 执行一轮到期的服务调用
+func (w *ServiceTaskWorker) RunOnce() {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("服务节点任务异常: %v", r)
+		}
+	}()
+
+	processed, err := w.workflowService.ProcessServiceJobs(time.Now())
+	if err != nil {
+		log.Printf("执行服务调用失败: %v", err)
+		return
+	}
+	if processed > 0 {
+		log.Printf("已执行 %d 个服务调用", processed)
+	}
+}
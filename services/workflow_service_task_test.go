@@ -0,0 +1,329 @@
+package services
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gin-web-api/models"
+)
+
+// serviceTestTree 开始 -> 服务节点 s1（错误分支到审批节点 a9）-> 审批节点 a1 -> 结束
+func serviceTestTree(url string) string {
+	return `{"key":"root","name":"发起","type":"ROOT","child":{"key":"s1","name":"同步ERP","type":"service",
+		"props":{"settings":{"service":{"url":"` + url + `","retry_backoff":"1m","max_retries":2,
+			"headers":{"X-Amount":"${variables.amount}"},
+			"body":{"amount":"${variables.amount}","title":"${instance.title}"},
+			"response_mapping":{"erp_id":"data.id"}}}},
+		"branches":[{"key":"s1_error","name":"失败","type":"condition",
+			"child":{"key":"a9","name":"人工处理","type":"approval","props":{"assignees":{"type":"users","user_ids":[5]}}}}],
+		"child":{"key":"a1","name":"审批","type":"approval","props":{"assignees":{"type":"users","user_ids":[2]}},
+			"child":{"key":"end","name":"结束","type":"END"}}}}`
+}
+
+// startServiceTestInstance 发起流程并返回实例和服务节点的调用任务
+func startServiceTestInstance(t *testing.T, s *WorkflowService, url string) (*models.WorkflowInstance, models.WorkflowServiceJob) {
+	t.Helper()
+
+	workflow := createTestWorkflow(t, s, serviceTestTree(url))
+	instance, err := s.StartWorkflowWithForm(&StartWorkflowWithFormRequest{
+		WorkflowID: workflow.ID, Title: "采购申请", Variables: `{"amount":500}`,
+	}, 1)
+	if err != nil {
+		t.Fatalf("发起流程失败: %v", err)
+	}
+	return instance, loadServiceTestJob(t, s, instance.ID)
+}
+
+// loadServiceTestJob 获取实例最近一次的调用任务
+func loadServiceTestJob(t *testing.T, s *WorkflowService, instanceID uint) models.WorkflowServiceJob {
+	t.Helper()
+
+	var job models.WorkflowServiceJob
+	if err := s.db.Where("instance_id = ?", instanceID).Order("id DESC").First(&job).Error; err != nil {
+		t.Fatalf("查询调用任务失败: %v", err)
+	}
+	return job
+}
+
+func TestRunServiceJobSuccess(t *testing.T) {
+	setupTestDB(t)
+	s := NewWorkflowService()
+
+	var requestBody map[string]interface{}
+	var amountHeader string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		json.Unmarshal(data, &requestBody)
+		amountHeader = r.Header.Get("X-Amount")
+		w.Write([]byte(`{"data":{"id":"ERP-1"}}`))
+	}))
+	defer server.Close()
+
+	instance, job := startServiceTestInstance(t, s, server.URL)
+	if tasks := openTestTasks(t, s, instance.ID); len(tasks) != 0 {
+		t.Fatalf("调用完成前不应创建任务, got %d", len(tasks))
+	}
+
+	claimed, err := s.runServiceJob(job.ID, time.Now())
+	if err != nil || !claimed {
+		t.Fatalf("runServiceJob() = %v, %v, want true, nil", claimed, err)
+	}
+	if requestBody["amount"] != float64(500) || requestBody["title"] != "采购申请" || amountHeader != "500" {
+		t.Errorf("请求内容 = %v, X-Amount = %q", requestBody, amountHeader)
+	}
+
+	job = loadServiceTestJob(t, s, instance.ID)
+	if job.Status != models.ServiceJobStatusSucceeded || job.Attempts != 1 || job.ResponseStatus != http.StatusOK {
+		t.Errorf("调用任务 = %s, attempts %d, status %d", job.Status, job.Attempts, job.ResponseStatus)
+	}
+
+	var updated models.WorkflowInstance
+	s.db.First(&updated, instance.ID)
+	var variables map[string]interface{}
+	json.Unmarshal([]byte(updated.Variables), &variables)
+	if variables["erp_id"] != "ERP-1" || variables["amount"] != float64(500) {
+		t.Errorf("流程变量 = %s", updated.Variables)
+	}
+	if nodes := updated.GetCurrentNodes(); len(nodes) != 1 || nodes[0] != "a1" {
+		t.Errorf("活动节点 = %v, want [a1]", nodes)
+	}
+	if tasks := openTestTasks(t, s, instance.ID); len(tasks) != 1 || tasks[0].NodeKey != "a1" || tasks[0].AssigneeID != 2 {
+		t.Errorf("调用成功后应在 a1 创建任务, got %+v", tasks)
+	}
+
+	// 已完成的任务不会被再次认领
+	if claimed, err := s.runServiceJob(job.ID, time.Now()); err != nil || claimed {
+		t.Errorf("再次执行 runServiceJob() = %v, %v, want false, nil", claimed, err)
+	}
+}
+
+func TestRunServiceJobRetryBackoff(t *testing.T) {
+	setupTestDB(t)
+	s := NewWorkflowService()
+
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	instance, job := startServiceTestInstance(t, s, server.URL)
+	if job.MaxAttempts != 3 {
+		t.Fatalf("MaxAttempts = %d, want 3", job.MaxAttempts)
+	}
+
+	// 失败后按 1m、2m 的间隔重试
+	for attempt, delay := range []time.Duration{time.Minute, 2 * time.Minute} {
+		before := time.Now()
+		claimed, err := s.runServiceJob(job.ID, job.NextRunAt)
+		if err != nil || !claimed {
+			t.Fatalf("第%d次 runServiceJob() = %v, %v", attempt+1, claimed, err)
+		}
+		after := time.Now()
+
+		job = loadServiceTestJob(t, s, instance.ID)
+		if job.Status != models.ServiceJobStatusPending || job.Attempts != attempt+1 {
+			t.Fatalf("第%d次失败后 status = %s, attempts = %d", attempt+1, job.Status, job.Attempts)
+		}
+		if job.NextRunAt.Before(before.Add(delay)) || job.NextRunAt.After(after.Add(delay)) {
+			t.Errorf("第%d次失败后下次执行时间 = %v, want %v 后", attempt+1, job.NextRunAt, delay)
+		}
+		if job.ResponseStatus != http.StatusBadGateway || !strings.Contains(job.LastError, "502") {
+			t.Errorf("响应状态 = %d, 错误 = %q", job.ResponseStatus, job.LastError)
+		}
+
+		// 未到重试时间时不会被认领
+		if claimed, err := s.runServiceJob(job.ID, job.NextRunAt.Add(-time.Second)); err != nil || claimed {
+			t.Errorf("未到重试时间 runServiceJob() = %v, %v, want false, nil", claimed, err)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("接口调用次数 = %d, want 2", calls)
+	}
+	if tasks := openTestTasks(t, s, instance.ID); len(tasks) != 0 {
+		t.Errorf("重试期间不应创建任务, got %+v", tasks)
+	}
+}
+
+func TestFinishServiceJobErrorBranch(t *testing.T) {
+	setupTestDB(t)
+	s := NewWorkflowService()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	instance, job := startServiceTestInstance(t, s, server.URL)
+	for i := 0; i < job.MaxAttempts; i++ {
+		job = loadServiceTestJob(t, s, instance.ID)
+		if _, err := s.runServiceJob(job.ID, job.NextRunAt); err != nil {
+			t.Fatalf("runServiceJob() error = %v", err)
+		}
+	}
+
+	job = loadServiceTestJob(t, s, instance.ID)
+	if job.Status != models.ServiceJobStatusFailed || job.Attempts != 3 || job.CompletedAt == nil {
+		t.Fatalf("重试用尽后 status = %s, attempts = %d", job.Status, job.Attempts)
+	}
+	tasks := openTestTasks(t, s, instance.ID)
+	if len(tasks) != 1 || tasks[0].NodeKey != "a9" || tasks[0].AssigneeID != 5 {
+		t.Fatalf("重试用尽后应执行错误分支, got %+v", tasks)
+	}
+
+	// 错误分支处理完后回到服务节点的子节点继续
+	if err := s.ApproveTask(tasks[0].ID, 5, "已人工同步"); err != nil {
+		t.Fatalf("ApproveTask() error = %v", err)
+	}
+	if tasks := openTestTasks(t, s, instance.ID); len(tasks) != 1 || tasks[0].NodeKey != "a1" {
+		t.Errorf("错误分支完成后应进入 a1, got %+v", tasks)
+	}
+}
+
+func TestFinishServiceJobCancelsStaleJob(t *testing.T) {
+	tests := []struct {
+		name  string
+		stale func(t *testing.T, s *WorkflowService, instance *models.WorkflowInstance, job models.WorkflowServiceJob)
+	}{
+		{
+			name: "实例已离开服务节点",
+			stale: func(t *testing.T, s *WorkflowService, instance *models.WorkflowInstance, job models.WorkflowServiceJob) {
+				s.db.First(instance, instance.ID)
+				instance.SetCurrentNodes([]string{"a1"})
+				if err := s.db.Save(instance).Error; err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+		{
+			name: "节点已重新进入",
+			stale: func(t *testing.T, s *WorkflowService, instance *models.WorkflowInstance, job models.WorkflowServiceJob) {
+				newer := job
+				newer.ID = 0
+				newer.Status = models.ServiceJobStatusPending
+				if err := s.db.Create(&newer).Error; err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+		{
+			name: "实例已结束",
+			stale: func(t *testing.T, s *WorkflowService, instance *models.WorkflowInstance, job models.WorkflowServiceJob) {
+				if err := s.db.Model(instance).Update("status", models.InstanceStatusCancelled).Error; err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTestDB(t)
+			s := NewWorkflowService()
+			instance, job := startServiceTestInstance(t, s, "http://erp.example.com/sync")
+
+			// 模拟已认领、正在调用接口的任务
+			if err := s.db.Model(&job).Updates(map[string]interface{}{"status": models.ServiceJobStatusRunning, "attempts": 1}).Error; err != nil {
+				t.Fatal(err)
+			}
+			tt.stale(t, s, instance, job)
+
+			if err := s.finishServiceJob(job.ID, http.StatusOK, `{"data":{"id":"ERP-1"}}`, nil); err != nil {
+				t.Fatalf("finishServiceJob() error = %v", err)
+			}
+
+			var finished models.WorkflowServiceJob
+			s.db.First(&finished, job.ID)
+			if finished.Status != models.ServiceJobStatusCancelled || finished.CompletedAt == nil {
+				t.Errorf("过期的调用任务 status = %s, want cancelled", finished.Status)
+			}
+			var updated models.WorkflowInstance
+			s.db.First(&updated, instance.ID)
+			if strings.Contains(updated.Variables, "ERP-1") {
+				t.Errorf("过期的调用结果不应写回流程变量: %s", updated.Variables)
+			}
+			if tasks := openTestTasks(t, s, instance.ID); len(tasks) != 0 {
+				t.Errorf("过期的调用结果不应继续流转, got %+v", tasks)
+			}
+		})
+	}
+}
+
+func TestRunServiceJobInvalidStoredJSON(t *testing.T) {
+	tests := []struct {
+		name      string
+		corrupt   func(t *testing.T, s *WorkflowService, instance *models.WorkflowInstance, job models.WorkflowServiceJob)
+		wantCalls int
+		wantError string
+	}{
+		{
+			name: "请求头格式错误",
+			corrupt: func(t *testing.T, s *WorkflowService, instance *models.WorkflowInstance, job models.WorkflowServiceJob) {
+				if err := s.db.Model(&job).Update("headers", `{"X-Amount":`).Error; err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantCalls: 0,
+			wantError: "解析请求头失败",
+		},
+		{
+			name: "流程变量格式错误",
+			corrupt: func(t *testing.T, s *WorkflowService, instance *models.WorkflowInstance, job models.WorkflowServiceJob) {
+				if err := s.db.Model(instance).Update("variables", `{"amount":`).Error; err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantCalls: 1,
+			wantError: "解析流程变量失败",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTestDB(t)
+			s := NewWorkflowService()
+
+			calls := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				w.Write([]byte(`{"data":{"id":"ERP-1"}}`))
+			}))
+			defer server.Close()
+
+			instance, job := startServiceTestInstance(t, s, server.URL)
+			tt.corrupt(t, s, instance, job)
+			var before models.WorkflowInstance
+			if err := s.db.First(&before, instance.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+
+			if claimed, err := s.runServiceJob(job.ID, time.Now()); err != nil || !claimed {
+				t.Fatalf("runServiceJob() = %v, %v, want true, nil", claimed, err)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("接口调用次数 = %d, want %d", calls, tt.wantCalls)
+			}
+
+			job = loadServiceTestJob(t, s, instance.ID)
+			if job.Status != models.ServiceJobStatusPending || !strings.Contains(job.LastError, tt.wantError) {
+				t.Errorf("status = %s, 错误 = %q, want 等待重试, 错误包含 %q", job.Status, job.LastError, tt.wantError)
+			}
+			var after models.WorkflowInstance
+			if err := s.db.First(&after, instance.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+			if after.Variables != before.Variables {
+				t.Errorf("流程变量被修改: %s, want %s", after.Variables, before.Variables)
+			}
+			if tasks := openTestTasks(t, s, instance.ID); len(tasks) != 0 {
+				t.Errorf("调用失败时不应继续流转, got %+v", tasks)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"testing"
+
+	"gin-web-api/models"
+)
+
+// conditionTestTree 开始 -> 条件网关 c（big：金额大于5000，默认分支 small）-> 审批节点 last -> 结束
+const conditionTestTree = `{"key":"root","name":"发起","type":"ROOT","child":{"key":"c","name":"金额判断","type":"condition","branches":[
+	{"key":"big","name":"大额","type":"condition",
+		"props":{"conditions":{"groups":[{"conditions":[{"condition":"gt","keyword":"variables.amount","value":5000}]}]}},
+		"child":{"key":"cfo","name":"财务总监","type":"approval","props":{"assignees":{"type":"users","user_ids":[2]}}}},
+	{"key":"small","name":"默认","type":"condition","child":{}}],
+	"child":{"key":"last","name":"经理","type":"approval","props":{"assignees":{"type":"users","user_ids":[3]}},
+		"child":{"key":"end","name":"结束","type":"END"}}}}`
+
+func TestEvaluateConditionBranches(t *testing.T) {
+	tests := []struct {
+		name       string
+		variables  string
+		conditions string // 覆盖 big 分支的条件配置，为空时不修改
+		wantNode   string
+		wantErr    bool
+	}{
+		{name: "满足条件", variables: `{"amount":9000}`, wantNode: "cfo"},
+		{name: "不满足条件走默认分支", variables: `{"amount":100}`, wantNode: "last"},
+		{name: "条件配置格式错误", variables: `{"amount":9000}`, conditions: `{"groups":`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTestDB(t)
+			s := NewWorkflowService()
+			workflow := createTestWorkflow(t, s, conditionTestTree)
+			if tt.conditions != "" {
+				if err := s.db.Model(&models.WorkflowBranch{}).Where("branch_key = ?", "big").
+					Update("conditions", tt.conditions).Error; err != nil {
+					t.Fatalf("修改分支条件失败: %v", err)
+				}
+			}
+
+			instance, err := s.StartWorkflowWithForm(&StartWorkflowWithFormRequest{
+				WorkflowID: workflow.ID, Title: "采购申请", Variables: tt.variables,
+			}, 1)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("StartWorkflowWithForm() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				var count int64
+				s.db.Model(&models.WorkflowInstance{}).Count(&count)
+				if count != 0 {
+					t.Errorf("条件错误时不应创建实例, got %d", count)
+				}
+				return
+			}
+
+			tasks := openTestTasks(t, s, instance.ID)
+			if len(tasks) != 1 || tasks[0].NodeKey != tt.wantNode {
+				t.Errorf("未处理任务 = %+v, want 节点 %s", tasks, tt.wantNode)
+			}
+		})
+	}
+}
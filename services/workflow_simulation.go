@@ -0,0 +1,391 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"gin-web-api/models"
+
+	"gorm.io/gorm"
+)
+
+// maxSimulationRounds 自动审批的最大轮数，防止配置错误导致循环
+const maxSimulationRounds = 100
+
+// errSimulationRollback 模拟结束后回滚事务，不保留任何数据
+var errSimulationRollback = errors.New("流程模拟回滚")
+
+// SimulateWorkflowRequest 流程模拟请求：模拟已保存的工作流（可以是草稿），或模拟未保存的节点树
+type SimulateWorkflowRequest struct {
+	WorkflowID  uint                 `json:"workflow_id"`
+	NodeTree    *models.NodeTreeData `json:"node_tree"`
+	FormID      uint                 `json:"form_id"`
+	FormValues  string               `json:"form_values"`
+	Variables   string               `json:"variables"`
+	InitiatorID uint                 `json:"initiator_id"` // 为空时使用当前用户
+	AutoApprove bool                 `json:"auto_approve"` // 逐步自动通过所有任务，直到流程结束
+}
+
+// SimulationAssignee 模拟解析出的审批人
+type SimulationAssignee struct {
+	UserID   uint   `json:"user_id"`
+	Username string `json:"username"`
+	FullName string `json:"full_name"`
+}
+
+// SimulationApproval 模拟到达的审批节点及解析出的审批人
+type SimulationApproval struct {
+	Round     int                  `json:"round"` // 第几轮到达，0 为发起时
+	NodeKey   string               `json:"node_key"`
+	NodeName  string               `json:"node_name"`
+	Queue     bool                 `json:"queue"` // 认领模式，审批人为候选人
+	Assignees []SimulationAssignee `json:"assignees"`
+	Chain     []SimulationAssignee `json:"chain,omitempty"` // 依次审批时全部审批人的顺序
+}
+
+// SimulationCondition 单个条件的评估结果
+type SimulationCondition struct {
+	Keyword   string      `json:"keyword"`
+	Condition string      `json:"condition"`
+	Value     interface{} `json:"value"`
+	Actual    interface{} `json:"actual"`
+	Matched   bool        `json:"matched"`
+}
+
+// SimulationBranch 分支的评估结果
+type SimulationBranch struct {
+	BranchKey  string                `json:"branch_key"`
+	BranchName string                `json:"branch_name"`
+	Default    bool                  `json:"default"`
+	Matched    bool                  `json:"matched"`
+	Selected   bool                  `json:"selected"`
+	Conditions []SimulationCondition `json:"conditions,omitempty"`
+}
+
+// SimulationDecision 条件节点的分支决策
+type SimulationDecision struct {
+	NodeKey        string             `json:"node_key"`
+	NodeName       string             `json:"node_name"`
+	SelectedBranch string             `json:"selected_branch"`
+	Branches       []SimulationBranch `json:"branches"`
+}
+
+// WorkflowSimulationResult 流程模拟结果
+type WorkflowSimulationResult struct {
+	WorkflowID   uint                      `json:"workflow_id"`
+	Version      int                       `json:"version"`
+	Status       models.InstanceStatus     `json:"status"`
+	Completed    bool                      `json:"completed"`
+	CurrentNodes []string                  `json:"current_nodes"`
+	Path         []models.ExecutionStep    `json:"path"`
+	Approvals    []SimulationApproval      `json:"approvals"`
+	Decisions    []SimulationDecision      `json:"decisions"`
+	Issues       []WorkflowValidationIssue `json:"issues,omitempty"` // 工作流校验发现的问题
+	Error        string                    `json:"error,omitempty"`  // 模拟中断的原因
+}
+
+// workflowSimulation 流程模拟过程中的记录
+type workflowSimulation struct {
+	formValues map[string]interface{}
+	decisions  []SimulationDecision
+}
+
+// recordDecision 记录条件节点各分支的评估结果
+func (sim *workflowSimulation) recordDecision(nodeTree *models.NodeTreeData, branchConditions map[string]string, ctx *ConditionContext, selected *models.NodeTreeData) {
+	decision := SimulationDecision{NodeKey: nodeTree.Key, NodeName: nodeTree.Name}
+	if selected != nil {
+		decision.SelectedBranch = selected.Key
+	}
+
+	for i := range nodeTree.Branches {
+		branch := &nodeTree.Branches[i]
+		result := SimulationBranch{
+			BranchKey:  branch.Key,
+			BranchName: branch.Name,
+			Selected:   selected != nil && selected.Key == branch.Key,
+		}
+
+		condition, err := ParseShowCondition(branchConditions[branch.Key])
+		switch {
+		case err != nil:
+		case IsEmptyCondition(condition):
+			result.Default = true
+		default:
+			result.Matched = EvaluateCondition(condition, ctx)
+			for _, group := range condition.Groups {
+				for _, cond := range group.Conditions {
+					actual, _ := ctx.Resolve(cond.Keyword)
+					result.Conditions = append(result.Conditions, SimulationCondition{
+						Keyword:   cond.Keyword,
+						Condition: cond.Condition,
+						Value:     cond.Value,
+						Actual:    actual,
+						Matched:   EvaluateSingleCondition(cond, ctx),
+					})
+				}
+			}
+		}
+		decision.Branches = append(decision.Branches, result)
+	}
+
+	sim.decisions = append(sim.decisions, decision)
+}
+
+// SimulateWorkflow 模拟流程运行：按传入的表单值、流程变量和发起人执行流程引擎，
+// 返回执行路径、各审批节点的审批人和分支决策；所有操作在事务中执行并回滚，不保留任何数据
+func (s *WorkflowService) SimulateWorkflow(req *SimulateWorkflowRequest, userID uint) (*WorkflowSimulationResult, error) {
+	if req.WorkflowID == 0 && req.NodeTree.IsEmpty() {
+		return nil, errors.New("请提供工作流ID或节点树")
+	}
+	initiatorID := req.InitiatorID
+	if initiatorID == 0 {
+		initiatorID = userID
+	}
+
+	sim := &workflowSimulation{formValues: make(map[string]interface{})}
+	if req.FormValues != "" {
+		if err := json.Unmarshal([]byte(req.FormValues), &sim.formValues); err != nil {
+			return nil, fmt.Errorf("解析表单值失败: %w", err)
+		}
+	}
+	variables := make(map[string]interface{})
+	if req.Variables != "" {
+		if err := json.Unmarshal([]byte(req.Variables), &variables); err != nil {
+			return nil, fmt.Errorf("解析流程变量失败: %w", err)
+		}
+	}
+
+	result := &WorkflowSimulationResult{}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		simService := &WorkflowService{db: tx, formService: &FormService{db: tx}, simulation: sim}
+
+		workflowID := req.WorkflowID
+		if workflowID == 0 {
+			workflow, err := simService.CreateWorkflowWithNodeTree(&CreateWorkflowWithTreeRequest{
+				Name:     "流程模拟",
+				FormID:   req.FormID,
+				NodeTree: req.NodeTree,
+			}, userID)
+			if err != nil {
+				return fmt.Errorf("创建模拟工作流失败: %w", err)
+			}
+			workflowID = workflow.ID
+		}
+
+		var workflow models.WorkflowDefinition
+		if err := tx.Preload("Nodes").First(&workflow, workflowID).Error; err != nil {
+			return fmt.Errorf("工作流不存在: %w", err)
+		}
+		if req.WorkflowID != 0 {
+			result.WorkflowID = workflow.ID
+			result.Version = workflow.Version
+		}
+
+		validation, err := simService.validateWorkflowInTx(tx, &ValidateWorkflowRequest{WorkflowID: workflow.ID})
+		if err != nil {
+			return err
+		}
+		result.Issues = validation.Issues
+
+		variablesJSON, _ := json.Marshal(variables)
+		instance := &models.WorkflowInstance{
+			WorkflowID:      workflow.ID,
+			WorkflowVersion: workflow.Version,
+			Title:           "流程模拟",
+			Status:          models.InstanceStatusRunning,
+			StartTime:       time.Now(),
+			InitiatorID:     initiatorID,
+			Variables:       string(variablesJSON),
+		}
+		if err := tx.Create(instance).Error; err != nil {
+			return fmt.Errorf("创建模拟实例失败: %w", err)
+		}
+
+		// 模拟中断时保留中断前的执行路径，一并返回
+		if err := simService.runSimulation(tx, instance, workflow, req.AutoApprove, result); err != nil {
+			result.Error = err.Error()
+		}
+		result.Status = instance.Status
+		result.Completed = instance.Status != models.InstanceStatusRunning
+		result.CurrentNodes = instance.GetCurrentNodes()
+		result.Path = instance.GetExecutionPath()
+		return errSimulationRollback
+	})
+	if err != nil && !errors.Is(err, errSimulationRollback) {
+		return nil, err
+	}
+
+	result.Decisions = sim.decisions
+	if result.CurrentNodes == nil {
+		result.CurrentNodes = []string{}
+	}
+	if result.Approvals == nil {
+		result.Approvals = []SimulationApproval{}
+	}
+	if result.Decisions == nil {
+		result.Decisions = []SimulationDecision{}
+	}
+	return result, nil
+}
+
+// runSimulation 启动模拟实例，需要时逐轮自动通过所有待处理任务
+func (s *WorkflowService) runSimulation(tx *gorm.DB, instance *models.WorkflowInstance, workflow models.WorkflowDefinition, autoApprove bool, result *WorkflowSimulationResult) error {
+	if err := tx.Transaction(func(tx *gorm.DB) error {
+		if err := s.executeWorkflowWithTree(tx, instance, workflow); err != nil {
+			return err
+		}
+		return tx.Save(instance).Error
+	}); err != nil {
+		return fmt.Errorf("启动流程失败: %w", err)
+	}
+
+	var lastTaskID uint
+	if err := s.collectSimulationApprovals(tx, instance.ID, 0, &lastTaskID, result); err != nil {
+		return err
+	}
+	if !autoApprove {
+		return nil
+	}
+
+	for round := 1; ; round++ {
+		if err := tx.First(instance, instance.ID).Error; err != nil {
+			return err
+		}
+		if instance.Status != models.InstanceStatusRunning {
+			return nil
+		}
+		if round > maxSimulationRounds {
+			return fmt.Errorf("超过最大模拟轮数 %d，流程可能存在循环", maxSimulationRounds)
+		}
+
+		var tasks []models.WorkflowTask
+		if err := tx.Where("instance_id = ? AND status IN ?", instance.ID, models.ActionableTaskStatuses).
+			Order("id").Find(&tasks).Error; err != nil {
+			return err
+		}
+		if len(tasks) == 0 {
+			return errors.New("流程没有待处理的任务，无法继续")
+		}
+
+		for _, task := range tasks {
+			if err := s.simulateApprove(tx, task.ID); err != nil {
+				return fmt.Errorf("自动通过节点 %s 失败: %w", task.NodeName, err)
+			}
+		}
+		if err := s.collectSimulationApprovals(tx, instance.ID, round, &lastTaskID, result); err != nil {
+			return err
+		}
+	}
+}
+
+// simulateApprove 以任务处理人（待认领任务为第一个候选人）的身份通过任务，任务已被处理时跳过
+func (s *WorkflowService) simulateApprove(tx *gorm.DB, taskID uint) error {
+	var task models.WorkflowTask
+	if err := tx.First(&task, taskID).Error; err != nil {
+		return err
+	}
+	if task.Status != models.TaskStatusPending && task.Status != models.TaskStatusClaimed {
+		return nil
+	}
+
+	userID := task.AssigneeID
+	if userID == 0 {
+		var candidate models.WorkflowTaskCandidate
+		if err := tx.Where("task_id = ?", task.ID).Order("id").First(&candidate).Error; err != nil {
+			return errors.New("待认领任务没有候选人")
+		}
+		if err := s.ClaimTask(task.ID, candidate.UserID); err != nil {
+			return err
+		}
+		userID = candidate.UserID
+	}
+	return s.ApproveTask(task.ID, userID, "流程模拟自动通过")
+}
+
+// collectSimulationApprovals 按节点汇总本轮新建的任务及其审批人
+func (s *WorkflowService) collectSimulationApprovals(tx *gorm.DB, instanceID uint, round int, lastTaskID *uint, result *WorkflowSimulationResult) error {
+	var tasks []models.WorkflowTask
+	if err := tx.Preload("Assignee").
+		Where("instance_id = ? AND id > ?", instanceID, *lastTaskID).
+		Order("id").Find(&tasks).Error; err != nil {
+		return err
+	}
+
+	if len(tasks) == 0 {
+		return nil
+	}
+	var instance models.WorkflowInstance
+	if err := tx.First(&instance, instanceID).Error; err != nil {
+		return err
+	}
+
+	index := make(map[string]int)
+	for _, task := range tasks {
+		*lastTaskID = task.ID
+
+		i, ok := index[task.NodeKey]
+		if !ok {
+			result.Approvals = append(result.Approvals, SimulationApproval{
+				Round:    round,
+				NodeKey:  task.NodeKey,
+				NodeName: task.NodeName,
+			})
+			i = len(result.Approvals) - 1
+			index[task.NodeKey] = i
+
+			chain, err := s.loadSimulationAssignees(tx, instance.GetApprovalChain(task.NodeKey))
+			if err != nil {
+				return err
+			}
+			result.Approvals[i].Chain = chain
+		}
+		approval := &result.Approvals[i]
+
+		if task.AssigneeID != 0 {
+			approval.Assignees = append(approval.Assignees, SimulationAssignee{
+				UserID:   task.AssigneeID,
+				Username: task.Assignee.Username,
+				FullName: task.Assignee.FullName,
+			})
+			continue
+		}
+
+		var candidates []models.WorkflowTaskCandidate
+		if err := tx.Preload("User").Where("task_id = ?", task.ID).Order("id").Find(&candidates).Error; err != nil {
+			return err
+		}
+		approval.Queue = true
+		for _, candidate := range candidates {
+			approval.Assignees = append(approval.Assignees, SimulationAssignee{
+				UserID:   candidate.UserID,
+				Username: candidate.User.Username,
+				FullName: candidate.User.FullName,
+			})
+		}
+	}
+	return nil
+}
+
+// loadSimulationAssignees 按顺序加载审批人信息
+func (s *WorkflowService) loadSimulationAssignees(tx *gorm.DB, userIDs []uint) ([]SimulationAssignee, error) {
+	if len(userIDs) == 0 {
+		return nil, nil
+	}
+	var users []models.User
+	if err := tx.Where("id IN ?", userIDs).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	userMap := make(map[uint]models.User, len(users))
+	for _, user := range users {
+		userMap[user.ID] = user
+	}
+
+	assignees := make([]SimulationAssignee, 0, len(userIDs))
+	for _, id := range userIDs {
+		user := userMap[id]
+		assignees = append(assignees, SimulationAssignee{UserID: id, Username: user.Username, FullName: user.FullName})
+	}
+	return assignees, nil
+}
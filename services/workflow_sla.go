@@ -0,0 +1,326 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"gin-web-api/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// applyTaskSLA 根据节点的时限设置计算任务的截止时间和优先级
+func (s *WorkflowService) applyTaskSLA(task *models.WorkflowTask, node models.WorkflowNode) {
+	settings, err := node.ParseSettings()
+	if err != nil || settings.SLA == nil {
+		return
+	}
+
+	task.Priority = settings.SLA.Priority
+	if settings.SLA.Duration == "" {
+		return
+	}
+	dueTime, err := calculateDueTime(time.Now(), settings.SLA)
+	if err != nil {
+		log.Printf("节点 %s 的处理时限配置无效: %v", node.NodeKey, err)
+		return
+	}
+	task.DueTime = &dueTime
+	task.SLACheckAt = &dueTime
+}
+
+// ProcessOverdueTasks 处理已超过检查时间的任务，返回处理的任务数量
+func (s *WorkflowService) ProcessOverdueTasks(now time.Time) (int, error) {
+	var taskIDs []uint
+	if err := s.db.Model(&models.WorkflowTask{}).
+		Joins("JOIN workflow_instances ON workflow_instances.id = workflow_tasks.instance_id").
+		Where("workflow_tasks.status IN ? AND workflow_tasks.sla_check_at <= ? AND workflow_instances.status = ?",
+			models.ActionableTaskStatuses, now, models.InstanceStatusRunning).
+		Order("workflow_tasks.sla_check_at").
+		Pluck("workflow_tasks.id", &taskIDs).Error; err != nil {
+		return 0, fmt.Errorf("查询超时任务失败: %w", err)
+	}
+
+	processed := 0
+	for _, taskID := range taskIDs {
+		if err := s.handleOverdueTask(taskID, now); err != nil {
+			log.Printf("处理超时任务 %d 失败: %v", taskID, err)
+			continue
+		}
+		processed++
+	}
+	return processed, nil
+}
+
+// handleOverdueTask 按节点的超时处理方式处理单个超时任务
+func (s *WorkflowService) handleOverdueTask(taskID uint, now time.Time) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var task models.WorkflowTask
+		if err := tx.First(&task, taskID).Error; err != nil {
+			return fmt.Errorf("任务不存在: %w", err)
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&task.Instance, task.InstanceID).Error; err != nil {
+			return fmt.Errorf("实例不存在: %w", err)
+		}
+
+		// 加锁后重新检查，避免与人工处理或其他副本重复处理
+		if err := tx.First(&task, taskID).Error; err != nil {
+			return err
+		}
+		if !task.Status.IsActionable() || task.SLACheckAt == nil || task.SLACheckAt.After(now) ||
+			task.Instance.Status != models.InstanceStatusRunning {
+			return nil
+		}
+
+		var node models.WorkflowNode
+		if err := tx.Where("workflow_id = ? AND node_key = ?", task.Instance.WorkflowID, task.NodeKey).First(&node).Error; err != nil {
+			return fmt.Errorf("找不到节点配置: %w", err)
+		}
+		settings, err := node.ParseSettings()
+		if err != nil {
+			return fmt.Errorf("解析节点设置失败: %w", err)
+		}
+		if settings.SLA == nil {
+			return tx.Model(&task).Update("sla_check_at", nil).Error
+		}
+
+		switch settings.SLA.Action {
+		case models.SLAActionAutoApprove:
+			return s.approveTaskInTx(tx, &task, models.SystemOperatorID, "超时自动通过", "处理超时，系统自动通过", "")
+
+		case models.SLAActionAutoReject:
+			return s.rejectTaskInTx(tx, &task, models.SystemOperatorID, "超时自动拒绝", "处理超时，系统自动拒绝", "")
+
+		case models.SLAActionEscalate:
+			managerID := s.findManagerInTx(tx, task.AssigneeID)
+			if managerID != 0 {
+				return s.reassignOverdueTaskInTx(tx, &task, settings.SLA, managerID, "超时升级", now)
+			}
+
+		case models.SLAActionReassign:
+			if settings.SLA.ReassignTo != 0 && settings.SLA.ReassignTo != task.AssigneeID {
+				return s.reassignOverdueTaskInTx(tx, &task, settings.SLA, settings.SLA.ReassignTo, "超时转交", now)
+			}
+		}
+
+		// 提醒处理人，找不到升级或转交对象时同样只做提醒
+		return s.remindOverdueTaskInTx(tx, &task, settings.SLA, now)
+	})
+}
+
+// remindOverdueTaskInTx 超时提醒：记录提醒并按提醒间隔安排下次检查
+func (s *WorkflowService) remindOverdueTaskInTx(tx *gorm.DB, task *models.WorkflowTask, sla *models.SLASettings, now time.Time) error {
+	task.SLACheckAt = nil
+	if sla.RemindInterval != "" {
+		interval, err := parseSLADuration(sla.RemindInterval)
+		if err != nil {
+			return fmt.Errorf("提醒间隔配置无效: %w", err)
+		}
+		next := now.Add(interval)
+		task.SLACheckAt = &next
+	}
+	if err := tx.Save(task).Error; err != nil {
+		return fmt.Errorf("更新任务失败: %w", err)
+	}
+
+	comment := "任务已超过处理时限，请尽快处理"
+	if task.DueTime != nil {
+		comment = fmt.Sprintf("任务已超过处理时限（%s），请尽快处理", task.DueTime.Format("2006-01-02 15:04"))
+	}
+	if err := s.recordHistoryInTx(tx, task.InstanceID, task.NodeKey, "超时提醒", models.SystemOperatorID, comment, "", ""); err != nil {
+		return err
+	}
+
+	recipients, err := s.taskRecipientsInTx(tx, task)
+	if err != nil {
+		return err
+	}
+	return s.notifyInTx(tx, models.NotificationTaskReminder, &task.Instance, task, recipients)
+}
+
+// reassignOverdueTaskInTx 将超时任务转给其他用户处理，并重新计算截止时间
+func (s *WorkflowService) reassignOverdueTaskInTx(tx *gorm.DB, task *models.WorkflowTask, sla *models.SLASettings, targetUserID uint, action string, now time.Time) error {
+	target, err := s.loadTargetUserInTx(tx, task, targetUserID)
+	if err != nil {
+		// 目标用户不可用时退化为提醒
+		log.Printf("超时任务 %d 无法转给用户 %d: %v", task.ID, targetUserID, err)
+		return s.remindOverdueTaskInTx(tx, task, sla, now)
+	}
+
+	if task.AssigneeID == 0 {
+		task.Status = models.TaskStatusClaimed
+	}
+	task.AssigneeID = target.ID
+	task.DelegatorID = nil
+	task.SLACheckAt = nil
+	if dueTime, err := calculateDueTime(now, sla); err == nil {
+		task.DueTime = &dueTime
+		task.SLACheckAt = &dueTime
+	}
+	if err := tx.Save(task).Error; err != nil {
+		return fmt.Errorf("更新任务失败: %w", err)
+	}
+
+	if err := s.recordHistoryInTx(tx, task.InstanceID, task.NodeKey, action, models.SystemOperatorID,
+		fmt.Sprintf("处理超时，转给%s处理", userDisplayName(target)), "", ""); err != nil {
+		return err
+	}
+	if err := s.notifyInTx(tx, models.NotificationTaskEscalated, &task.Instance, task, []uint{target.ID}); err != nil {
+		return err
+	}
+	return s.publishEventInTx(tx, models.EventTaskAssigned, &task.Instance, task, models.SystemOperatorID)
+}
+
+// findManagerInTx 查找用户所在部门（优先主部门）的负责人，用户本人是负责人时向上级部门查找
+func (s *WorkflowService) findManagerInTx(tx *gorm.DB, userID uint) uint {
+	if userID == 0 {
+		return 0
+	}
+
+	var userDepartments []models.UserDepartment
+	if err := tx.Where("user_id = ?", userID).Order("is_main DESC").Find(&userDepartments).Error; err != nil {
+		return 0
+	}
+
+	for _, userDepartment := range userDepartments {
+		departmentID := &userDepartment.DepartmentID
+		// 限制层级，避免部门数据成环
+		for depth := 0; departmentID != nil && depth < 20; depth++ {
+			var department models.Department
+			if err := tx.First(&department, *departmentID).Error; err != nil {
+				break
+			}
+			if department.ManagerID != nil && *department.ManagerID != userID {
+				return *department.ManagerID
+			}
+			departmentID = department.ParentID
+		}
+	}
+	return 0
+}
+
+// calculateDueTime 根据时限设置计算截止时间
+func calculateDueTime(start time.Time, sla *models.SLASettings) (time.Time, error) {
+	duration, err := parseSLADuration(sla.Duration)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if sla.Calendar == nil {
+		return start.Add(duration), nil
+	}
+	return addBusinessDuration(start, duration, sla.Calendar)
+}
+
+// parseSLADuration 解析时长，除标准格式（如 "4h30m"）外支持以 d 表示天数（如 "2d"）
+func parseSLADuration(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, errors.New("未配置处理时限")
+	}
+	var duration time.Duration
+	if strings.HasSuffix(value, "d") {
+		days, err := strconv.ParseFloat(strings.TrimSuffix(value, "d"), 64)
+		if err != nil {
+			return 0, fmt.Errorf("无效的时长: %s", value)
+		}
+		duration = time.Duration(days * float64(24*time.Hour))
+	} else {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return 0, fmt.Errorf("无效的时长: %s", value)
+		}
+		duration = parsed
+	}
+	if duration <= 0 {
+		return 0, fmt.Errorf("时长必须大于0: %s", value)
+	}
+	return duration, nil
+}
+
+// addBusinessDuration 按工作时间日历计算经过指定工作时长后的时间
+func addBusinessDuration(start time.Time, duration time.Duration, calendar *models.BusinessCalendar) (time.Time, error) {
+	location := time.Local
+	if calendar.Timezone != "" {
+		loc, err := time.LoadLocation(calendar.Timezone)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("无效的时区: %s", calendar.Timezone)
+		}
+		location = loc
+	}
+
+	startHour, startMinute, err := parseClock(calendar.StartTime, "09:00")
+	if err != nil {
+		return time.Time{}, err
+	}
+	endHour, endMinute, err := parseClock(calendar.EndTime, "18:00")
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	workDays := calendar.WorkDays
+	if len(workDays) == 0 {
+		workDays = []int{1, 2, 3, 4, 5}
+	}
+	isWorkDay := func(day time.Time) bool {
+		date := day.Format("2006-01-02")
+		for _, d := range calendar.ExtraWorkdays {
+			if d == date {
+				return true
+			}
+		}
+		for _, d := range calendar.Holidays {
+			if d == date {
+				return false
+			}
+		}
+		for _, weekday := range workDays {
+			if int(day.Weekday()) == weekday {
+				return true
+			}
+		}
+		return false
+	}
+
+	current := start.In(location)
+	remaining := duration
+	// 最多向后查找约十年的日期，避免日历配置导致没有工作日时死循环
+	for i := 0; i < 3660; i++ {
+		year, month, day := current.Date()
+		dayStart := time.Date(year, month, day, startHour, startMinute, 0, 0, location)
+		dayEnd := time.Date(year, month, day, endHour, endMinute, 0, 0, location)
+		nextDayStart := dayStart.AddDate(0, 0, 1)
+
+		if !isWorkDay(current) || !current.Before(dayEnd) {
+			current = nextDayStart
+			continue
+		}
+		if current.Before(dayStart) {
+			current = dayStart
+		}
+
+		available := dayEnd.Sub(current)
+		if remaining <= available {
+			return current.Add(remaining), nil
+		}
+		remaining -= available
+		current = nextDayStart
+	}
+	return time.Time{}, errors.New("工作时间日历中没有可用的工作日")
+}
+
+// parseClock 解析 "HH:MM" 格式的时间
+func parseClock(value, defaultValue string) (int, int, error) {
+	if value == "" {
+		value = defaultValue
+	}
+	clock, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, 0, fmt.Errorf("无效的时间: %s", value)
+	}
+	return clock.Hour(), clock.Minute(), nil
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"gin-web-api/models"
+)
+
+func TestAddBusinessDuration(t *testing.T) {
+	// 2024-03-01 是周五
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, 3, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name     string
+		start    time.Time
+		duration time.Duration
+		calendar models.BusinessCalendar
+		want     time.Time
+	}{
+		{"当天工作时间内", at(1, 10, 0), 2 * time.Hour, models.BusinessCalendar{}, at(1, 12, 0)},
+		{"正好到下班时间", at(1, 9, 0), 9 * time.Hour, models.BusinessCalendar{}, at(1, 18, 0)},
+		{"跨周末", at(1, 16, 0), 4 * time.Hour, models.BusinessCalendar{}, at(4, 11, 0)},
+		{"上班前从上班时间开始", at(1, 7, 30), time.Hour, models.BusinessCalendar{}, at(1, 10, 0)},
+		{"下班后从下个工作日开始", at(1, 19, 0), time.Hour, models.BusinessCalendar{}, at(4, 10, 0)},
+		{"周末从周一开始", at(2, 10, 0), 30 * time.Minute, models.BusinessCalendar{}, at(4, 9, 30)},
+		{"多个工作日", at(4, 9, 0), 3 * 9 * time.Hour, models.BusinessCalendar{}, at(6, 18, 0)},
+		{"跳过节假日", at(1, 17, 0), 2 * time.Hour, models.BusinessCalendar{Holidays: []string{"2024-03-04"}}, at(5, 10, 0)},
+		{"调休上班日", at(1, 17, 0), 2 * time.Hour, models.BusinessCalendar{ExtraWorkdays: []string{"2024-03-02"}}, at(2, 10, 0)},
+		{
+			"自定义工作日和工作时间", at(1, 10, 0), 4 * time.Hour,
+			models.BusinessCalendar{WorkDays: []int{6}, StartTime: "08:30", EndTime: "12:00"}, at(9, 9, 0),
+		},
+		{
+			"按日历时区计算", time.Date(2024, 3, 1, 17, 0, 0, 0, time.FixedZone("UTC+8", 8*3600)), time.Hour,
+			models.BusinessCalendar{Timezone: "UTC"}, at(1, 10, 0),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := addBusinessDuration(tt.start, tt.duration, &tt.calendar)
+			if err != nil {
+				t.Fatalf("addBusinessDuration() error = %v", err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("addBusinessDuration() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddBusinessDurationInvalidCalendar(t *testing.T) {
+	tests := []struct {
+		name     string
+		calendar models.BusinessCalendar
+	}{
+		{"无效的时区", models.BusinessCalendar{Timezone: "Mars/Base"}},
+		{"无效的上班时间", models.BusinessCalendar{StartTime: "9点"}},
+		{"无效的下班时间", models.BusinessCalendar{EndTime: "25:00"}},
+		{"没有工作日", models.BusinessCalendar{WorkDays: []int{7}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := addBusinessDuration(time.Now(), time.Hour, &tt.calendar); err == nil {
+				t.Error("addBusinessDuration() 应返回错误")
+			}
+		})
+	}
+}
@@ -0,0 +1,340 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"gin-web-api/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 加签位置
+const (
+	AddSignBefore = "before" // 前加签：加签人先审批，全部通过后交还当前审批人
+	AddSignAfter  = "after"  // 后加签：当前审批人通过后，再由加签人审批
+)
+
+// TransferTask 转办任务：将待处理任务交给其他用户处理，原处理人不再参与
+func (s *WorkflowService) TransferTask(taskID, userID, targetUserID uint, comment string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		task, err := s.loadOperableTaskInTx(tx, taskID, userID)
+		if err != nil {
+			return err
+		}
+		if task.DelegatorID != nil {
+			return errors.New("委托中的任务不能转办")
+		}
+
+		target, err := s.loadTargetUserInTx(tx, task, targetUserID)
+		if err != nil {
+			return err
+		}
+
+		task.AssigneeID = target.ID
+		if err := tx.Save(task).Error; err != nil {
+			return fmt.Errorf("转办任务失败: %w", err)
+		}
+
+		if err := s.recordHistoryInTx(tx, task.InstanceID, task.NodeKey, "转办", userID,
+			fmt.Sprintf("转办给%s: %s", userDisplayName(target), comment), "", ""); err != nil {
+			return err
+		}
+		return s.publishEventInTx(tx, models.EventTaskAssigned, &task.Instance, task, userID)
+	})
+}
+
+// DelegateTask 委托任务：被委托人处理后，任务交还原处理人继续审批
+func (s *WorkflowService) DelegateTask(taskID, userID, targetUserID uint, comment string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		task, err := s.loadOperableTaskInTx(tx, taskID, userID)
+		if err != nil {
+			return err
+		}
+		if task.DelegatorID != nil {
+			return errors.New("委托中的任务不能再次委托")
+		}
+
+		target, err := s.loadTargetUserInTx(tx, task, targetUserID)
+		if err != nil {
+			return err
+		}
+
+		delegatorID := task.AssigneeID
+		task.DelegatorID = &delegatorID
+		task.AssigneeID = target.ID
+		if err := tx.Save(task).Error; err != nil {
+			return fmt.Errorf("委托任务失败: %w", err)
+		}
+
+		if err := s.recordHistoryInTx(tx, task.InstanceID, task.NodeKey, "委托", userID,
+			fmt.Sprintf("委托给%s: %s", userDisplayName(target), comment), "", ""); err != nil {
+			return err
+		}
+		return s.publishEventInTx(tx, models.EventTaskAssigned, &task.Instance, task, userID)
+	})
+}
+
+// AddSigner 加签：在当前审批人之前或之后增加审批人，加签人须全部审批通过后节点才能完成
+func (s *WorkflowService) AddSigner(taskID, userID uint, signerIDs []uint, position, comment string) error {
+	if position != AddSignBefore && position != AddSignAfter {
+		return fmt.Errorf("不支持的加签位置: %s", position)
+	}
+	if len(signerIDs) == 0 {
+		return errors.New("请选择加签人")
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		task, err := s.loadOperableTaskInTx(tx, taskID, userID)
+		if err != nil {
+			return err
+		}
+		if task.DelegatorID != nil {
+			return errors.New("委托中的任务不能加签")
+		}
+
+		// 前加签的任务立即由加签人处理，后加签的任务等待当前审批人通过
+		status := models.TaskStatusPending
+		if position == AddSignAfter {
+			status = models.TaskStatusWaiting
+		}
+
+		var names []string
+		for _, signerID := range signerIDs {
+			signer, err := s.loadTargetUserInTx(tx, task, signerID)
+			if err != nil {
+				return err
+			}
+			signTask := &models.WorkflowTask{
+				InstanceID:   task.InstanceID,
+				NodeKey:      task.NodeKey,
+				NodeName:     task.NodeName,
+				AssigneeID:   signer.ID,
+				Status:       status,
+				ParentTaskID: &task.ID,
+				SignType:     position,
+				DueTime:      task.DueTime,
+				SLACheckAt:   task.SLACheckAt,
+				Priority:     task.Priority,
+			}
+			if err := tx.Create(signTask).Error; err != nil {
+				return fmt.Errorf("创建加签任务失败: %w", err)
+			}
+			if err := s.publishEventInTx(tx, models.EventTaskCreated, nil, signTask, userID); err != nil {
+				return err
+			}
+			names = append(names, userDisplayName(signer))
+		}
+
+		if position == AddSignBefore {
+			task.Status = models.TaskStatusWaiting
+			if err := tx.Save(task).Error; err != nil {
+				return fmt.Errorf("更新任务失败: %w", err)
+			}
+		}
+
+		action := "前加签"
+		if position == AddSignAfter {
+			action = "后加签"
+		}
+		return s.recordHistoryInTx(tx, task.InstanceID, task.NodeKey, action, userID,
+			fmt.Sprintf("加签%s: %s", strings.Join(names, "、"), comment), "", "")
+	})
+}
+
+// resolveDelegatedTaskInTx 被委托人处理委托任务，记录处理意见后将任务交还委托人
+func (s *WorkflowService) resolveDelegatedTaskInTx(tx *gorm.DB, task *models.WorkflowTask, userID uint, action, comment string) error {
+	task.AssigneeID = *task.DelegatorID
+	task.DelegatorID = nil
+	task.Comment = comment
+	if err := tx.Save(task).Error; err != nil {
+		return fmt.Errorf("交还委托任务失败: %w", err)
+	}
+	if err := s.recordHistoryInTx(tx, task.InstanceID, task.NodeKey, action, userID, comment, "", ""); err != nil {
+		return err
+	}
+	return s.publishEventInTx(tx, models.EventTaskAssigned, &task.Instance, task, userID)
+}
+
+// activateSignTasksInTx 任务审批通过后激活相关加签任务：
+// 后加签人的任务开始处理；前加签人全部通过后，原审批人的任务恢复待处理
+func (s *WorkflowService) activateSignTasksInTx(tx *gorm.DB, task *models.WorkflowTask) error {
+	if err := tx.Model(&models.WorkflowTask{}).
+		Where("parent_task_id = ? AND status = ?", task.ID, models.TaskStatusWaiting).
+		Update("status", models.TaskStatusPending).Error; err != nil {
+		return fmt.Errorf("激活加签任务失败: %w", err)
+	}
+
+	if task.ParentTaskID == nil {
+		return nil
+	}
+
+	var openCount int64
+	if err := tx.Model(&models.WorkflowTask{}).
+		Where("parent_task_id = ? AND id <> ? AND status IN ?", *task.ParentTaskID, task.ID, models.OpenTaskStatuses).
+		Count(&openCount).Error; err != nil {
+		return err
+	}
+	if openCount > 0 {
+		return nil
+	}
+
+	return tx.Model(&models.WorkflowTask{}).
+		Where("id = ? AND status = ?", *task.ParentTaskID, models.TaskStatusWaiting).
+		Update("status", models.TaskStatusPending).Error
+}
+
+// ClaimTask 认领任务：候选人认领待认领任务后成为处理人，同一任务只能被一人认领
+func (s *WorkflowService) ClaimTask(taskID, userID uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var task models.WorkflowTask
+		if err := tx.Preload("Instance").First(&task, taskID).Error; err != nil {
+			return fmt.Errorf("任务不存在: %w", err)
+		}
+		if err := checkInstanceRunning(&task.Instance); err != nil {
+			return err
+		}
+
+		var count int64
+		if err := tx.Model(&models.WorkflowTaskCandidate{}).
+			Where("task_id = ? AND user_id = ?", taskID, userID).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return errors.New("不是该任务的候选人，无法认领")
+		}
+
+		// 以条件更新保证并发认领时只有一人成功
+		result := tx.Model(&models.WorkflowTask{}).
+			Where("id = ? AND assignee_id = 0 AND status = ?", taskID, models.TaskStatusPending).
+			Updates(map[string]interface{}{"assignee_id": userID, "status": models.TaskStatusClaimed})
+		if result.Error != nil {
+			return fmt.Errorf("认领任务失败: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("任务已被认领或已处理")
+		}
+
+		if err := s.recordHistoryInTx(tx, task.InstanceID, task.NodeKey, "认领", userID, "", "", ""); err != nil {
+			return err
+		}
+		task.AssigneeID = userID
+		task.Status = models.TaskStatusClaimed
+		return s.publishEventInTx(tx, models.EventTaskAssigned, &task.Instance, &task, userID)
+	})
+}
+
+// ReleaseTask 释放任务：将已认领的任务退回候选人队列
+func (s *WorkflowService) ReleaseTask(taskID, userID uint, comment string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var task models.WorkflowTask
+		if err := tx.Preload("Instance").First(&task, taskID).Error; err != nil {
+			return fmt.Errorf("任务不存在: %w", err)
+		}
+		if err := checkInstanceRunning(&task.Instance); err != nil {
+			return err
+		}
+		if task.DelegatorID != nil {
+			return errors.New("委托中的任务不能释放")
+		}
+
+		result := tx.Model(&models.WorkflowTask{}).
+			Where("id = ? AND assignee_id = ? AND status = ?", taskID, userID, models.TaskStatusClaimed).
+			Updates(map[string]interface{}{"assignee_id": 0, "status": models.TaskStatusPending})
+		if result.Error != nil {
+			return fmt.Errorf("释放任务失败: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("只能释放自己认领的任务")
+		}
+
+		if err := s.recordHistoryInTx(tx, task.InstanceID, task.NodeKey, "释放", userID, comment, "", ""); err != nil {
+			return err
+		}
+		task.AssigneeID = 0
+		task.Status = models.TaskStatusPending
+		return s.publishEventInTx(tx, models.EventTaskAssigned, &task.Instance, &task, userID)
+	})
+}
+
+// createQueueTaskInTx 创建待认领任务，并记录可认领的候选人
+func (s *WorkflowService) createQueueTaskInTx(tx *gorm.DB, instance *models.WorkflowInstance, node models.WorkflowNode, candidateIDs []uint) error {
+	task := &models.WorkflowTask{
+		InstanceID: instance.ID,
+		NodeKey:    node.NodeKey,
+		NodeName:   node.Name,
+		Status:     models.TaskStatusPending,
+	}
+	s.applyTaskSLA(task, node)
+	if err := tx.Create(task).Error; err != nil {
+		return fmt.Errorf("创建待认领任务失败: %w", err)
+	}
+
+	seen := make(map[uint]bool)
+	for _, userID := range candidateIDs {
+		if seen[userID] {
+			continue
+		}
+		seen[userID] = true
+		if err := tx.Create(&models.WorkflowTaskCandidate{TaskID: task.ID, UserID: userID}).Error; err != nil {
+			return fmt.Errorf("创建任务候选人失败: %w", err)
+		}
+	}
+	return s.publishEventInTx(tx, models.EventTaskCreated, instance, task, models.SystemOperatorID)
+}
+
+// loadOperableTaskInTx 获取当前用户可操作的待处理任务，并锁定所属实例
+func (s *WorkflowService) loadOperableTaskInTx(tx *gorm.DB, taskID, userID uint) (*models.WorkflowTask, error) {
+	var task models.WorkflowTask
+	if err := tx.First(&task, taskID).Error; err != nil {
+		return nil, fmt.Errorf("任务不存在: %w", err)
+	}
+	if task.AssigneeID != userID {
+		return nil, errors.New("无权限处理此任务")
+	}
+	if !task.Status.IsActionable() {
+		return nil, errors.New("任务已处理")
+	}
+
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&task.Instance, task.InstanceID).Error; err != nil {
+		return nil, fmt.Errorf("实例不存在: %w", err)
+	}
+	if err := checkInstanceRunning(&task.Instance); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// loadTargetUserInTx 获取转办、委托或加签的目标用户，目标用户不能已在本节点有未处理的任务
+func (s *WorkflowService) loadTargetUserInTx(tx *gorm.DB, task *models.WorkflowTask, targetUserID uint) (*models.User, error) {
+	var user models.User
+	if err := tx.First(&user, targetUserID).Error; err != nil {
+		return nil, fmt.Errorf("目标用户不存在: %w", err)
+	}
+	if !user.IsActive {
+		return nil, fmt.Errorf("目标用户已停用: %s", userDisplayName(&user))
+	}
+
+	var count int64
+	if err := tx.Model(&models.WorkflowTask{}).
+		Where("instance_id = ? AND node_key = ? AND assignee_id = ? AND status IN ?",
+			task.InstanceID, task.NodeKey, targetUserID, models.OpenTaskStatuses).
+		Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, fmt.Errorf("用户%s在当前节点已有待处理任务", userDisplayName(&user))
+	}
+	return &user, nil
+}
+
+// userDisplayName 获取用户显示名称
+func userDisplayName(user *models.User) string {
+	if user.FullName != "" {
+		return user.FullName
+	}
+	return user.Username
+}
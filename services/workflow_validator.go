@@ -0,0 +1,406 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gin-web-api/models"
+
+	"gorm.io/gorm"
+)
+
+// 校验问题级别
+const (
+	ValidationLevelError   = "error"   // 错误：不能发布
+	ValidationLevelWarning = "warning" // 警告：可以发布，建议修改
+)
+
+// ValidateWorkflowRequest 工作流校验请求：校验已保存的工作流，或校验未保存的节点树
+type ValidateWorkflowRequest struct {
+	WorkflowID uint                 `json:"workflow_id"`
+	NodeTree   *models.NodeTreeData `json:"node_tree"`
+	FormID     *uint                `json:"form_id"` // 校验节点树时关联的表单，用于检查条件引用的表单字段
+}
+
+// WorkflowValidationIssue 校验发现的问题
+type WorkflowValidationIssue struct {
+	Level    string `json:"level"`
+	Code     string `json:"code"`
+	Path     string `json:"path"` // 节点在节点树中的路径，如 node_tree.child.branches[0].child
+	NodeKey  string `json:"node_key,omitempty"`
+	NodeName string `json:"node_name,omitempty"`
+	Message  string `json:"message"`
+}
+
+// WorkflowValidationResult 工作流校验结果
+type WorkflowValidationResult struct {
+	Valid  bool                      `json:"valid"`
+	Issues []WorkflowValidationIssue `json:"issues"`
+}
+
+// WorkflowValidationError 工作流校验未通过
+type WorkflowValidationError struct {
+	Issues []WorkflowValidationIssue
+}
+
+func (e *WorkflowValidationError) Error() string {
+	var messages []string
+	for _, issue := range e.Issues {
+		if issue.Level == ValidationLevelError {
+			messages = append(messages, issue.Message)
+		}
+	}
+	if len(messages) == 1 {
+		return "工作流校验未通过: " + messages[0]
+	}
+	return fmt.Sprintf("工作流校验未通过: %s 等%d个问题", messages[0], len(messages))
+}
+
+// ValidateWorkflow 校验工作流定义：节点标识、结束节点、不可达节点、条件分支、审批人及条件引用的表单字段
+func (s *WorkflowService) ValidateWorkflow(req *ValidateWorkflowRequest) (*WorkflowValidationResult, error) {
+	return s.validateWorkflowInTx(s.db, req)
+}
+
+// validateWorkflowInTx 在事务中校验工作流定义
+func (s *WorkflowService) validateWorkflowInTx(tx *gorm.DB, req *ValidateWorkflowRequest) (*WorkflowValidationResult, error) {
+	nodeTree := req.NodeTree
+	formID := req.FormID
+	var nodes []models.WorkflowNode
+
+	if req.WorkflowID != 0 {
+		var workflow models.WorkflowDefinition
+		if err := tx.Preload("Nodes.Branches").First(&workflow, req.WorkflowID).Error; err != nil {
+			return nil, fmt.Errorf("工作流不存在: %w", err)
+		}
+		parsed, err := workflow.ParseNodeTree()
+		if err != nil {
+			return nil, fmt.Errorf("解析节点树失败: %w", err)
+		}
+		if parsed == nil {
+			// 按节点列表创建的工作流不做节点树校验
+			return &WorkflowValidationResult{Valid: true, Issues: []WorkflowValidationIssue{}}, nil
+		}
+		fillNodeTreeProps(parsed, workflow.Nodes)
+		nodeTree = parsed
+		formID = workflow.FormID
+		nodes = workflow.Nodes
+	}
+	if nodeTree.IsEmpty() {
+		return nil, errors.New("请提供工作流ID或节点树")
+	}
+
+	v := &workflowValidator{keys: make(map[string]string)}
+	if formID != nil {
+		var form models.FormDefinition
+		if err := tx.Preload("Cards.Attributes.Attribute").First(&form, *formID).Error; err != nil {
+			v.add(ValidationLevelError, "form_not_found", "node_tree", nil, "关联的表单不存在")
+		} else {
+			v.formFields = make(map[string]bool)
+			for _, card := range form.Cards {
+				for _, attr := range card.Attributes {
+					v.formFields[attr.Attribute.FieldKey] = true
+				}
+			}
+		}
+	}
+
+	v.validate(nodeTree, nodes)
+	return v.result(), nil
+}
+
+// workflowValidator 节点树校验器
+type workflowValidator struct {
+	formFields map[string]bool   // 关联表单的字段，nil 表示未关联表单
+	keys       map[string]string // 节点标识 -> 首次出现的路径
+	issues     []WorkflowValidationIssue
+}
+
+// add 记录校验问题
+func (v *workflowValidator) add(level, code, path string, node *models.NodeTreeData, message string) {
+	issue := WorkflowValidationIssue{Level: level, Code: code, Path: path, Message: message}
+	if node != nil {
+		issue.NodeKey = node.Key
+		issue.NodeName = node.Name
+	}
+	v.issues = append(v.issues, issue)
+}
+
+// result 生成校验结果，没有错误级别的问题即为通过
+func (v *workflowValidator) result() *WorkflowValidationResult {
+	result := &WorkflowValidationResult{Valid: true, Issues: v.issues}
+	if result.Issues == nil {
+		result.Issues = []WorkflowValidationIssue{}
+	}
+	for _, issue := range result.Issues {
+		if issue.Level == ValidationLevelError {
+			result.Valid = false
+			break
+		}
+	}
+	return result
+}
+
+// validate 校验整棵节点树
+func (v *workflowValidator) validate(root *models.NodeTreeData, nodes []models.WorkflowNode) {
+	if root.Type != models.NodeTypeRoot && root.Type != models.NodeTypeStart {
+		v.add(ValidationLevelError, "invalid_root", "node_tree", root, "根节点必须是开始节点（ROOT）")
+	}
+
+	v.walk(root, "node_tree", true)
+
+	// 主流程必须以结束节点结尾
+	last, lastPath := root, "node_tree"
+	for last.Type != models.NodeTypeEnd && !last.Child.IsEmpty() {
+		last, lastPath = last.Child, lastPath+".child"
+	}
+	if last.Type != models.NodeTypeEnd {
+		v.add(ValidationLevelError, "missing_end", lastPath, last, "流程没有到达结束节点（END）的路径")
+	}
+
+	// 节点记录不在节点树中，流程不会执行到
+	for _, node := range nodes {
+		if _, ok := v.keys[node.NodeKey]; !ok {
+			v.add(ValidationLevelWarning, "unreachable_node", "", &models.NodeTreeData{Key: node.NodeKey, Name: node.Name},
+				fmt.Sprintf("节点 %s 不在节点树中，不会被执行", node.Name))
+		}
+	}
+}
+
+// walk 递归校验节点及其子节点、分支
+func (v *workflowValidator) walk(node *models.NodeTreeData, path string, isRoot bool) {
+	if node == nil {
+		return
+	}
+	if node.Key == "" {
+		// 空对象（如 "child": {}）表示没有子节点
+		if node.Name != "" || node.Type != "" {
+			v.add(ValidationLevelError, "missing_key", path, node, fmt.Sprintf("节点 %s 缺少标识", node.Name))
+		}
+		return
+	}
+	v.checkKey(node, path)
+
+	switch node.Type {
+	case models.NodeTypeRoot, models.NodeTypeStart:
+		if !isRoot {
+			v.add(ValidationLevelError, "invalid_node_type", path, node, "开始节点只能作为根节点")
+		}
+	case models.NodeTypeEnd:
+		if !node.Child.IsEmpty() {
+			v.add(ValidationLevelError, "unreachable_node", path+".child", node.Child,
+				fmt.Sprintf("结束节点 %s 之后的节点不会被执行", node.Name))
+			return
+		}
+	case models.NodeTypeApproval:
+		v.checkAssignees(node, path)
+	case models.NodeTypeCondition:
+		v.checkConditionBranches(node, path)
+	case models.NodeTypeParallel:
+		if len(node.Branches) == 0 {
+			v.add(ValidationLevelWarning, "parallel_no_branches", path, node, fmt.Sprintf("并行节点 %s 没有分支", node.Name))
+		}
+	case models.NodeTypeMerge:
+	case models.NodeTypeService:
+		v.checkService(node, path)
+	default:
+		v.add(ValidationLevelError, "unknown_node_type", path, node, fmt.Sprintf("节点 %s 的类型 %s 不受支持", node.Name, node.Type))
+	}
+
+	if node.Props != nil {
+		if !isJSONOrEmpty(node.Props.Settings) {
+			v.add(ValidationLevelError, "invalid_settings", path, node, fmt.Sprintf("节点 %s 的设置不是有效的JSON", node.Name))
+		}
+		if node.Type != models.NodeTypeCondition {
+			v.checkConditionFields(node, path, node.Props.Conditions)
+		}
+		v.checkFieldPermissions(node, path)
+	}
+
+	for i := range node.Branches {
+		branch := &node.Branches[i]
+		branchPath := fmt.Sprintf("%s.branches[%d]", path, i)
+		if branch.Key == "" {
+			v.add(ValidationLevelError, "missing_key", branchPath, branch, fmt.Sprintf("分支 %s 缺少标识", branch.Name))
+		} else {
+			v.checkKey(branch, branchPath)
+		}
+		v.walk(branch.Child, branchPath+".child", false)
+	}
+	v.walk(node.Child, path+".child", false)
+}
+
+// checkKey 检查节点标识是否重复
+func (v *workflowValidator) checkKey(node *models.NodeTreeData, path string) {
+	if first, ok := v.keys[node.Key]; ok {
+		v.add(ValidationLevelError, "duplicate_key", path, node, fmt.Sprintf("节点标识 %s 重复（与 %s 相同）", node.Key, first))
+		return
+	}
+	v.keys[node.Key] = path
+}
+
+// checkAssignees 检查审批节点是否配置了审批人
+func (v *workflowValidator) checkAssignees(node *models.NodeTreeData, path string) {
+	var assignees string
+	if node.Props != nil {
+		assignees = propsJSON(node.Props.Assignees)
+	}
+	if assignees == "" {
+		v.add(ValidationLevelError, "approval_no_assignees", path, node, fmt.Sprintf("审批节点 %s 未配置审批人", node.Name))
+		return
+	}
+
+	var config AssigneeConfig
+	if err := json.Unmarshal([]byte(assignees), &config); err != nil {
+		v.add(ValidationLevelError, "invalid_assignees", path, node, fmt.Sprintf("审批节点 %s 的审批人配置无效", node.Name))
+		return
+	}
+
+	var ids []uint
+	switch config.Type {
+	case "users":
+		ids = config.UserIDs
+	case "roles":
+		ids = config.RoleIDs
+	case "departments":
+		ids = config.DepartmentIDs
+	default:
+		v.add(ValidationLevelError, "invalid_assignees", path, node, fmt.Sprintf("审批节点 %s 的审批人类型 %s 不受支持", node.Name, config.Type))
+		return
+	}
+	if len(ids) == 0 {
+		v.add(ValidationLevelError, "approval_no_assignees", path, node, fmt.Sprintf("审批节点 %s 未配置审批人", node.Name))
+	}
+}
+
+// checkService 检查服务节点的请求地址和错误分支
+func (v *workflowValidator) checkService(node *models.NodeTreeData, path string) {
+	var settings models.NodeSettings
+	if node.Props != nil {
+		if raw := propsJSON(node.Props.Settings); raw != "" && json.Unmarshal([]byte(raw), &settings) != nil {
+			// 设置不是有效JSON时由 invalid_settings 报告
+			return
+		}
+	}
+	service := settings.Service
+	if service == nil || service.URL == "" {
+		v.add(ValidationLevelError, "service_no_url", path, node, fmt.Sprintf("服务节点 %s 未配置请求地址", node.Name))
+		return
+	}
+
+	if service.ErrorBranch == "" {
+		if len(node.Branches) > 1 {
+			v.add(ValidationLevelError, "service_error_branch", path, node,
+				fmt.Sprintf("服务节点 %s 有多个分支，需要在设置中指定错误分支", node.Name))
+		}
+		return
+	}
+	if findServiceErrorBranch(node, service) == nil {
+		v.add(ValidationLevelError, "service_error_branch", path, node,
+			fmt.Sprintf("服务节点 %s 的错误分支 %s 不存在", node.Name, service.ErrorBranch))
+	}
+}
+
+// checkConditionBranches 检查条件节点的分支：至少一个分支，且有未配置条件的默认分支
+func (v *workflowValidator) checkConditionBranches(node *models.NodeTreeData, path string) {
+	if len(node.Branches) == 0 {
+		v.add(ValidationLevelError, "condition_no_branches", path, node, fmt.Sprintf("条件节点 %s 没有分支", node.Name))
+		return
+	}
+
+	defaults := 0
+	for i := range node.Branches {
+		branch := &node.Branches[i]
+		branchPath := fmt.Sprintf("%s.branches[%d]", path, i)
+
+		var raw json.RawMessage
+		if branch.Props != nil {
+			raw = branch.Props.Conditions
+		}
+		condition, err := ParseShowCondition(propsJSON(raw))
+		if err != nil {
+			v.add(ValidationLevelError, "invalid_condition", branchPath, branch, fmt.Sprintf("分支 %s 的条件配置无效", branch.Name))
+			continue
+		}
+		if IsEmptyCondition(condition) {
+			defaults++
+			if defaults > 1 {
+				v.add(ValidationLevelWarning, "unreachable_node", branchPath, branch,
+					fmt.Sprintf("分支 %s 与前面的分支都是默认分支，不会被执行", branch.Name))
+			}
+			continue
+		}
+		v.checkConditionFields(branch, branchPath, raw)
+	}
+
+	if defaults == 0 {
+		v.add(ValidationLevelError, "condition_no_default", path, node,
+			fmt.Sprintf("条件节点 %s 没有默认分支（未配置条件的分支）", node.Name))
+	}
+}
+
+// checkConditionFields 检查条件引用的表单字段是否存在于关联表单中
+func (v *workflowValidator) checkConditionFields(node *models.NodeTreeData, path string, raw json.RawMessage) {
+	condition, err := ParseShowCondition(propsJSON(raw))
+	if err != nil {
+		v.add(ValidationLevelError, "invalid_condition", path, node, fmt.Sprintf("节点 %s 的条件配置无效", node.Name))
+		return
+	}
+	if condition == nil {
+		return
+	}
+
+	for _, group := range condition.Groups {
+		for _, cond := range group.Conditions {
+			keyword := strings.TrimSpace(cond.Keyword)
+			if _, inner, ok := splitAggregateKeyword(keyword); ok {
+				keyword = inner
+			}
+			scope, fieldPath := splitConditionKeyword(keyword)
+			if scope != ConditionScopeForm {
+				continue
+			}
+			if v.formFields == nil {
+				v.add(ValidationLevelError, "unknown_form_field", path, node,
+					fmt.Sprintf("节点 %s 的条件引用了表单字段 %s，但工作流未关联表单", node.Name, fieldPath))
+				continue
+			}
+			field := strings.SplitN(fieldPath, ".", 2)[0]
+			if !v.formFields[field] {
+				v.add(ValidationLevelError, "unknown_form_field", path, node,
+					fmt.Sprintf("节点 %s 的条件引用的表单字段 %s 不在关联表单中", node.Name, field))
+			}
+		}
+	}
+}
+
+// checkFieldPermissions 检查节点的表单字段权限是否有效、字段是否存在于关联表单中
+func (v *workflowValidator) checkFieldPermissions(node *models.NodeTreeData, path string) {
+	permissions, err := ParseFieldPermissions(propsJSON(node.Props.FieldPermissions))
+	if err != nil {
+		v.add(ValidationLevelError, "invalid_field_permissions", path, node, fmt.Sprintf("节点 %s 的%s", node.Name, err.Error()))
+		return
+	}
+	if v.formFields == nil {
+		return
+	}
+	fields := make([]string, 0, len(permissions))
+	for field := range permissions {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		if !v.formFields[field] {
+			v.add(ValidationLevelError, "unknown_form_field", path, node,
+				fmt.Sprintf("节点 %s 的字段权限引用的表单字段 %s 不在关联表单中", node.Name, field))
+		}
+	}
+}
+
+// isJSONOrEmpty 判断节点配置是否为空或有效的JSON
+func isJSONOrEmpty(raw json.RawMessage) bool {
+	value := propsJSON(raw)
+	return value == "" || json.Valid([]byte(value))
+}
@@ -0,0 +1,232 @@
+package services
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+
+	"gin-web-api/models"
+)
+
+// validatorTestTree 开始 -> 给定节点 -> 结束
+func validatorTestTree(node string) string {
+	return `{"key":"root","name":"发起","type":"ROOT","child":` + node + `}`
+}
+
+func TestValidateWorkflowNodeTree(t *testing.T) {
+	setupTestDB(t)
+	s := NewWorkflowService()
+	form, err := NewFormService().CreateFormDefinition(&CreateFormRequest{Object: "t", Name: "采购", Key: "purchase", Cards: []CreateCardRequest{{
+		Name: "基本信息",
+		Attributes: []CreateAttributeRequest{{
+			Attribute: CreateFieldAttrRequest{Object: "t", Name: "金额", Key: "amount", Type: models.DataTypeNumber, Element: models.ElementTypeNumber},
+			Element:   models.ElementTypeNumber, Name: "金额", Show: true,
+		}},
+	}}}, 1)
+	if err != nil {
+		t.Fatalf("创建表单失败: %v", err)
+	}
+
+	const (
+		end      = `{"key":"end","name":"结束","type":"END"}`
+		approver = `"props":{"assignees":{"type":"users","user_ids":[2]}}`
+	)
+	tests := []struct {
+		name      string
+		tree      string
+		withForm  bool
+		wantCodes []string
+	}{
+		{
+			name: "有效的流程",
+			tree: validatorTestTree(`{"key":"a1","name":"审批","type":"approval",` + approver + `,"child":` + end + `}`),
+		},
+		{
+			name:      "根节点不是开始节点",
+			tree:      `{"key":"a1","name":"审批","type":"approval",` + approver + `,"child":` + end + `}`,
+			wantCodes: []string{"invalid_root"},
+		},
+		{
+			name:      "没有结束节点",
+			tree:      validatorTestTree(`{"key":"a1","name":"审批","type":"approval",` + approver + `}`),
+			wantCodes: []string{"missing_end"},
+		},
+		{
+			name:      "结束节点之后还有节点",
+			tree:      validatorTestTree(`{"key":"end","name":"结束","type":"END","child":{"key":"a1","name":"审批","type":"approval",` + approver + `}}`),
+			wantCodes: []string{"unreachable_node"},
+		},
+		{
+			name:      "空子节点对象表示没有子节点",
+			tree:      validatorTestTree(`{"key":"a1","name":"审批","type":"approval",` + approver + `,"child":{"key":"end","name":"结束","type":"END","child":{}}}`),
+			wantCodes: nil,
+		},
+		{
+			name:      "节点缺少标识",
+			tree:      validatorTestTree(`{"name":"审批","type":"approval",` + approver + `}`),
+			wantCodes: []string{"missing_end", "missing_key"},
+		},
+		{
+			name:      "节点标识重复",
+			tree:      validatorTestTree(`{"key":"a1","name":"审批","type":"approval",` + approver + `,"child":{"key":"a1","name":"复核","type":"approval",` + approver + `,"child":` + end + `}}`),
+			wantCodes: []string{"duplicate_key"},
+		},
+		{
+			name:      "开始节点不在根位置",
+			tree:      validatorTestTree(`{"key":"r2","name":"发起","type":"ROOT","child":` + end + `}`),
+			wantCodes: []string{"invalid_node_type"},
+		},
+		{
+			name:      "不支持的节点类型",
+			tree:      validatorTestTree(`{"key":"x","name":"抄送","type":"cc","child":` + end + `}`),
+			wantCodes: []string{"unknown_node_type"},
+		},
+		{
+			name:      "审批节点未配置审批人",
+			tree:      validatorTestTree(`{"key":"a1","name":"审批","type":"approval","props":{"assignees":{"type":"roles","role_ids":[]}},"child":` + end + `}`),
+			wantCodes: []string{"approval_no_assignees"},
+		},
+		{
+			name:      "审批人类型不受支持",
+			tree:      validatorTestTree(`{"key":"a1","name":"审批","type":"approval","props":{"assignees":{"type":"groups"}},"child":` + end + `}`),
+			wantCodes: []string{"invalid_assignees"},
+		},
+		{
+			name: "条件节点没有默认分支且引用了未关联表单的字段",
+			tree: validatorTestTree(`{"key":"c1","name":"金额判断","type":"condition","branches":[
+				{"key":"b1","name":"大额","type":"condition","props":{"conditions":{"groups":[{"conditions":[{"condition":"gt","keyword":"form.amount","value":1000}]}]}}}],
+				"child":` + end + `}`),
+			wantCodes: []string{"condition_no_default", "unknown_form_field"},
+		},
+		{
+			name:     "条件引用关联表单的字段",
+			withForm: true,
+			tree: validatorTestTree(`{"key":"c1","name":"金额判断","type":"condition","branches":[
+				{"key":"b1","name":"大额","type":"condition","props":{"conditions":{"groups":[{"conditions":[{"condition":"gt","keyword":"sum(form.amount)","value":1000}]}]}}},
+				{"key":"b2","name":"其他","type":"condition"}],
+				"child":` + end + `}`),
+		},
+		{
+			name:     "条件引用不在关联表单中的字段",
+			withForm: true,
+			tree: validatorTestTree(`{"key":"c1","name":"金额判断","type":"condition","branches":[
+				{"key":"b1","name":"天数","type":"condition","props":{"conditions":{"groups":[{"conditions":[{"condition":"gt","keyword":"form.days","value":3}]}]}}},
+				{"key":"b2","name":"其他","type":"condition"},
+				{"key":"b3","name":"兜底","type":"condition"}],
+				"child":` + end + `}`),
+			wantCodes: []string{"unknown_form_field", "unreachable_node"},
+		},
+		{
+			name:      "条件节点没有分支",
+			tree:      validatorTestTree(`{"key":"c1","name":"金额判断","type":"condition","child":` + end + `}`),
+			wantCodes: []string{"condition_no_branches"},
+		},
+		{
+			name:      "并行节点没有分支",
+			tree:      validatorTestTree(`{"key":"p1","name":"会签","type":"parallel","child":` + end + `}`),
+			wantCodes: []string{"parallel_no_branches"},
+		},
+		{
+			name:      "分支缺少标识",
+			tree:      validatorTestTree(`{"key":"p1","name":"会签","type":"parallel","branches":[{"name":"财务","child":{"key":"a1","name":"审批","type":"approval",` + approver + `}}],"child":` + end + `}`),
+			wantCodes: []string{"missing_key"},
+		},
+		{
+			name:      "服务节点未配置请求地址",
+			tree:      validatorTestTree(`{"key":"s1","name":"同步","type":"service","child":` + end + `}`),
+			wantCodes: []string{"service_no_url"},
+		},
+		{
+			name: "服务节点的错误分支不存在",
+			tree: validatorTestTree(`{"key":"s1","name":"同步","type":"service","props":{"settings":{"service":{"url":"https://erp.example.com","error_branch":"missing"}}},
+				"branches":[{"key":"s1_error","name":"失败","type":"condition"}],"child":` + end + `}`),
+			wantCodes: []string{"service_error_branch"},
+		},
+		{
+			name:      "字段权限引用不在关联表单中的字段",
+			withForm:  true,
+			tree:      validatorTestTree(`{"key":"a1","name":"审批","type":"approval","props":{"assignees":{"type":"users","user_ids":[2]},"field_permissions":{"amount":"editable","days":"hidden"}},"child":` + end + `}`),
+			wantCodes: []string{"unknown_form_field"},
+		},
+		{
+			name:      "字段权限无效",
+			tree:      validatorTestTree(`{"key":"a1","name":"审批","type":"approval","props":{"assignees":{"type":"users","user_ids":[2]},"field_permissions":{"amount":"write"}},"child":` + end + `}`),
+			wantCodes: []string{"invalid_field_permissions"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &ValidateWorkflowRequest{NodeTree: mustValidatorTree(t, tt.tree)}
+			if tt.withForm {
+				req.FormID = &form.ID
+			}
+
+			result, err := s.validateWorkflowInTx(s.db, req)
+			if err != nil {
+				t.Fatalf("validateWorkflowInTx() error = %v", err)
+			}
+			var codes []string
+			seen := make(map[string]bool)
+			errorCount := 0
+			for _, issue := range result.Issues {
+				if issue.Level == ValidationLevelError {
+					errorCount++
+				}
+				if !seen[issue.Code] {
+					seen[issue.Code] = true
+					codes = append(codes, issue.Code)
+				}
+			}
+			sort.Strings(codes)
+			if !reflect.DeepEqual(codes, tt.wantCodes) {
+				t.Errorf("问题 = %v, want %v (%+v)", codes, tt.wantCodes, result.Issues)
+			}
+			if result.Valid != (errorCount == 0) {
+				t.Errorf("Valid = %v, 错误数 = %d", result.Valid, errorCount)
+			}
+		})
+	}
+}
+
+func TestValidateWorkflowSavedWorkflow(t *testing.T) {
+	setupTestDB(t)
+	s := NewWorkflowService()
+
+	tree := mustValidatorTree(t, validatorTestTree(`{"key":"a1","name":"审批","type":"approval","child":{"key":"end","name":"结束","type":"END"}}`))
+	workflow, err := s.CreateWorkflowWithNodeTree(&CreateWorkflowWithTreeRequest{Name: "采购", NodeTree: tree}, 1)
+	if err != nil {
+		t.Fatalf("创建工作流失败: %v", err)
+	}
+
+	// 节点树中的审批人保存在节点记录上，校验已保存的工作流时需要合并回节点树
+	result, err := s.validateWorkflowInTx(s.db, &ValidateWorkflowRequest{WorkflowID: workflow.ID})
+	if err != nil {
+		t.Fatalf("validateWorkflowInTx() error = %v", err)
+	}
+	if result.Valid || len(result.Issues) != 1 || result.Issues[0].Code != "approval_no_assignees" || result.Issues[0].NodeKey != "a1" {
+		t.Fatalf("校验结果 = %+v", result)
+	}
+	if result.Issues[0].Path != "node_tree.child" {
+		t.Errorf("Path = %q, want node_tree.child", result.Issues[0].Path)
+	}
+
+	if _, err := s.validateWorkflowInTx(s.db, &ValidateWorkflowRequest{}); err == nil {
+		t.Error("未提供工作流和节点树时应返回错误")
+	}
+	if _, err := s.validateWorkflowInTx(s.db, &ValidateWorkflowRequest{WorkflowID: 999}); err == nil {
+		t.Error("工作流不存在时应返回错误")
+	}
+}
+
+// mustValidatorTree 解析节点树JSON
+func mustValidatorTree(t *testing.T, data string) *models.NodeTreeData {
+	t.Helper()
+
+	var tree models.NodeTreeData
+	if err := json.Unmarshal([]byte(data), &tree); err != nil {
+		t.Fatalf("节点树格式错误: %v", err)
+	}
+	return &tree
+}
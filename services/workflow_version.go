@@ -0,0 +1,570 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gin-web-api/models"
+
+	"gorm.io/gorm"
+)
+
+// CreateWorkflowVersionRequest 基于已有版本创建新版本的请求，未提供的字段沿用来源版本
+type CreateWorkflowVersionRequest struct {
+	Name        string               `json:"name"`
+	Description string               `json:"description"`
+	Category    string               `json:"category"`
+	FormID      *uint                `json:"form_id"`
+	NodeTree    *models.NodeTreeData `json:"node_tree"`
+}
+
+// UpdateWorkflowDraftRequest 修改草稿版本的请求，未提供的字段保持不变
+type UpdateWorkflowDraftRequest struct {
+	Name        string               `json:"name"`
+	Description string               `json:"description"`
+	Category    string               `json:"category"`
+	FormID      *uint                `json:"form_id"`
+	NodeTree    *models.NodeTreeData `json:"node_tree"`
+}
+
+// WorkflowNodeDiff 两个版本间单个节点的差异
+type WorkflowNodeDiff struct {
+	Key    string   `json:"key"`
+	Name   string   `json:"name"`
+	Fields []string `json:"fields,omitempty"` // 发生变化的配置项
+}
+
+// WorkflowVersionDiff 两个版本的差异
+type WorkflowVersionDiff struct {
+	FromID       uint               `json:"from_id"`
+	FromVersion  int                `json:"from_version"`
+	ToID         uint               `json:"to_id"`
+	ToVersion    int                `json:"to_version"`
+	Fields       []string           `json:"fields"` // 工作流基本信息中发生变化的字段
+	AddedNodes   []WorkflowNodeDiff `json:"added_nodes"`
+	RemovedNodes []WorkflowNodeDiff `json:"removed_nodes"`
+	ChangedNodes []WorkflowNodeDiff `json:"changed_nodes"`
+}
+
+// CreateWorkflowVersion 基于已有版本创建新的草稿版本，节点配置按节点标识从来源版本复制
+func (s *WorkflowService) CreateWorkflowVersion(sourceID uint, req *CreateWorkflowVersionRequest, creatorID uint) (*models.WorkflowDefinition, error) {
+	var workflow *models.WorkflowDefinition
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var source models.WorkflowDefinition
+		if err := tx.Preload("Nodes.Branches").First(&source, sourceID).Error; err != nil {
+			return fmt.Errorf("工作流不存在: %w", err)
+		}
+
+		lineageID := source.GetLineageID()
+		var maxVersion int
+		if err := tx.Model(&models.WorkflowDefinition{}).
+			Where("lineage_id = ? OR id = ?", lineageID, lineageID).
+			Select("COALESCE(MAX(version), 0)").
+			Scan(&maxVersion).Error; err != nil {
+			return fmt.Errorf("查询版本号失败: %w", err)
+		}
+
+		workflow = &models.WorkflowDefinition{
+			Name:        source.Name,
+			Description: source.Description,
+			Category:    source.Category,
+			Version:     maxVersion + 1,
+			LineageID:   lineageID,
+			Status:      models.WorkflowStatusDraft,
+			FormID:      source.FormID,
+			CreatedBy:   creatorID,
+		}
+		if req.Name != "" {
+			workflow.Name = req.Name
+		}
+		if req.Description != "" {
+			workflow.Description = req.Description
+		}
+		if req.Category != "" {
+			workflow.Category = req.Category
+		}
+		if req.FormID != nil {
+			workflow.FormID = req.FormID
+		}
+		if err := tx.Create(workflow).Error; err != nil {
+			return fmt.Errorf("创建工作流版本失败: %w", err)
+		}
+
+		nodeTree := req.NodeTree
+		if nodeTree == nil {
+			parsed, err := source.ParseNodeTree()
+			if err != nil {
+				return fmt.Errorf("解析节点树失败: %w", err)
+			}
+			nodeTree = parsed
+		}
+
+		if err := s.rebuildNodesInTx(tx, workflow, nodeTree, source.Nodes); err != nil {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return workflow, nil
+}
+
+// UpdateWorkflowDraft 修改未发布的草稿版本，已发布的版本不可修改，需创建新版本
+func (s *WorkflowService) UpdateWorkflowDraft(workflowID uint, req *UpdateWorkflowDraftRequest) (*models.WorkflowDefinition, error) {
+	var workflow models.WorkflowDefinition
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Preload("Nodes.Branches").First(&workflow, workflowID).Error; err != nil {
+			return fmt.Errorf("工作流不存在: %w", err)
+		}
+		if workflow.IsPublished() || workflow.Status != models.WorkflowStatusDraft {
+			return errors.New("已发布的版本不可修改，请创建新版本")
+		}
+
+		if req.Name != "" {
+			workflow.Name = req.Name
+		}
+		if req.Description != "" {
+			workflow.Description = req.Description
+		}
+		if req.Category != "" {
+			workflow.Category = req.Category
+		}
+		if req.FormID != nil {
+			workflow.FormID = req.FormID
+		}
+
+		if req.NodeTree != nil {
+			oldNodes := workflow.Nodes
+			if err := s.deleteNodesInTx(tx, &workflow); err != nil {
+				return err
+			}
+			if err := s.rebuildNodesInTx(tx, &workflow, req.NodeTree, oldNodes); err != nil {
+				return err
+			}
+		}
+
+		workflow.Nodes = nil
+		if err := tx.Omit("Nodes", "RootNode", "Form", "Creator").Save(&workflow).Error; err != nil {
+			return fmt.Errorf("更新工作流失败: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &workflow, nil
+}
+
+// PublishWorkflow 发布草稿版本：发布前校验工作流定义，发布后版本不可修改，版本系列没有默认版本或 setDefault 为 true 时设为默认版本
+func (s *WorkflowService) PublishWorkflow(workflowID, userID uint, setDefault bool) (*models.WorkflowDefinition, error) {
+	var workflow models.WorkflowDefinition
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&workflow, workflowID).Error; err != nil {
+			return fmt.Errorf("工作流不存在: %w", err)
+		}
+		if workflow.IsPublished() {
+			return errors.New("该版本已发布")
+		}
+
+		var nodeCount int64
+		if err := tx.Model(&models.WorkflowNode{}).Where("workflow_id = ?", workflow.ID).Count(&nodeCount).Error; err != nil {
+			return err
+		}
+		if nodeCount == 0 {
+			return errors.New("工作流没有节点，无法发布")
+		}
+
+		result, err := s.validateWorkflowInTx(tx, &ValidateWorkflowRequest{WorkflowID: workflow.ID})
+		if err != nil {
+			return err
+		}
+		if !result.Valid {
+			return &WorkflowValidationError{Issues: result.Issues}
+		}
+
+		now := time.Now()
+		workflow.Status = models.WorkflowStatusActive
+		workflow.PublishedAt = &now
+		workflow.PublishedBy = &userID
+		if err := tx.Save(&workflow).Error; err != nil {
+			return fmt.Errorf("发布工作流失败: %w", err)
+		}
+
+		if !setDefault {
+			var defaultCount int64
+			if err := s.lineageQuery(tx, &workflow).
+				Where("is_default = ? AND status = ?", true, models.WorkflowStatusActive).
+				Count(&defaultCount).Error; err != nil {
+				return err
+			}
+			setDefault = defaultCount == 0
+		}
+		if setDefault {
+			return s.setDefaultVersionInTx(tx, &workflow)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &workflow, nil
+}
+
+// PromoteWorkflowVersion 将已发布的版本设为默认版本，新发起的实例使用默认版本，运行中的实例不受影响
+func (s *WorkflowService) PromoteWorkflowVersion(workflowID uint) (*models.WorkflowDefinition, error) {
+	var workflow models.WorkflowDefinition
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&workflow, workflowID).Error; err != nil {
+			return fmt.Errorf("工作流不存在: %w", err)
+		}
+		if !workflow.IsPublished() || workflow.Status != models.WorkflowStatusActive {
+			return errors.New("只能将已发布且启用的版本设为默认版本")
+		}
+		return s.setDefaultVersionInTx(tx, &workflow)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &workflow, nil
+}
+
+// UpdateWorkflowStatus 更新工作流状态：启用草稿即发布该版本，已发布的版本不能改回草稿
+func (s *WorkflowService) UpdateWorkflowStatus(workflowID uint, status models.WorkflowStatus, userID uint) (*models.WorkflowDefinition, error) {
+	var workflow models.WorkflowDefinition
+	if err := s.db.First(&workflow, workflowID).Error; err != nil {
+		return nil, fmt.Errorf("工作流不存在: %w", err)
+	}
+
+	switch status {
+	case models.WorkflowStatusActive:
+		if !workflow.IsPublished() {
+			return s.PublishWorkflow(workflowID, userID, false)
+		}
+	case models.WorkflowStatusDraft:
+		if workflow.IsPublished() {
+			return nil, errors.New("已发布的版本不能改回草稿，请创建新版本")
+		}
+	case models.WorkflowStatusInactive:
+	default:
+		return nil, fmt.Errorf("不支持的工作流状态: %s", status)
+	}
+
+	workflow.Status = status
+	if err := s.db.Save(&workflow).Error; err != nil {
+		return nil, fmt.Errorf("更新工作流状态失败: %w", err)
+	}
+	return &workflow, nil
+}
+
+// ListWorkflowVersions 获取工作流所属版本系列的全部版本，按版本号倒序
+func (s *WorkflowService) ListWorkflowVersions(workflowID uint) ([]models.WorkflowDefinition, error) {
+	var workflow models.WorkflowDefinition
+	if err := s.db.First(&workflow, workflowID).Error; err != nil {
+		return nil, fmt.Errorf("工作流不存在: %w", err)
+	}
+
+	var versions []models.WorkflowDefinition
+	if err := s.lineageQuery(s.db, &workflow).Preload("Creator").Order("version DESC").Find(&versions).Error; err != nil {
+		return nil, fmt.Errorf("获取版本列表失败: %w", err)
+	}
+	return versions, nil
+}
+
+// DiffWorkflowVersions 比较两个版本的基本信息和节点配置
+func (s *WorkflowService) DiffWorkflowVersions(fromID, toID uint) (*WorkflowVersionDiff, error) {
+	var from, to models.WorkflowDefinition
+	if err := s.db.Preload("Nodes.Branches").First(&from, fromID).Error; err != nil {
+		return nil, fmt.Errorf("工作流不存在: %w", err)
+	}
+	if err := s.db.Preload("Nodes.Branches").First(&to, toID).Error; err != nil {
+		return nil, fmt.Errorf("工作流不存在: %w", err)
+	}
+	if from.GetLineageID() != to.GetLineageID() {
+		return nil, errors.New("只能比较同一工作流的不同版本")
+	}
+
+	diff := &WorkflowVersionDiff{
+		FromID:       from.ID,
+		FromVersion:  from.Version,
+		ToID:         to.ID,
+		ToVersion:    to.Version,
+		Fields:       []string{},
+		AddedNodes:   []WorkflowNodeDiff{},
+		RemovedNodes: []WorkflowNodeDiff{},
+		ChangedNodes: []WorkflowNodeDiff{},
+	}
+	if from.Name != to.Name {
+		diff.Fields = append(diff.Fields, "name")
+	}
+	if from.Description != to.Description {
+		diff.Fields = append(diff.Fields, "description")
+	}
+	if from.Category != to.Category {
+		diff.Fields = append(diff.Fields, "category")
+	}
+	if !equalUintPtr(from.FormID, to.FormID) {
+		diff.Fields = append(diff.Fields, "form_id")
+	}
+
+	fromTree, err := from.ParseNodeTree()
+	if err != nil {
+		return nil, fmt.Errorf("解析节点树失败: %w", err)
+	}
+	toTree, err := to.ParseNodeTree()
+	if err != nil {
+		return nil, fmt.Errorf("解析节点树失败: %w", err)
+	}
+	fromParents := nodeParentKeys(fromTree)
+	toParents := nodeParentKeys(toTree)
+
+	fromNodes := make(map[string]models.WorkflowNode)
+	for _, node := range from.Nodes {
+		fromNodes[node.NodeKey] = node
+	}
+	toNodes := make(map[string]models.WorkflowNode)
+	for _, node := range to.Nodes {
+		toNodes[node.NodeKey] = node
+		old, ok := fromNodes[node.NodeKey]
+		if !ok {
+			diff.AddedNodes = append(diff.AddedNodes, WorkflowNodeDiff{Key: node.NodeKey, Name: node.Name})
+			continue
+		}
+
+		fields := diffNodeFields(old, node)
+		if fromParents[node.NodeKey] != toParents[node.NodeKey] {
+			fields = append(fields, "parent")
+		}
+		if len(fields) > 0 {
+			diff.ChangedNodes = append(diff.ChangedNodes, WorkflowNodeDiff{Key: node.NodeKey, Name: node.Name, Fields: fields})
+		}
+	}
+	for _, node := range from.Nodes {
+		if _, ok := toNodes[node.NodeKey]; !ok {
+			diff.RemovedNodes = append(diff.RemovedNodes, WorkflowNodeDiff{Key: node.NodeKey, Name: node.Name})
+		}
+	}
+
+	return diff, nil
+}
+
+// resolveStartVersion 确定新实例使用的版本：优先使用版本系列中启用的默认版本，否则使用指定的已启用版本
+func (s *WorkflowService) resolveStartVersion(tx *gorm.DB, workflowID uint) (uint, error) {
+	var workflow models.WorkflowDefinition
+	if err := tx.First(&workflow, workflowID).Error; err != nil {
+		return 0, fmt.Errorf("工作流定义不存在: %w", err)
+	}
+
+	var defaultVersion models.WorkflowDefinition
+	err := s.lineageQuery(tx, &workflow).
+		Where("is_default = ? AND status = ?", true, models.WorkflowStatusActive).
+		Order("version DESC").
+		First(&defaultVersion).Error
+	if err == nil {
+		return defaultVersion.ID, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, fmt.Errorf("查询默认版本失败: %w", err)
+	}
+
+	if workflow.Status != models.WorkflowStatusActive {
+		return 0, errors.New("工作流未激活，无法启动")
+	}
+	return workflow.ID, nil
+}
+
+// setDefaultVersionInTx 将指定版本设为版本系列中唯一的默认版本
+func (s *WorkflowService) setDefaultVersionInTx(tx *gorm.DB, workflow *models.WorkflowDefinition) error {
+	if err := s.lineageQuery(tx, workflow).Where("id <> ?", workflow.ID).Update("is_default", false).Error; err != nil {
+		return fmt.Errorf("更新默认版本失败: %w", err)
+	}
+	workflow.IsDefault = true
+	if err := tx.Model(workflow).Update("is_default", true).Error; err != nil {
+		return fmt.Errorf("更新默认版本失败: %w", err)
+	}
+	return nil
+}
+
+// lineageQuery 查询与指定工作流同一版本系列的版本（兼容未设置版本系列ID的历史数据）
+func (s *WorkflowService) lineageQuery(tx *gorm.DB, workflow *models.WorkflowDefinition) *gorm.DB {
+	lineageID := workflow.GetLineageID()
+	return tx.Model(&models.WorkflowDefinition{}).Where("lineage_id = ? OR id = ?", lineageID, lineageID)
+}
+
+// rebuildNodesInTx 按节点树创建节点记录，并按节点标识恢复原有节点和分支的配置
+func (s *WorkflowService) rebuildNodesInTx(tx *gorm.DB, workflow *models.WorkflowDefinition, nodeTree *models.NodeTreeData, configNodes []models.WorkflowNode) error {
+	workflow.NodeData = ""
+	workflow.RootNodeID = nil
+
+	// 没有节点树的工作流（按节点列表创建）直接复制节点
+	if nodeTree.IsEmpty() {
+		for _, source := range configNodes {
+			node := source
+			node.ID = 0
+			node.WorkflowID = workflow.ID
+			node.ParentNodeID = nil
+			node.ChildNodeID = nil
+			node.Branches = nil
+			node.CreatedAt = time.Time{}
+			node.UpdatedAt = time.Time{}
+			if err := tx.Create(&node).Error; err != nil {
+				return fmt.Errorf("复制节点失败: %w", err)
+			}
+		}
+		return tx.Model(workflow).Updates(map[string]interface{}{"node_data": "", "root_node_id": nil}).Error
+	}
+
+	if err := workflow.SetNodeTree(nodeTree); err != nil {
+		return fmt.Errorf("设置节点树失败: %w", err)
+	}
+	rootNode, err := s.createNodesFromTree(tx, workflow.ID, nodeTree, nil)
+	if err != nil {
+		return fmt.Errorf("创建节点失败: %w", err)
+	}
+	workflow.RootNodeID = &rootNode.ID
+	if err := tx.Model(workflow).Updates(map[string]interface{}{
+		"node_data":    workflow.NodeData,
+		"root_node_id": rootNode.ID,
+	}).Error; err != nil {
+		return fmt.Errorf("保存节点树失败: %w", err)
+	}
+
+	return s.copyNodeConfigsInTx(tx, workflow.ID, nodeTree, configNodes)
+}
+
+// copyNodeConfigsInTx 将节点配置（审批人、审批方式、条件、设置等）按节点标识复制到指定工作流的节点上，
+// 节点树中已带有配置（props）的节点和分支以节点树为准
+func (s *WorkflowService) copyNodeConfigsInTx(tx *gorm.DB, workflowID uint, nodeTree *models.NodeTreeData, configNodes []models.WorkflowNode) error {
+	hasProps := func(key string) bool {
+		treeNode := nodeTree.FindNode(key)
+		return treeNode != nil && treeNode.Props != nil
+	}
+
+	for _, source := range configNodes {
+		var node models.WorkflowNode
+		err := tx.Where("workflow_id = ? AND node_key = ?", workflowID, source.NodeKey).First(&node).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if !hasProps(source.NodeKey) {
+			if err := tx.Model(&node).Updates(map[string]interface{}{
+				"approval_mode":     source.ApprovalMode,
+				"position":          source.Position,
+				"assignees":         source.Assignees,
+				"conditions":        source.Conditions,
+				"settings":          source.Settings,
+				"next_nodes":        source.NextNodes,
+				"form_conditions":   source.FormConditions,
+				"field_permissions": source.FieldPermissions,
+				"sort_order":        source.SortOrder,
+			}).Error; err != nil {
+				return fmt.Errorf("复制节点配置失败: %w", err)
+			}
+		}
+
+		for _, branch := range source.Branches {
+			if branch.Conditions == "" || hasProps(branch.BranchKey) {
+				continue
+			}
+			if err := tx.Model(&models.WorkflowBranch{}).
+				Where("node_id = ? AND branch_key = ?", node.ID, branch.BranchKey).
+				Update("conditions", branch.Conditions).Error; err != nil {
+				return fmt.Errorf("复制分支条件失败: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// deleteNodesInTx 删除工作流的全部节点和分支
+func (s *WorkflowService) deleteNodesInTx(tx *gorm.DB, workflow *models.WorkflowDefinition) error {
+	var nodeIDs []uint
+	for _, node := range workflow.Nodes {
+		nodeIDs = append(nodeIDs, node.ID)
+	}
+	if len(nodeIDs) == 0 {
+		return nil
+	}
+	if err := tx.Where("node_id IN ?", nodeIDs).Delete(&models.WorkflowBranch{}).Error; err != nil {
+		return fmt.Errorf("删除分支失败: %w", err)
+	}
+	if err := tx.Where("id IN ?", nodeIDs).Delete(&models.WorkflowNode{}).Error; err != nil {
+		return fmt.Errorf("删除节点失败: %w", err)
+	}
+	return nil
+}
+
+// diffNodeFields 比较两个版本中同一节点的配置，返回发生变化的配置项
+func diffNodeFields(from, to models.WorkflowNode) []string {
+	var fields []string
+	if from.Name != to.Name {
+		fields = append(fields, "name")
+	}
+	if from.Type != to.Type {
+		fields = append(fields, "type")
+	}
+	if from.ApprovalMode != to.ApprovalMode {
+		fields = append(fields, "approval_mode")
+	}
+	if from.Assignees != to.Assignees {
+		fields = append(fields, "assignees")
+	}
+	if from.Conditions != to.Conditions {
+		fields = append(fields, "conditions")
+	}
+	if from.Settings != to.Settings {
+		fields = append(fields, "settings")
+	}
+	if from.FormConditions != to.FormConditions {
+		fields = append(fields, "form_conditions")
+	}
+	if from.FieldPermissions != to.FieldPermissions {
+		fields = append(fields, "field_permissions")
+	}
+
+	fromBranches := make(map[string]string)
+	for _, branch := range from.Branches {
+		fromBranches[branch.BranchKey] = branch.Name + "\x00" + branch.Conditions
+	}
+	branchesChanged := len(from.Branches) != len(to.Branches)
+	for _, branch := range to.Branches {
+		if value, ok := fromBranches[branch.BranchKey]; !ok || value != branch.Name+"\x00"+branch.Conditions {
+			branchesChanged = true
+		}
+	}
+	if branchesChanged {
+		fields = append(fields, "branches")
+	}
+	return fields
+}
+
+// nodeParentKeys 计算节点树中每个节点的上级节点标识
+func nodeParentKeys(root *models.NodeTreeData) map[string]string {
+	parents := make(map[string]string)
+	var walk func(node *models.NodeTreeData, parentKey string)
+	walk = func(node *models.NodeTreeData, parentKey string) {
+		if node.IsEmpty() {
+			return
+		}
+		parents[node.Key] = parentKey
+		walk(node.Child, node.Key)
+		for i := range node.Branches {
+			walk(node.Branches[i].Child, node.Key)
+		}
+	}
+	walk(root, "")
+	return parents
+}
+
+// equalUintPtr 比较两个可空ID是否相同
+func equalUintPtr(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}